@@ -35,7 +35,7 @@ func main() {
 
 		fmt.Println(fmt.Sprintf("Usage of %s <tool>", os.Args[0]))
 		fmt.Println()
-		fmt.Println(fmt.Sprintf("ECAL %v - Event Condition Action Language", config.ProductVersion))
+		fmt.Println(fmt.Sprintf("ECAL %v - Event Condition Action Language", config.ProductVersion()))
 		fmt.Println()
 		fmt.Println("Available commands:")
 		fmt.Println()
@@ -13,6 +13,8 @@ package tool
 import (
 	"bufio"
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"flag"
@@ -40,6 +42,12 @@ type CLIDebugInterpreter struct {
 
 	DebugServerAddr *string // Debug server address
 	RunDebugServer  *bool   // Run a debug server
+	WSServerAddr    *string // WebSocket debug server address (no server is started if empty)
+	HTTPServerAddr  *string // HTTP REST debug server address (no server is started if empty)
+	TLSCert         *string // PEM file of the TLS certificate of the debug servers
+	TLSKey          *string // PEM file of the TLS key of the debug servers
+	TLSCA           *string // PEM file of the CA to verify client certificates (optional)
+	AllowedOrigins  *string // Comma separated list of browser origins which may connect to the debug servers
 	EchoDebugServer *bool   // Echo all input and output of the debug server
 	Interactive     *bool   // Flag if the interpreter should open a console in the current tty.
 	BreakOnStart    *bool   // Flag if the debugger should stop the execution on start
@@ -47,14 +55,16 @@ type CLIDebugInterpreter struct {
 
 	LogOut io.Writer // Log output
 
-	debugServer *debugTelnetServer // Debug server if started
+	debugServer     *debugTelnetServer    // Debug server if started
+	debugWSServer   *debugWebSocketServer // WebSocket debug server if started
+	debugHTTPServer *debugHTTPServer      // HTTP REST debug server if started
 }
 
 /*
 NewCLIDebugInterpreter wraps an existing CLIInterpreter object and adds capabilities.
 */
 func NewCLIDebugInterpreter(i *CLIInterpreter) *CLIDebugInterpreter {
-	return &CLIDebugInterpreter{i, nil, nil, nil, nil, nil, nil, os.Stdout, nil}
+	return &CLIDebugInterpreter{i, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, os.Stdout, nil, nil, nil}
 }
 
 /*
@@ -68,6 +78,12 @@ func (i *CLIDebugInterpreter) ParseArgs() bool {
 
 	i.DebugServerAddr = flag.String("serveraddr", "localhost:33274", "Debug server address") // Think BERTA
 	i.RunDebugServer = flag.Bool("server", false, "Run a debug server")
+	i.WSServerAddr = flag.String("ws-serveraddr", "", "Run a WebSocket debug server on the given address")
+	i.HTTPServerAddr = flag.String("http-addr", "", "Run a HTTP REST debug server on the given address")
+	i.TLSCert = flag.String("tls-cert", "", "PEM file of the TLS certificate for the debug server")
+	i.TLSKey = flag.String("tls-key", "", "PEM file of the TLS key for the debug server")
+	i.TLSCA = flag.String("tls-ca", "", "PEM file of a CA to verify client certificates of the debug server")
+	i.AllowedOrigins = flag.String("allowed-origins", "", "Comma separated list of browser origins which may connect to the WebSocket and HTTP debug servers")
 	i.EchoDebugServer = flag.Bool("echo", false, "Echo all i/o of the debug server")
 	i.Interactive = flag.Bool("interactive", true, "Run interactive console")
 	i.BreakOnStart = flag.Bool("breakonstart", false, "Stop the execution on start")
@@ -87,6 +103,12 @@ func (i *CLIDebugInterpreter) Interpret() error {
 
 	err := i.CreateRuntimeProvider("debug console")
 
+	var tlsConfig *tls.Config
+
+	if err == nil {
+		tlsConfig, err = i.CreateTLSConfig()
+	}
+
 	if err == nil {
 
 		// Set custom messages
@@ -95,6 +117,12 @@ func (i *CLIDebugInterpreter) Interpret() error {
 		if *i.RunDebugServer {
 			i.CLIInterpreter.CustomWelcomeMessage += fmt.Sprintf("with debug server on %v - ", *i.DebugServerAddr)
 		}
+		if i.WSServerAddr != nil && *i.WSServerAddr != "" {
+			i.CLIInterpreter.CustomWelcomeMessage += fmt.Sprintf("with WebSocket debug server on %v - ", *i.WSServerAddr)
+		}
+		if i.HTTPServerAddr != nil && *i.HTTPServerAddr != "" {
+			i.CLIInterpreter.CustomWelcomeMessage += fmt.Sprintf("with HTTP debug server on %v - ", *i.HTTPServerAddr)
+		}
 		i.CLIInterpreter.CustomWelcomeMessage += "prefix debug commands with ##"
 		i.CustomHelpString = "    @dbg [glob] - List all available debug commands.\n"
 
@@ -103,6 +131,7 @@ func (i *CLIDebugInterpreter) Interpret() error {
 		i.RuntimeProvider.Debugger = interpreter.NewECALDebugger(i.GlobalVS)
 		i.RuntimeProvider.Debugger.BreakOnStart(*i.BreakOnStart)
 		i.RuntimeProvider.Debugger.BreakOnError(*i.BreakOnError)
+		i.RuntimeProvider.Debugger.SetLogger(i.RuntimeProvider.Logger)
 
 		// Set this object as a custom handler to deal with input.
 
@@ -113,7 +142,7 @@ func (i *CLIDebugInterpreter) Interpret() error {
 			// Start the debug server
 
 			i.debugServer = &debugTelnetServer{*i.DebugServerAddr, "ECALDebugServer: ",
-				nil, true, *i.EchoDebugServer, i, i.RuntimeProvider.Logger}
+				nil, true, *i.EchoDebugServer, i, i.RuntimeProvider.Logger, tlsConfig}
 
 			wg := &sync.WaitGroup{}
 			wg.Add(1)
@@ -125,6 +154,40 @@ func (i *CLIDebugInterpreter) Interpret() error {
 			}
 		}
 
+		if i.WSServerAddr != nil && *i.WSServerAddr != "" {
+
+			// Start the WebSocket debug server
+
+			i.debugWSServer = &debugWebSocketServer{&debugTelnetServer{*i.WSServerAddr, "ECALWebSocketDebugServer: ",
+				nil, true, *i.EchoDebugServer, i, i.RuntimeProvider.Logger, tlsConfig}, i.allowedOrigins()}
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			go i.debugWSServer.Run(wg)
+			wg.Wait()
+
+			if *i.Interactive {
+				defer i.StopDebugServer()
+			}
+		}
+
+		if i.HTTPServerAddr != nil && *i.HTTPServerAddr != "" {
+
+			// Start the HTTP REST debug server
+
+			i.debugHTTPServer = &debugHTTPServer{&debugTelnetServer{*i.HTTPServerAddr, "ECALHTTPDebugServer: ",
+				nil, true, *i.EchoDebugServer, i, i.RuntimeProvider.Logger, tlsConfig}, i.allowedOrigins()}
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			go i.debugHTTPServer.Run(wg)
+			wg.Wait()
+
+			if *i.Interactive {
+				defer i.StopDebugServer()
+			}
+		}
+
 		err = i.CLIInterpreter.Interpret(*i.Interactive)
 	}
 
@@ -132,13 +195,91 @@ func (i *CLIDebugInterpreter) Interpret() error {
 }
 
 /*
-StopDebugServer stops the debug server if it was started.
+allowedOrigins returns the list of browser origins which may connect to the
+debug servers.
+*/
+func (i *CLIDebugInterpreter) allowedOrigins() []string {
+	var origins []string
+
+	if i.AllowedOrigins != nil {
+		for _, o := range strings.Split(*i.AllowedOrigins, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				origins = append(origins, o)
+			}
+		}
+	}
+
+	return origins
+}
+
+/*
+CreateTLSConfig creates the TLS configuration for the debug servers from the
+given PEM files. Returns nil if no certificate and key were given.
+*/
+func (i *CLIDebugInterpreter) CreateTLSConfig() (*tls.Config, error) {
+	var tlsConfig *tls.Config
+
+	fileParam := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+
+	certFile, keyFile := fileParam(i.TLSCert), fileParam(i.TLSKey)
+
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	} else if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("TLS needs a certificate and a key file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+
+	if err == nil {
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+
+		if caFile := fileParam(i.TLSCA); caFile != "" {
+			var caPEM []byte
+
+			if caPEM, err = os.ReadFile(caFile); err == nil {
+				pool := x509.NewCertPool()
+
+				if !pool.AppendCertsFromPEM(caPEM) {
+					err = fmt.Errorf("Could not read any certificates from %v", caFile)
+				}
+
+				tlsConfig.ClientCAs = pool
+				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+	}
+
+	if err != nil {
+		tlsConfig = nil
+	}
+
+	return tlsConfig, err
+}
+
+/*
+StopDebugServer stops the debug servers if they were started.
 */
 func (i *CLIDebugInterpreter) StopDebugServer() {
 	if i.debugServer != nil && i.debugServer.listener != nil {
 		i.debugServer.listen = false
 		i.debugServer.listener.Close() // Attempt to cleanup
 	}
+	if i.debugWSServer != nil && i.debugWSServer.listener != nil {
+		i.debugWSServer.listen = false
+		i.debugWSServer.listener.Close() // Attempt to cleanup
+	}
+	if i.debugHTTPServer != nil && i.debugHTTPServer.listener != nil {
+		i.debugHTTPServer.listen = false
+		i.debugHTTPServer.listener.Close() // Attempt to cleanup
+	}
 }
 
 /*
@@ -161,7 +302,7 @@ func (i *CLIDebugInterpreter) CanHandle(s string) bool {
 /*
 Handle handles a given input string.
 */
-func (i *CLIDebugInterpreter) Handle(ot OutputTerminal, line string) {
+func (i *CLIDebugInterpreter) Handle(ot util.OutputTerminal, line string) {
 
 	if strings.HasPrefix(line, "@dbg") {
 
@@ -218,6 +359,7 @@ type debugTelnetServer struct {
 	echo        bool
 	interpreter *CLIDebugInterpreter
 	logger      util.Logger
+	tlsConfig   *tls.Config // TLS configuration (connections are not encrypted if nil)
 }
 
 /*
@@ -241,6 +383,12 @@ func (s *debugTelnetServer) Run(wg *sync.WaitGroup) {
 			s.logger.LogInfo(s.logPrefix,
 				"Running Debug Server on ", tcpaddr.String())
 
+			var listener net.Listener = s.listener
+
+			if s.tlsConfig != nil {
+				listener = tls.NewListener(s.listener, s.tlsConfig)
+			}
+
 			for s.listen {
 				var conn net.Conn
 
@@ -248,7 +396,7 @@ func (s *debugTelnetServer) Run(wg *sync.WaitGroup) {
 				// When the test was working correctly, this print was
 				// extremely talkative.  So I killed it.
 
-				if conn, err = s.listener.Accept(); err == nil {
+				if conn, err = listener.Accept(); err == nil {
 					go s.HandleConnection(conn)
 
 				} else if s.listen {
@@ -282,8 +430,26 @@ func (s *debugTelnetServer) HandleConnection(conn net.Conn) {
 	fmt.Printf("Entered HandleConnection function\n")
 	tid := s.interpreter.RuntimeProvider.NewThreadID()
 	inputReader := bufio.NewReader(conn)
-	outputTerminal := OutputTerminal(&bufioWriterShim{fmt.Sprint(conn.RemoteAddr()),
-		bufio.NewWriter(conn), s.echo, s.interpreter.LogOut})
+	outputTerminal := util.OutputTerminal(&bufioWriterShim{fmt.Sprint(conn.RemoteAddr()),
+		bufio.NewWriter(conn), s.echo, s.interpreter.LogOut, &sync.Mutex{}})
+
+	// Send notifications about changed watch expressions to the client
+
+	debugger := s.interpreter.RuntimeProvider.Debugger
+	debugger.AddWatchListener(fmt.Sprint(conn.RemoteAddr()),
+		func(threadID uint64, expression string, value interface{}) {
+			outBytes, err := json.MarshalIndent(map[string]interface{}{
+				"WatchNotification": map[string]interface{}{
+					"ThreadID":   threadID,
+					"Expression": expression,
+					"Value":      value,
+				},
+			}, "", "  ")
+			if err == nil {
+				outputTerminal.WriteString(fmt.Sprintln(fmt.Sprintln(string(outBytes))))
+			}
+		})
+	defer debugger.RemoveWatchListener(fmt.Sprint(conn.RemoteAddr()))
 
 	line := ""
 
@@ -313,7 +479,7 @@ func (s *debugTelnetServer) HandleConnection(conn net.Conn) {
 				buffer := bytes.NewBuffer(nil)
 
 				s.interpreter.HandleInput(&bufioWriterShim{"tmpbuffer",
-					bufio.NewWriter(buffer), false, s.interpreter.LogOut}, line, tid)
+					bufio.NewWriter(buffer), false, s.interpreter.LogOut, &sync.Mutex{}}, line, tid)
 
 				if isHelpTable {
 
@@ -361,12 +527,16 @@ type bufioWriterShim struct {
 	writer *bufio.Writer
 	echo   bool
 	logOut io.Writer
+	lock   *sync.Mutex
 }
 
 /*
 WriteString write a string to the writer.
 */
 func (shim *bufioWriterShim) WriteString(s string) {
+	shim.lock.Lock()
+	defer shim.lock.Unlock()
+
 	if shim.echo {
 		fmt.Fprintln(shim.logOut, fmt.Sprintf("%v < %v", shim.id, s))
 	}
@@ -0,0 +1,269 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package tool
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rhedin/Abe_ecal/util"
+)
+
+/*
+debugHTTPServer is a debug server which exposes the debugger as a REST API.
+All responses are JSON objects.
+*/
+type debugHTTPServer struct {
+	*debugTelnetServer          // Server configuration shared with the telnet server
+	allowedOrigins     []string // Browser origins which are allowed to send requests
+}
+
+/*
+Run runs the debug server.
+*/
+func (s *debugHTTPServer) Run(wg *sync.WaitGroup) {
+	tcpaddr, err := net.ResolveTCPAddr("tcp", s.address)
+
+	if err == nil {
+
+		s.listener, err = net.ListenTCP("tcp", tcpaddr)
+
+		if err == nil {
+
+			wg.Done()
+
+			s.logger.LogInfo(s.logPrefix,
+				"Running HTTP Debug Server on ", tcpaddr.String())
+
+			var listener net.Listener = s.listener
+
+			if s.tlsConfig != nil {
+				listener = tls.NewListener(s.listener, s.tlsConfig)
+			}
+
+			err = http.Serve(listener, s.Handler())
+
+			if s.listen {
+				s.logger.LogError(s.logPrefix, err)
+			}
+
+			return
+		}
+	}
+
+	if s.listen && err != nil {
+		s.logger.LogError(s.logPrefix, "Could not start HTTP debug server - ", err)
+		wg.Done()
+	}
+}
+
+/*
+Handler returns the HTTP handler for all REST endpoints. Requests from browser
+origins which were not explicitly allowed are rejected and POST requests must
+have a JSON content type so they cannot be sent by plain HTML forms.
+*/
+func (s *debugHTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /breakpoint", s.handleSetBreakpoint)
+	mux.HandleFunc("DELETE /breakpoint/{target...}", s.handleRemoveBreakpoint)
+	mux.HandleFunc("GET /thread/{id}", s.handleDescribeThread)
+	mux.HandleFunc("POST /thread/{id}/continue", s.handleContinueThread)
+	mux.HandleFunc("POST /inject", s.handleInject)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if err := checkOrigin(r, s.allowedOrigins); err != nil {
+			s.writeError(w, http.StatusForbidden, err)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+				s.writeError(w, http.StatusUnsupportedMediaType,
+					fmt.Errorf("Content type must be application/json"))
+				return
+			}
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+/*
+handleStatus returns the current status of the debugger.
+*/
+func (s *debugHTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, s.debugger().Status())
+}
+
+/*
+handleSetBreakpoint sets a breakpoint.
+*/
+func (s *debugHTTPServer) handleSetBreakpoint(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Source string `json:"source"`
+		Line   int    `json:"line"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Source == "" || req.Line < 1 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("Need a source and a line"))
+		return
+	}
+
+	s.debugger().SetBreakPoint(req.Source, req.Line)
+	s.writeResult(w, map[string]interface{}{})
+}
+
+/*
+handleRemoveBreakpoint removes a breakpoint. The source of the breakpoint can
+contain slashes - the line is always the last path element.
+*/
+func (s *debugHTTPServer) handleRemoveBreakpoint(w http.ResponseWriter, r *http.Request) {
+	target := r.PathValue("target")
+
+	if i := strings.LastIndex(target, "/"); i > 0 {
+		if line, err := strconv.Atoi(target[i+1:]); err == nil {
+			s.debugger().RemoveBreakPoint(target[:i], line)
+			s.writeResult(w, map[string]interface{}{})
+			return
+		}
+	}
+
+	s.writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid breakpoint - the path should end with a source and a line"))
+}
+
+/*
+handleDescribeThread describes a thread currently observed by the debugger.
+*/
+func (s *debugHTTPServer) handleDescribeThread(w http.ResponseWriter, r *http.Request) {
+	threadID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("Thread ID should be a number"))
+		return
+	}
+
+	if res, ok := s.debugger().Describe(threadID).(map[string]interface{}); ok && res != nil {
+		s.writeResult(w, res)
+		return
+	}
+
+	s.writeError(w, http.StatusNotFound, fmt.Errorf("Cannot find thread %v", threadID))
+}
+
+/*
+handleContinueThread continues a suspended thread.
+*/
+func (s *debugHTTPServer) handleContinueThread(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type string `json:"type"`
+	}
+	var cmd util.ContType
+
+	threadID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("Thread ID should be a number"))
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("Need a continue type"))
+		return
+	}
+
+	switch strings.ToLower(req.Type) {
+	case "resume":
+		cmd = util.Resume
+	case "stepin":
+		cmd = util.StepIn
+	case "stepover":
+		cmd = util.StepOver
+	case "stepout":
+		cmd = util.StepOut
+	case "abort":
+		cmd = util.Abort
+	default:
+		s.writeError(w, http.StatusBadRequest,
+			fmt.Errorf("Invalid continue type %v - must be resume, stepIn, stepOver, stepOut or abort", req.Type))
+		return
+	}
+
+	s.debugger().Continue(threadID, cmd)
+	s.writeResult(w, map[string]interface{}{})
+}
+
+/*
+handleInject copies a value from an expression into a suspended thread.
+*/
+func (s *debugHTTPServer) handleInject(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ThreadID uint64 `json:"threadId"`
+		Var      string `json:"var"`
+		Expr     string `json:"expr"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Var == "" || req.Expr == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("Need a thread ID, a variable name and an expression"))
+		return
+	}
+
+	if err := s.debugger().InjectValue(req.ThreadID, req.Var, req.Expr); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResult(w, map[string]interface{}{})
+}
+
+/*
+debugger returns the debugger of the interpreter.
+*/
+func (s *debugHTTPServer) debugger() util.ECALDebugger {
+	return s.interpreter.RuntimeProvider.Debugger
+}
+
+/*
+writeResult writes a JSON result.
+*/
+func (s *debugHTTPServer) writeResult(w http.ResponseWriter, res interface{}) {
+	outBytes, err := json.MarshalIndent(res, "", "  ")
+
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(outBytes)
+}
+
+/*
+writeError writes a JSON error.
+*/
+func (s *debugHTTPServer) writeError(w http.ResponseWriter, status int, err error) {
+	outBytes, _ := json.MarshalIndent(map[string]interface{}{
+		"DebuggerError": err.Error(),
+	}, "", "  ")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(outBytes)
+}
@@ -13,10 +13,21 @@ package tool
 import (
 	"bufio"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"flag"
 	"fmt"
+	"io"
+	"math/big"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -26,8 +37,10 @@ import (
 
 	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_ecal/interpreter"
+	"github.com/rhedin/Abe_ecal/parser"
 	"github.com/rhedin/Abe_ecal/stdlib"
 	"github.com/rhedin/Abe_ecal/util"
+	"golang.org/x/net/websocket"
 )
 
 var testDebugLogOut *bytes.Buffer
@@ -223,6 +236,26 @@ ECAL error in foo (console input): 123 () (Line:1 Pos:1)
 		t.Error("Unexpected result:", testTerm.out.String())
 		return
 	}
+
+	// Debug command output can be captured without a terminal
+
+	sot, _ := util.NewStringOutputTerminal()
+
+	tdin.Handle(sot, "##status")
+
+	if res := sot.String(); !strings.Contains(res, `"breakpoints"`) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	sot.Reset()
+
+	tdin.Handle(sot, "##foo")
+
+	if res := sot.String(); !strings.Contains(res, "Unknown command: foo") {
+		t.Error("Unexpected result:", res)
+		return
+	}
 }
 
 func TestDebugTelnetServer(t *testing.T) {
@@ -396,3 +429,585 @@ func TestDebugTelnetServer(t *testing.T) {
 		return
 	}
 }
+
+func TestDebugWebSocketServer(t *testing.T) {
+	tdin := newTestDebugWithConfig()
+	defer tearDown()
+
+	if err := tdin.CreateRuntimeProvider("foo"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.RuntimeProvider.Logger = util.NewMemoryLogger(10)
+	tdin.RuntimeProvider.ImportLocator = &util.MemoryImportLocator{}
+	tdin.RuntimeProvider.Debugger = interpreter.NewECALDebugger(tdin.GlobalVS)
+	tdin.RuntimeProvider.Debugger.BreakOnError(false)
+	tdin.CustomHandler = tdin
+
+	addr := "localhost:33275"
+	mlog := util.NewMemoryLogger(10)
+
+	srv := &debugWebSocketServer{&debugTelnetServer{
+		address:     addr,
+		logPrefix:   "testdebugserver",
+		listener:    nil,
+		listen:      true,
+		echo:        true,
+		interpreter: tdin,
+		logger:      mlog,
+	}, []string{"http://localhost"}}
+	defer func() {
+		srv.listen = false
+		srv.listener.Close() // Attempt to cleanup
+	}()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go srv.Run(wg)
+	wg.Wait()
+
+	// Browser connections from other origins are rejected
+
+	if _, err := websocket.Dial(fmt.Sprintf("ws://%v/", addr), "", "http://evil.example/"); err == nil {
+		t.Error("Connection from a foreign origin should be rejected")
+		return
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	if err := checkOrigin(req, nil); err != nil {
+		t.Error("Request without an origin should be accepted:", err)
+		return
+	}
+
+	req.Header.Set("Origin", "http://evil.example")
+
+	if err := checkOrigin(req, []string{"http://localhost"}); err == nil || err.Error() != "Origin http://evil.example is not allowed" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	conn, err := websocket.Dial(fmt.Sprintf("ws://%v/", addr), "", "http://localhost/")
+	errorutil.AssertOk(err)
+	reader := bufio.NewReader(conn)
+
+	fmt.Fprintf(conn, "a:= 1; a\n")
+
+	line, err := reader.ReadString('}')
+	errorutil.AssertOk(err)
+
+	if line != `{
+  "EncodedOutput": "MQo="
+}` {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	fmt.Fprintf(conn, "##status\n")
+
+	line, err = reader.ReadString('}')
+	errorutil.AssertOk(err)
+	l, err := reader.ReadString('}')
+	errorutil.AssertOk(err)
+	line += l
+	l, err = reader.ReadString('}')
+	errorutil.AssertOk(err)
+	line += l
+	line = strings.TrimSpace(line)
+
+	if line != `{
+  "breakonstart": false,
+  "breakpoints": {},
+  "sources": [
+    "console input"
+  ],
+  "threads": {}
+}` {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	testDebugLogOut.Reset()
+
+	errorutil.AssertOk(conn.Close())
+
+	time.Sleep(10 * time.Millisecond)
+
+	if !strings.Contains(testDebugLogOut.String(), "Disconnected") {
+		t.Error("Unexpected output:", testDebugLogOut)
+		return
+	}
+
+	// Make sure we can't start a second server on the same port
+
+	mlog2 := util.NewMemoryLogger(10)
+
+	srv2 := &debugWebSocketServer{&debugTelnetServer{
+		address:     addr,
+		logPrefix:   "testdebugserver",
+		listener:    nil,
+		listen:      true,
+		echo:        true,
+		interpreter: tdin,
+		logger:      mlog2,
+	}, nil}
+
+	wg = &sync.WaitGroup{}
+	wg.Add(1)
+	go srv2.Run(wg)
+	wg.Wait()
+
+	if !strings.Contains(mlog2.String(), "address already in use") {
+		t.Error("Unexpected output:", mlog2.String())
+		return
+	}
+
+	mlog.Reset()
+
+	srv.listener.Close()
+
+	time.Sleep(5 * time.Millisecond)
+
+	if !strings.Contains(mlog.String(), "use of closed network connection") {
+		t.Error("Unexpected output:", mlog.String())
+		return
+	}
+}
+
+/*
+writeTestCertificate writes a self-signed certificate for localhost and its
+key as PEM files into a given directory.
+*/
+func writeTestCertificate(dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	errorutil.AssertOk(err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	errorutil.AssertOk(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	errorutil.AssertOk(err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	errorutil.AssertOk(os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600))
+	errorutil.AssertOk(os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestDebugTelnetServerTLS(t *testing.T) {
+	tdin := newTestDebugWithConfig()
+	defer tearDown()
+
+	if err := tdin.CreateRuntimeProvider("foo"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.RuntimeProvider.Logger = util.NewMemoryLogger(10)
+	tdin.RuntimeProvider.ImportLocator = &util.MemoryImportLocator{}
+	tdin.RuntimeProvider.Debugger = interpreter.NewECALDebugger(tdin.GlobalVS)
+	tdin.RuntimeProvider.Debugger.BreakOnError(false)
+	tdin.CustomHandler = tdin
+
+	certFile, keyFile := writeTestCertificate(t.TempDir())
+	empty := ""
+
+	tdin.TLSCert = &certFile
+	tdin.TLSKey = &empty
+
+	if _, err := tdin.CreateTLSConfig(); err == nil || err.Error() != "TLS needs a certificate and a key file" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.TLSKey = &certFile
+
+	if _, err := tdin.CreateTLSConfig(); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.TLSKey = &keyFile
+	tdin.TLSCA = &keyFile
+
+	if _, err := tdin.CreateTLSConfig(); err == nil || err.Error() != fmt.Sprintf("Could not read any certificates from %v", keyFile) {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.TLSCA = &empty
+
+	tlsConfig, err := tdin.CreateTLSConfig()
+	errorutil.AssertOk(err)
+
+	addr := "localhost:33276"
+
+	srv := &debugTelnetServer{
+		address:     addr,
+		logPrefix:   "testdebugserver",
+		listener:    nil,
+		listen:      true,
+		echo:        true,
+		interpreter: tdin,
+		logger:      util.NewMemoryLogger(10),
+		tlsConfig:   tlsConfig,
+	}
+	defer func() {
+		srv.listen = false
+		srv.listener.Close() // Attempt to cleanup
+	}()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go srv.Run(wg)
+	wg.Wait()
+
+	caPEM, err := os.ReadFile(certFile)
+	errorutil.AssertOk(err)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(caPEM)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: rootCAs})
+	errorutil.AssertOk(err)
+
+	fmt.Fprintf(conn, "a:= 1; a\n")
+
+	line, err := bufio.NewReader(conn).ReadString('}')
+	errorutil.AssertOk(err)
+
+	if line != `{
+  "EncodedOutput": "MQo="
+}` {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	conn.Close()
+
+	// A plaintext connection must be rejected
+
+	plainConn, err := net.Dial("tcp", addr)
+	errorutil.AssertOk(err)
+
+	fmt.Fprintf(plainConn, "a:= 1; a\n")
+
+	if line, err := bufio.NewReader(plainConn).ReadString('}'); err == nil {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	plainConn.Close()
+
+	// Require client certificates
+
+	tdin.TLSCA = &certFile
+
+	tlsConfig, err = tdin.CreateTLSConfig()
+	errorutil.AssertOk(err)
+
+	addr = "localhost:33277"
+
+	srv2 := &debugTelnetServer{
+		address:     addr,
+		logPrefix:   "testdebugserver",
+		listener:    nil,
+		listen:      true,
+		echo:        true,
+		interpreter: tdin,
+		logger:      util.NewMemoryLogger(10),
+		tlsConfig:   tlsConfig,
+	}
+	defer func() {
+		srv2.listen = false
+		srv2.listener.Close() // Attempt to cleanup
+	}()
+
+	wg = &sync.WaitGroup{}
+	wg.Add(1)
+	go srv2.Run(wg)
+	wg.Wait()
+
+	conn, err = tls.Dial("tcp", addr, &tls.Config{RootCAs: rootCAs})
+	errorutil.AssertOk(err)
+
+	fmt.Fprintf(conn, "a:= 1; a\n")
+
+	if line, err := bufio.NewReader(conn).ReadString('}'); err == nil {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	conn.Close()
+
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	errorutil.AssertOk(err)
+
+	conn, err = tls.Dial("tcp", addr, &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{clientCert}})
+	errorutil.AssertOk(err)
+
+	fmt.Fprintf(conn, "a:= 1; a\n")
+
+	line, err = bufio.NewReader(conn).ReadString('}')
+	errorutil.AssertOk(err)
+
+	if line != `{
+  "EncodedOutput": "MQo="
+}` {
+		t.Error("Unexpected output:", line)
+		return
+	}
+
+	conn.Close()
+}
+
+func TestDebugHTTPServer(t *testing.T) {
+	tdin := newTestDebugWithConfig()
+	defer tearDown()
+
+	if err := tdin.CreateRuntimeProvider("foo"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	tdin.RuntimeProvider.Logger = util.NewMemoryLogger(10)
+	tdin.RuntimeProvider.ImportLocator = &util.MemoryImportLocator{}
+	tdin.RuntimeProvider.Debugger = interpreter.NewECALDebugger(tdin.GlobalVS)
+	tdin.RuntimeProvider.Debugger.BreakOnError(false)
+
+	addr := "localhost:33278"
+	mlog := util.NewMemoryLogger(10)
+
+	srv := &debugHTTPServer{&debugTelnetServer{
+		address:     addr,
+		logPrefix:   "testdebugserver",
+		listener:    nil,
+		listen:      true,
+		echo:        true,
+		interpreter: tdin,
+		logger:      mlog,
+	}, []string{"http://localhost:8080"}}
+	defer func() {
+		srv.listen = false
+		srv.listener.Close() // Attempt to cleanup
+	}()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go srv.Run(wg)
+	wg.Wait()
+
+	requestWithHeader := func(method string, path string, body string, header map[string]string) (int, string) {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%v%v", addr, path), strings.NewReader(body))
+		errorutil.AssertOk(err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		errorutil.AssertOk(err)
+		defer resp.Body.Close()
+		res, err := io.ReadAll(resp.Body)
+		errorutil.AssertOk(err)
+		return resp.StatusCode, string(res)
+	}
+
+	request := func(method string, path string, body string) (int, string) {
+		return requestWithHeader(method, path, body, map[string]string{"Content-Type": "application/json"})
+	}
+
+	if status, res := request("POST", "/breakpoint", `{"source":"my/code","line":3}`); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/breakpoint", `{"source":"my/code","line":4}`); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("DELETE", "/breakpoint/my/code/4", ""); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("GET", "/status", ""); status != 200 || res != `{
+  "breakonstart": false,
+  "breakpoints": {
+    "my/code:3": true
+  },
+  "sources": null,
+  "threads": {}
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	ast, err := parser.ParseWithRuntime("my/code", `
+a := 1
+b := a
+c := b
+`, tdin.RuntimeProvider)
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	wg = &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err := ast.Runtime.Eval(tdin.GlobalVS, make(map[string]interface{}), 1)
+		errorutil.AssertOk(err)
+		wg.Done()
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, res := request("GET", "/thread/1", ""); strings.Contains(res, `"threadRunning": false`) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if status, res := request("GET", "/thread/1", ""); status != 200 || !strings.Contains(res, `"code": "b := a"`) {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/inject", `{"threadId":1,"var":"a","expr":"42"}`); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/thread/1/continue", `{"type":"resume"}`); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	wg.Wait()
+
+	if res := tdin.GlobalVS.String(); res != `GlobalScope {
+    a (float64) : 42
+    b (float64) : 42
+    c (float64) : 42
+}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Error cases
+
+	if status, res := request("POST", "/breakpoint", `{"source":"my/code"}`); status != 400 || res != `{
+  "DebuggerError": "Need a source and a line"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("DELETE", "/breakpoint/my/code/x", ""); status != 400 || res != `{
+  "DebuggerError": "Invalid breakpoint - the path should end with a source and a line"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("GET", "/thread/x", ""); status != 400 || res != `{
+  "DebuggerError": "Thread ID should be a number"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("GET", "/thread/5", ""); status != 404 || res != `{
+  "DebuggerError": "Cannot find thread 5"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/thread/5/continue", `{"type":"jump"}`); status != 400 || res != `{
+  "DebuggerError": "Invalid continue type jump - must be resume, stepIn, stepOver, stepOut or abort"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/thread/x/continue", `{"type":"resume"}`); status != 400 || res != `{
+  "DebuggerError": "Thread ID should be a number"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/thread/5/continue", `x`); status != 400 || res != `{
+  "DebuggerError": "Need a continue type"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/inject", `{"threadId":5,"var":"a","expr":"1"}`); status != 400 || res != `{
+  "DebuggerError": "Cannot find suspended thread 5"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := request("POST", "/inject", `{}`); status != 400 || res != `{
+  "DebuggerError": "Need a thread ID, a variable name and an expression"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, _ := request("GET", "/foo", ""); status != 404 {
+		t.Error("Unexpected result:", status)
+		return
+	}
+
+	if status, res := requestWithHeader("POST", "/inject", `{"threadId":5,"var":"a","expr":"1"}`,
+		map[string]string{"Content-Type": "text/plain"}); status != 415 || res != `{
+  "DebuggerError": "Content type must be application/json"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := requestWithHeader("POST", "/breakpoint", `{"source":"my/code","line":3}`,
+		map[string]string{"Content-Type": "application/json", "Origin": "http://evil.example"}); status != 403 || res != `{
+  "DebuggerError": "Origin http://evil.example is not allowed"
+}` {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	if status, res := requestWithHeader("POST", "/breakpoint", `{"source":"my/code","line":3}`,
+		map[string]string{"Content-Type": "application/json; charset=utf-8", "Origin": "http://localhost:8080"}); status != 200 || res != "{}" {
+		t.Error("Unexpected result:", status, res)
+		return
+	}
+
+	mlog.Reset()
+
+	srv.listener.Close()
+
+	time.Sleep(5 * time.Millisecond)
+
+	if !strings.Contains(mlog.String(), "use of closed network connection") {
+		t.Error("Unexpected output:", mlog.String())
+		return
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package tool
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+/*
+debugWebSocketServer is a debug server which sends and receives debug data
+over WebSocket connections. It uses the same line based JSON protocol as the
+telnet debug server.
+*/
+type debugWebSocketServer struct {
+	*debugTelnetServer          // Connection handling of the telnet server
+	allowedOrigins     []string // Browser origins which are allowed to connect
+}
+
+/*
+Run runs the debug server.
+*/
+func (s *debugWebSocketServer) Run(wg *sync.WaitGroup) {
+	tcpaddr, err := net.ResolveTCPAddr("tcp", s.address)
+
+	if err == nil {
+
+		s.listener, err = net.ListenTCP("tcp", tcpaddr)
+
+		if err == nil {
+
+			wg.Done()
+
+			s.logger.LogInfo(s.logPrefix,
+				"Running WebSocket Debug Server on ", tcpaddr.String())
+
+			var listener net.Listener = s.listener
+
+			if s.tlsConfig != nil {
+				listener = tls.NewListener(s.listener, s.tlsConfig)
+			}
+
+			// Browsers always send an Origin header - reject browser
+			// connections from origins which were not explicitly allowed
+
+			err = http.Serve(listener, websocket.Server{
+				Handshake: func(config *websocket.Config, r *http.Request) error {
+					return checkOrigin(r, s.allowedOrigins)
+				},
+				Handler: func(conn *websocket.Conn) {
+					addr, _ := net.ResolveTCPAddr("tcp", conn.Request().RemoteAddr)
+					s.HandleConnection(&webSocketConn{conn, addr})
+				},
+			})
+
+			if s.listen {
+				s.logger.LogError(s.logPrefix, err)
+			}
+
+			return
+		}
+	}
+
+	if s.listen && err != nil {
+		s.logger.LogError(s.logPrefix, "Could not start WebSocket debug server - ", err)
+		wg.Done()
+	}
+}
+
+/*
+checkOrigin checks the Origin header of a request. Requests without an Origin
+header (i.e. non-browser clients) and requests from allowed origins are
+accepted.
+*/
+func checkOrigin(r *http.Request, allowedOrigins []string) error {
+	origin := strings.TrimSuffix(r.Header.Get("Origin"), "/")
+
+	if origin == "" {
+		return nil
+	}
+
+	for _, o := range allowedOrigins {
+		if strings.EqualFold(origin, strings.TrimSuffix(o, "/")) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Origin %v is not allowed", origin)
+}
+
+/*
+webSocketConn is a WebSocket connection which reports the network address
+of the remote client instead of its origin.
+*/
+type webSocketConn struct {
+	*websocket.Conn
+	addr net.Addr
+}
+
+/*
+RemoteAddr returns the network address of the remote client.
+*/
+func (c *webSocketConn) RemoteAddr() net.Addr {
+	return c.addr
+}
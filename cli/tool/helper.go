@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"github.com/rhedin/Abe_common/stringutil"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 /*
@@ -48,25 +49,20 @@ type CLIInputHandler interface {
 	/*
 	   Handle handles a given input string.
 	*/
-	Handle(ot OutputTerminal, input string)
+	Handle(ot util.OutputTerminal, input string)
 }
 
 /*
-OutputTerminal is a generic output terminal which can write strings.
+InputHandler is a handler object for CLI input which can be used by
+applications which embed the CLI interpreter.
 */
-type OutputTerminal interface {
-
-	/*
-	   WriteString write a string on this terminal.
-	*/
-	WriteString(s string)
-}
+type InputHandler = CLIInputHandler
 
 /*
 matchesFulltextSearch checks if a given text matches a given glob expression. Returns
 true if an error occurs.
 */
-func matchesFulltextSearch(ot OutputTerminal, text string, glob string) bool {
+func matchesFulltextSearch(ot util.OutputTerminal, text string, glob string) bool {
 	var res bool
 
 	re, err := stringutil.GlobToRegex(glob)
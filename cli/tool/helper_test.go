@@ -19,6 +19,7 @@ import (
 
 	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_common/termutil"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 type testConsoleLineTerminal struct {
@@ -68,7 +69,7 @@ func (t *testCustomHandler) CanHandle(s string) bool {
 	return s == "@cus"
 }
 
-func (t *testCustomHandler) Handle(ot OutputTerminal, input string) {}
+func (t *testCustomHandler) Handle(ot util.OutputTerminal, input string) {}
 
 func (t *testCustomHandler) LoadInitialFile(tid uint64) error {
 	return nil
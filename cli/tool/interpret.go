@@ -48,7 +48,10 @@ type CLICustomHandler interface {
 }
 
 /*
-CLIInterpreter is a commandline interpreter for ECAL.
+CLIInterpreter is a commandline interpreter for ECAL. It can be embedded in
+other applications by constructing it directly with a runtime provider and a
+global variable scope. Input can then be processed with HandleInput without
+parsing any command line arguments.
 */
 type CLIInterpreter struct {
 	GlobalVS        parser.Scope                     // Global variable scope
@@ -83,7 +86,7 @@ type CLIInterpreter struct {
 NewCLIInterpreter creates a new commandline interpreter for ECAL.
 */
 func NewCLIInterpreter() *CLIInterpreter {
-	return &CLIInterpreter{scope.NewScope(scope.GlobalScope), nil, nil, "", "",
+	return &CLIInterpreter{scope.NewGlobalScope(), nil, nil, "", "",
 		[]*engine.Rule{}, "", true, nil, nil, nil, nil, os.Stdout}
 }
 
@@ -169,7 +172,8 @@ func (i *CLIInterpreter) CreateRuntimeProvider(name string) error {
 
 			// Create interpreter
 
-			i.RuntimeProvider = interpreter.NewECALRuntimeProvider(name, importLocator, logger)
+			i.RuntimeProvider = interpreter.NewECALRuntimeProvider(name,
+				interpreter.WithImportLocator(importLocator), interpreter.WithLogger(logger))
 		}
 	}
 
@@ -205,6 +209,9 @@ func (i *CLIInterpreter) LoadInitialFile(tid uint64) error {
 
 		if err == nil {
 			if ast, err = parser.ParseWithRuntime(i.EntryFile, string(initFile), i.RuntimeProvider); err == nil {
+				if i.RuntimeProvider.Debugger != nil {
+					i.RuntimeProvider.Debugger.NotifySourceLoaded(i.EntryFile)
+				}
 				if err = ast.Runtime.Validate(); err == nil {
 					_, err = ast.Runtime.Eval(i.GlobalVS, make(map[string]interface{}), tid)
 				}
@@ -251,7 +258,7 @@ func (i *CLIInterpreter) Interpret(interactive bool) error {
 		err = i.CreateTerm()
 
 		if interactive {
-			fmt.Fprintln(i.LogOut, fmt.Sprintf("ECAL %v", config.ProductVersion))
+			fmt.Fprintln(i.LogOut, fmt.Sprintf("ECAL %v", config.ProductVersion()))
 		}
 
 		// Create Runtime Provider
@@ -367,7 +374,7 @@ HandleInput handles input to this interpreter. It parses a given input line
 and outputs on the given output terminal. Requires a thread ID of the executing
 thread - use the RuntimeProvider to generate a unique one.
 */
-func (i *CLIInterpreter) HandleInput(ot OutputTerminal, line string, tid uint64) {
+func (i *CLIInterpreter) HandleInput(ot util.OutputTerminal, line string, tid uint64) {
 
 	// Process the entered line
 
@@ -375,7 +382,7 @@ func (i *CLIInterpreter) HandleInput(ot OutputTerminal, line string, tid uint64)
 
 		// Show help
 
-		ot.WriteString(fmt.Sprintf("ECAL %v\n", config.ProductVersion))
+		ot.WriteString(fmt.Sprintf("ECAL %v\n", config.ProductVersion()))
 		ot.WriteString(fmt.Sprint("\n"))
 		ot.WriteString(fmt.Sprint("Console supports all normal ECAL statements and the following special commands:\n"))
 		ot.WriteString(fmt.Sprint("\n"))
@@ -432,7 +439,7 @@ func (i *CLIInterpreter) HandleInput(ot OutputTerminal, line string, tid uint64)
 /*
 handleSpecialStatements handles inbuild special statements.
 */
-func (i *CLIInterpreter) handleSpecialStatements(ot OutputTerminal, line string) bool {
+func (i *CLIInterpreter) handleSpecialStatements(ot util.OutputTerminal, line string) bool {
 
 	if strings.HasPrefix(line, "@prof") {
 		args := strings.Split(line, " ")[1:]
@@ -474,11 +481,11 @@ func (i *CLIInterpreter) handleSpecialStatements(ot OutputTerminal, line string)
 /*
 displaySymbols lists all available inbuild functions and available stdlib packages of ECAL.
 */
-func (i *CLIInterpreter) displaySymbols(ot OutputTerminal, args []string) {
+func (i *CLIInterpreter) displaySymbols(ot util.OutputTerminal, args []string) {
 
 	tabData := []string{"Inbuild function", "Description"}
 
-	for name, f := range interpreter.InbuildFuncMap {
+	for name, f := range interpreter.InbuildFuncs() {
 		ds, _ := f.DocString()
 
 		if len(args) > 0 && !matchesFulltextSearch(ot, fmt.Sprintf("%v %v", name, ds), args[0]) {
@@ -516,7 +523,7 @@ func (i *CLIInterpreter) displaySymbols(ot OutputTerminal, args []string) {
 /*
 displayPackage list all available constants and functions of a stdlib package.
 */
-func (i *CLIInterpreter) displayPackage(ot OutputTerminal, args []string) {
+func (i *CLIInterpreter) displayPackage(ot util.OutputTerminal, args []string) {
 
 	_, constSymbols, funcSymbols := stdlib.GetStdlibSymbols()
 
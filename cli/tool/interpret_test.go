@@ -27,6 +27,7 @@ import (
 	"github.com/rhedin/Abe_ecal/config"
 	"github.com/rhedin/Abe_ecal/engine"
 	"github.com/rhedin/Abe_ecal/interpreter"
+	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/stdlib"
 	"github.com/rhedin/Abe_ecal/util"
 )
@@ -288,7 +289,7 @@ func TestInterpret(t *testing.T) {
 		return
 	}
 
-	if testLogOut.String() != `ECAL `+config.ProductVersion+`
+	if testLogOut.String() != `ECAL `+config.ProductVersion().String()+`
 Log level: info - Root directory: tooltest
 123
 Type 'q' or 'quit' to exit the shell and '?' to get help
@@ -394,3 +395,110 @@ ECAL error in foo (console input): 123 () (Line:1 Pos:1)
 		return
 	}
 }
+
+func TestDisplayRegisteredPackage(t *testing.T) {
+	tin := newTestInterpreterWithConfig()
+	defer tearDown()
+
+	if err := tin.CreateRuntimeProvider("foo"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := stdlib.RegisterPackage("customPkg", "Custom package", map[string]util.ECALFunction{
+		"Atoi": stdlib.NewECALFunctionAdapter(reflect.ValueOf(strconv.Atoi), "Converts a string."),
+	}, map[string]interface{}{
+		"Answer": 42,
+	}); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+	defer stdlib.UnregisterPackage("customPkg")
+
+	tin.HandleInput(testTerm, "@std customPkg", tin.RuntimeProvider.NewThreadID())
+
+	if testTerm.out.String() != `
+╒═════════════════╤══════╕
+│Constant         │Value │
+╞═════════════════╪══════╡
+│customPkg.Answer │42    │
+│                 │      │
+╘═════════════════╧══════╛
+╒═══════════════╤═══════════════════╕
+│Function       │Description        │
+╞═══════════════╪═══════════════════╡
+│customPkg.Atoi │Converts a string. │
+│               │                   │
+╘═══════════════╧═══════════════════╛
+`[1:] {
+		t.Error("Unexpected result:", testTerm.out.String())
+		return
+	}
+}
+
+type testEmbeddedHandler struct {
+	handled []string
+}
+
+func (t *testEmbeddedHandler) CanHandle(s string) bool {
+	return strings.HasPrefix(s, "!")
+}
+
+func (t *testEmbeddedHandler) Handle(ot util.OutputTerminal, input string) {
+	t.handled = append(t.handled, input)
+	ot.WriteString(fmt.Sprintln("handled", input))
+}
+
+func (t *testEmbeddedHandler) LoadInitialFile(tid uint64) error {
+	return nil
+}
+
+func TestEmbeddedCLIInterpreter(t *testing.T) {
+	var handler InputHandler = &testEmbeddedHandler{}
+
+	erp := interpreter.NewECALRuntimeProvider("embedded",
+		interpreter.WithImportLocator(&util.MemoryImportLocator{Files: make(map[string]string)}),
+		interpreter.WithLogger(util.NewMemoryLogger(10)))
+
+	tin := &CLIInterpreter{
+		GlobalVS:             scope.NewGlobalScope(),
+		RuntimeProvider:      erp,
+		CustomWelcomeMessage: "Welcome",
+		CustomHelpString:     "    !cmd - A custom command.\n",
+		CustomHandler:        handler.(CLICustomHandler),
+		LogOut:               &bytes.Buffer{},
+	}
+
+	ot := &testOutputTerminal{}
+	tid := erp.NewThreadID()
+
+	tin.HandleInput(ot, "a := 1 + 2", tid)
+	tin.HandleInput(ot, "a * 2", tid)
+
+	if res := ot.b.String(); res != "6\n" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, _, _ := tin.GlobalVS.GetValue("a"); res != float64(3) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ot.b.Reset()
+	tin.HandleInput(ot, "!cmd foo", tid)
+
+	if res := ot.b.String(); res != "handled !cmd foo\n" ||
+		len(handler.(*testEmbeddedHandler).handled) != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ot.b.Reset()
+	tin.HandleInput(ot, "?", tid)
+
+	if res := ot.b.String(); !strings.Contains(res, "!cmd - A custom command.") {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -326,13 +326,14 @@ func runInterpreter(reader io.ReaderAt, size int64) (interface{}, error) {
 	if err == nil {
 		var ast *parser.ASTNode
 
-		erp := interpreter.NewECALRuntimeProvider(osArgs[0], il, util.NewStdOutLogger())
+		erp := interpreter.NewECALRuntimeProvider(osArgs[0],
+			interpreter.WithImportLocator(il), interpreter.WithLogger(util.NewStdOutLogger()))
 
 		if ast, err = parser.ParseWithRuntime(os.Args[0], il.Files[".ecalsrc-entry"], erp); err == nil {
 			if err = ast.Runtime.Validate(); err == nil {
 				var osArgs []interface{}
 
-				vs := scope.NewScope(scope.GlobalScope)
+				vs := scope.NewGlobalScope()
 				for _, arg := range os.Args {
 					osArgs = append(osArgs, arg)
 				}
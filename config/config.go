@@ -12,7 +12,9 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/rhedin/Abe_common/errorutil"
 )
@@ -21,9 +23,14 @@ import (
 // ================
 
 /*
-ProductVersion is the current version of ECAL
+productVersion is the current version of ECAL as a string (updated by the release process)
 */
-const ProductVersion = "1.6.2"
+const productVersion = "1.6.2"
+
+/*
+currentVersion is the parsed current version of ECAL
+*/
+var currentVersion = mustParseVersion(productVersion)
 
 /*
 Known configuration options for ECAL
@@ -95,3 +102,149 @@ func Bool(key string) bool {
 
 	return ret
 }
+
+// Version handling
+// ================
+
+/*
+Version is a semantic version number.
+*/
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+}
+
+/*
+versionRegexp matches semantic version strings.
+*/
+var versionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?$`)
+
+/*
+ParseVersion parses a version string of the form major.minor.patch[-prerelease].
+*/
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	m := versionRegexp.FindStringSubmatch(s)
+
+	if m == nil {
+		return v, fmt.Errorf("Invalid version: %v", s)
+	}
+
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	v.PreRelease = m[4]
+
+	return v, nil
+}
+
+/*
+mustParseVersion parses a version string and panics if it is not valid.
+*/
+func mustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+
+	errorutil.AssertOk(err)
+
+	return v
+}
+
+/*
+String returns the version as a string.
+*/
+func (v Version) String() string {
+	res := fmt.Sprintf("%v.%v.%v", v.Major, v.Minor, v.Patch)
+
+	if v.PreRelease != "" {
+		res = fmt.Sprintf("%v-%v", res, v.PreRelease)
+	}
+
+	return res
+}
+
+/*
+Compare compares this version with another version. Returns -1 if this
+version is lower, 0 if both are equal and 1 if this version is higher. A
+pre-release version is lower than the corresponding release version.
+*/
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+
+	if v.PreRelease == other.PreRelease {
+		return 0
+	} else if v.PreRelease == "" {
+		return 1
+	} else if other.PreRelease == "" {
+		return -1
+	}
+
+	return comparePreRelease(v.PreRelease, other.PreRelease)
+}
+
+/*
+comparePreRelease compares two pre-release strings according to semantic
+versioning. The dot separated identifiers are compared from left to right.
+Numeric identifiers are compared numerically and have a lower precedence than
+alphanumeric identifiers. A shorter list of identifiers has a lower precedence
+if all preceding identifiers are equal.
+*/
+func comparePreRelease(p1 string, p2 string) int {
+	ids1 := strings.Split(p1, ".")
+	ids2 := strings.Split(p2, ".")
+
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		n1, err1 := strconv.ParseUint(ids1[i], 10, 64)
+		n2, err2 := strconv.ParseUint(ids2[i], 10, 64)
+
+		switch {
+		case err1 == nil && err2 == nil && n1 != n2:
+			if n1 < n2 {
+				return -1
+			}
+			return 1
+
+		case err1 == nil && err2 != nil:
+			return -1
+
+		case err1 != nil && err2 == nil:
+			return 1
+
+		case ids1[i] < ids2[i]:
+			return -1
+
+		case ids1[i] > ids2[i]:
+			return 1
+		}
+	}
+
+	if len(ids1) < len(ids2) {
+		return -1
+	} else if len(ids1) > len(ids2) {
+		return 1
+	}
+
+	return 0
+}
+
+/*
+ProductVersion returns the current version of ECAL.
+*/
+func ProductVersion() Version {
+	return currentVersion
+}
+
+/*
+VersionAtLeast checks if the current version of ECAL is at least a given version.
+*/
+func VersionAtLeast(v Version) bool {
+	return currentVersion.Compare(v) >= 0
+}
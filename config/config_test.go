@@ -33,3 +33,127 @@ func TestConfig(t *testing.T) {
 		return
 	}
 }
+
+func TestVersion(t *testing.T) {
+
+	if res := ProductVersion().String(); res != productVersion {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	v, err := ParseVersion("1.12.3-beta.1")
+
+	if err != nil || v.Major != 1 || v.Minor != 12 || v.Patch != 3 || v.PreRelease != "beta.1" {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	if res := v.String(); res != "1.12.3-beta.1" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if v, err = ParseVersion("v2.0.1"); err != nil || v.String() != "2.0.1" {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	for _, s := range []string{"", "1.2", "1.2.x", "1.2.3-", "a1.2.3"} {
+		if _, err := ParseVersion(s); err == nil || err.Error() != "Invalid version: "+s {
+			t.Error("Unexpected result:", s, err)
+			return
+		}
+	}
+
+	compare := func(s1 string, s2 string) int {
+		v1, _ := ParseVersion(s1)
+		v2, _ := ParseVersion(s2)
+		return v1.Compare(v2)
+	}
+
+	if res := compare("1.2.3", "1.2.3"); res != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.2.3", "1.2.4"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.3.0", "1.2.9"); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("2.0.0", "10.0.0"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.2.3-alpha", "1.2.3"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.2.3", "1.2.3-alpha"); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.2.3-alpha", "1.2.3-beta"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.2.3-beta", "1.2.3-alpha"); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-rc.2", "1.0.0-rc.10"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-rc.10", "1.0.0-rc.2"); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-1", "1.0.0-alpha"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-alpha", "1.0.0-alpha.1"); res != -1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-alpha.beta", "1.0.0-alpha.1"); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := compare("1.0.0-rc.1", "1.0.0-rc.1"); res != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The current version cannot be changed through the returned value
+
+	pv := ProductVersion()
+	pv.Major++
+
+	if ProductVersion().String() != productVersion {
+		t.Error("Unexpected result:", ProductVersion())
+		return
+	}
+
+	if !VersionAtLeast(Version{1, 0, 0, ""}) || !VersionAtLeast(ProductVersion()) ||
+		VersionAtLeast(Version{ProductVersion().Major + 1, 0, 0, ""}) {
+		t.Error("Unexpected result")
+		return
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+/*
+Package ecaltest contains utilities for testing Go code which uses ECAL.
+*/
+package ecaltest
+
+import (
+	"fmt"
+
+	"github.com/rhedin/Abe_common/datautil"
+	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/scope"
+)
+
+/*
+DefaultTestLoggerBufferSize is the default number of messages which are kept
+by a TestLogger.
+*/
+const DefaultTestLoggerBufferSize = 20
+
+/*
+TestLoggerFunctionName is the name of the test logger function in a scope
+returned by NewTestScope.
+*/
+const TestLoggerFunctionName = "testlog"
+
+/*
+TestLogger is a simple ECAL function which records all its calls in a ring buffer.
+*/
+type TestLogger struct {
+	buf *datautil.RingBuffer
+}
+
+/*
+NewTestLogger returns a new test logger which keeps the default number of messages.
+*/
+func NewTestLogger() *TestLogger {
+	return NewTestLoggerWithBuffer(DefaultTestLoggerBufferSize)
+}
+
+/*
+NewTestLoggerWithBuffer returns a new test logger which keeps the last n messages.
+*/
+func NewTestLoggerWithBuffer(n int) *TestLogger {
+	return &TestLogger{datautil.NewRingBuffer(n)}
+}
+
+/*
+NewTestScope returns a new global scope which has a test logger installed as
+the function testlog.
+*/
+func NewTestScope() parser.Scope {
+	vs := scope.NewGlobalScope()
+	vs.SetValue(TestLoggerFunctionName, NewTestLogger())
+	return vs
+}
+
+/*
+Buffer returns the ring buffer which holds the recorded messages.
+*/
+func (tl *TestLogger) Buffer() *datautil.RingBuffer {
+	return tl.buf
+}
+
+/*
+Run executes this function.
+*/
+func (tl *TestLogger) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	tl.buf.Add(fmt.Sprint(args...))
+	return nil, nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (tl *TestLogger) DocString() (string, error) {
+	return "testlogger docstring", nil
+}
+
+/*
+String returns a string representation of this function.
+*/
+func (tl *TestLogger) String() string {
+	return "TestLogger"
+}
+
+/*
+MarshalJSON returns a string representation of this function as JSON.
+*/
+func (tl *TestLogger) MarshalJSON() ([]byte, error) {
+	return []byte(tl.String()), nil
+}
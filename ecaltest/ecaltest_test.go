@@ -0,0 +1,95 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package ecaltest
+
+import (
+	"testing"
+
+	"github.com/rhedin/Abe_ecal/interpreter"
+	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
+)
+
+func TestTestLogger(t *testing.T) {
+	tl := NewTestLoggerWithBuffer(2)
+
+	if res, err := tl.Run("", nil, nil, 0, []interface{}{"foo", 1}); res != nil || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+	tl.Run("", nil, nil, 0, []interface{}{"bar"})
+	tl.Run("", nil, nil, 0, []interface{}{"baz"})
+
+	if res := tl.Buffer().String(); res != "bar\nbaz" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, _ := tl.DocString(); res != "testlogger docstring" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, _ := tl.MarshalJSON(); string(res) != tl.String() || tl.String() != "TestLogger" {
+		t.Error("Unexpected result:", string(res))
+		return
+	}
+}
+
+func TestTestScope(t *testing.T) {
+
+	// Evaluate ECAL code with a prepared test scope
+
+	vs := NewTestScope()
+
+	_, err := interpreter.UnitTestEvalAndASTAndImport(`
+for a in range(1, 3) {
+	testlog("Number: ", a)
+}
+`, vs, "", &util.MemoryImportLocator{Files: make(map[string]string)})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Inspect the recorded messages
+
+	f, ok, _ := vs.GetValue(TestLoggerFunctionName)
+	if !ok {
+		t.Error("Test logger should be defined")
+		return
+	}
+
+	if res := f.(*TestLogger).Buffer().String(); res != `
+Number: 1
+Number: 2
+Number: 3`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Install a test logger into an existing scope
+
+	vs = scope.NewScope("myscope")
+	tl := NewTestLogger()
+	vs.SetValue("mylog", tl)
+
+	if _, err = interpreter.UnitTestEvalAndASTAndImport(`mylog("test")`, vs, "", nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := tl.Buffer().String(); res != "test" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -87,6 +87,12 @@ type Monitor interface {
 	*/
 	EventPath() []*Event
 
+	/*
+		EventHistory returns the chain of events which created this monitor. Unlike
+		EventPath this can be called while the monitor is still active.
+	*/
+	EventHistory() []*Event
+
 	/*
 	   EventPathString returns the event path as a string.
 	*/
@@ -241,6 +247,14 @@ EventPath returns the chain of events which created this monitor.
 func (mb *monitorBase) EventPath() []*Event {
 	errorutil.AssertTrue(mb.finished, "Cannot get event path on an unfinished monitor")
 
+	return mb.EventHistory()
+}
+
+/*
+EventHistory returns the chain of events which created this monitor. Unlike
+EventPath this can be called while the monitor is still active.
+*/
+func (mb *monitorBase) EventHistory() []*Event {
 	path := []*Event{mb.event}
 
 	child := mb.Parent
@@ -398,6 +398,24 @@ func (tp *ThreadPool) WorkerCount() int {
 	return len(tp.workerMap)
 }
 
+/*
+ActiveWorkerCount returns the current count of workers which are not idle.
+*/
+func (tp *ThreadPool) ActiveWorkerCount() int {
+	tp.workerMapLock.Lock()
+	defer tp.workerMapLock.Unlock()
+	return len(tp.workerMap) - len(tp.workerIdleMap)
+}
+
+/*
+QueueSize returns the current count of tasks which are waiting in the queue.
+*/
+func (tp *ThreadPool) QueueSize() int {
+	tp.queueLock.Lock()
+	defer tp.queueLock.Unlock()
+	return tp.queue.Size()
+}
+
 /*
 WaitAll waits for all workers to become idle.
 */
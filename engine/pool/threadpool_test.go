@@ -354,6 +354,11 @@ func TestThreadPoolThresholds(t *testing.T) {
 		return
 	}
 
+	if awc, qs := tp.ActiveWorkerCount(), tp.QueueSize(); awc != 0 || qs != 0 {
+		t.Error("Unexpected result:", awc, qs)
+		return
+	}
+
 	if taskFinishCounter != 20 {
 		t.Error("Unexpected result:", taskFinishCounter)
 		return
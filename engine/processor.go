@@ -41,6 +41,17 @@ type Processor interface {
 	*/
 	Workers() int
 
+	/*
+	   EventCount returns the number of events which are either waiting to be
+	   processed or are currently being processed.
+	*/
+	EventCount() int
+
+	/*
+	   ActiveWorkers returns the number of threads which are currently processing events.
+	*/
+	ActiveWorkers() int
+
 	/*
 	   Reset removes all stored rules from this processor.
 	*/
@@ -185,6 +196,21 @@ func (p *eventProcessor) Workers() int {
 	return p.workerCount
 }
 
+/*
+EventCount returns the number of events which are either waiting to be
+processed or are currently being processed.
+*/
+func (p *eventProcessor) EventCount() int {
+	return p.pool.QueueSize() + p.pool.ActiveWorkerCount()
+}
+
+/*
+ActiveWorkers returns the number of threads which are currently processing events.
+*/
+func (p *eventProcessor) ActiveWorkers() int {
+	return p.pool.ActiveWorkerCount()
+}
+
 /*
 Reset removes all stored rules from this processor.
 */
@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_common/sortutil"
+	"github.com/rhedin/Abe_common/stringutil"
 )
 
 /*
@@ -468,6 +470,7 @@ type RuleMatcherKey struct {
 	bitsAny     uint64
 	bitsValue   map[interface{}]uint64
 	bitsRegexes map[uint64]*regexp.Regexp
+	bitsMaps    map[uint64]map[interface{}]interface{}
 }
 
 /*
@@ -490,6 +493,14 @@ func (rm *RuleMatcherKey) addRule(num uint, bit uint64, key string, value interf
 		rm.bitsAny |= bit
 		rm.bitsRegexes[bit] = regex
 
+	} else if pattern, ok := toStateMap(value); ok {
+
+		// Nested maps are matched like regexes - the presence of the key
+		// is checked before the nested map is matched
+
+		rm.bitsAny |= bit
+		rm.bitsMaps[bit] = pattern
+
 	} else {
 		rm.bitsValue[value] |= bit
 	}
@@ -501,7 +512,7 @@ match adds matching rules to a given bit mask.
 func (rm *RuleMatcherKey) match(bits uint64, value interface{}) uint64 {
 	toRemove := rm.bitsAny ^ rm.bits
 
-	if value != nil {
+	if value != nil && reflect.TypeOf(value).Comparable() {
 		if additionalBits, ok := rm.bitsValue[value]; ok {
 			toRemove = rm.bitsAny | additionalBits ^ rm.bits
 		}
@@ -519,9 +530,67 @@ func (rm *RuleMatcherKey) match(bits uint64, value interface{}) uint64 {
 		}
 	}
 
+	for bm, pattern := range rm.bitsMaps {
+
+		if keyMatchedBits&bm > 0 && !nestedStateMatch(pattern, value) {
+
+			// Nested map does not match remove the bit
+
+			keyMatchedBits ^= keyMatchedBits & bm
+		}
+	}
+
 	return keyMatchedBits
 }
 
+/*
+nestedStateMatch checks if a nested map pattern matches a value of an event
+state. NULL values in the pattern match any value.
+*/
+func nestedStateMatch(pattern map[interface{}]interface{}, value interface{}) bool {
+	valueMap, ok := toStateMap(value)
+
+	if !ok {
+		return false
+	}
+
+	for k, v := range pattern {
+		sv, ok := valueMap[k]
+
+		if !ok {
+			return false
+		}
+
+		if nestedPattern, ok := toStateMap(v); ok {
+			if !nestedStateMatch(nestedPattern, sv) {
+				return false
+			}
+		} else if v != nil && !reflect.DeepEqual(v, sv) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+toStateMap converts a given value into a state map if possible.
+*/
+func toStateMap(value interface{}) (map[interface{}]interface{}, bool) {
+	switch m := value.(type) {
+	case map[interface{}]interface{}:
+		return m, true
+	case map[string]interface{}:
+		res := make(map[interface{}]interface{})
+		for k, v := range m {
+			res[k] = v
+		}
+		return res, true
+	}
+
+	return nil, false
+}
+
 /*
 unmatch removes all registered rules in this
 */
@@ -567,6 +636,23 @@ func (rm *RuleMatcherKey) String() string {
 
 	buf.WriteString("]")
 
+	if len(rm.bitsMaps) > 0 {
+		var mkeys []uint64
+		for k := range rm.bitsMaps {
+			mkeys = append(mkeys, k)
+		}
+
+		sortutil.UInt64s(mkeys)
+
+		buf.WriteString(" [")
+
+		for _, k := range mkeys {
+			buf.WriteString(fmt.Sprintf("%08X:%v ", k, stringutil.ConvertToString(rm.bitsMaps[k])))
+		}
+
+		buf.WriteString("]")
+	}
+
 	return buf.String()
 }
 
@@ -612,7 +698,8 @@ func (ri *RuleIndexState) addRuleAtLevel(rule *Rule, kindMatchLevel []string) {
 		var keyMatcher *RuleMatcherKey
 
 		if keyMatcher, ok = ri.keyMap[k]; !ok {
-			keyMatcher = &RuleMatcherKey{0, 0, make(map[interface{}]uint64), make(map[uint64]*regexp.Regexp)}
+			keyMatcher = &RuleMatcherKey{0, 0, make(map[interface{}]uint64), make(map[uint64]*regexp.Regexp),
+				make(map[uint64]map[interface{}]interface{})}
 			ri.keyMap[k] = keyMatcher
 		}
 
@@ -496,6 +496,97 @@ core - RuleIndexKind (0)
 	}
 }
 
+func TestRuleIndexStateNestedMatch(t *testing.T) {
+	ruleindexidcounter = 0
+	defer func() {
+		ruleindexidcounter = 0
+	}()
+
+	rule1 := &Rule{
+		"TestRule1",                  // Name
+		"",                           // Description
+		[]string{"core.main.tester"}, // Kind match
+		[]string{"data.read"},        // Match on event cascade scope
+		map[string]interface{}{ // Match on event state
+			"user": map[interface{}]interface{}{
+				"role": "admin",
+				"id":   nil,
+			},
+		},
+		0,          // Priority of the rule
+		[]string{}, // List of suppressed rules by this rule
+		func(p Processor, m Monitor, e *Event, tid uint64) error { // Action of the rule
+			return nil
+		},
+	}
+
+	index := NewRuleIndex()
+	index.AddRule(rule1)
+
+	if res := index.String(); res != `
+core - RuleIndexKind (0)
+  main - RuleIndexKind (1)
+    tester - RuleIndexKind (2)
+      RuleIndexState (3) [TestRule1 ]
+        user - 00000001 *:00000001 [] [] [00000001:{"id":null,"role":"admin"} ]
+`[1:] {
+		t.Error("Unexpected index layout:", res)
+		return
+	}
+
+	if res := index.Match(&Event{
+		"bla",
+		[]string{"core", "main", "tester"},
+		map[interface{}]interface{}{ // Match on event state
+			"user": map[interface{}]interface{}{
+				"role": "admin",
+				"id":   []interface{}{1, 2},
+			},
+		},
+	}); printRules(res) != "[TestRule1]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := index.Match(&Event{
+		"bla",
+		[]string{"core", "main", "tester"},
+		map[interface{}]interface{}{ // Match on event state
+			"user": map[interface{}]interface{}{
+				"role": "guest",
+				"id":   1,
+			},
+		},
+	}); printRules(res) != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := index.Match(&Event{
+		"bla",
+		[]string{"core", "main", "tester"},
+		map[interface{}]interface{}{ // Match on event state
+			"user": map[interface{}]interface{}{
+				"role": "admin",
+			},
+		},
+	}); printRules(res) != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := index.Match(&Event{
+		"bla",
+		[]string{"core", "main", "tester"},
+		map[interface{}]interface{}{ // Match on event state
+			"user": []interface{}{"admin"},
+		},
+	}); printRules(res) != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func printRules(rules []*Rule) string {
 	var ret []string
 
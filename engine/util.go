@@ -11,8 +11,11 @@
 package engine
 
 import (
+	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/rhedin/Abe_common/stringutil"
 )
 
 // Globals
@@ -72,9 +75,14 @@ func (rs *RuleScope) IsAllowedAll(scopePaths []string) bool {
 }
 
 /*
-IsAllowed checks if a given scope path is allowed within this rule scope.
+IsAllowed checks if a given scope path is allowed within this rule scope. The
+scope path may contain glob wildcards (e.g. data.*).
 */
 func (rs *RuleScope) IsAllowed(scopePath string) bool {
+	if strings.Contains(scopePath, RuleKindWildcard) {
+		return rs.isAllowedGlob(scopePath)
+	}
+
 	allowed := false
 	scopeDefs := rs.scopeDefs
 
@@ -99,6 +107,57 @@ func (rs *RuleScope) IsAllowed(scopePath string) bool {
 	return allowed
 }
 
+/*
+isAllowedGlob checks if a scope path with glob wildcards is allowed. This is
+the case if the path before the first wildcard is allowed or if any allowed
+definition of this rule scope matches the glob.
+*/
+func (rs *RuleScope) isAllowedGlob(scopePath string) bool {
+	prefix := strings.TrimSuffix(scopePath[:strings.Index(scopePath, RuleKindWildcard)], ".")
+
+	if prefix != "" && rs.IsAllowed(prefix) {
+		return true
+	}
+
+	re, err := stringutil.GlobToRegex(scopePath)
+
+	if err != nil {
+		return false
+	}
+
+	matcher, err := regexp.Compile("^" + re + "$")
+
+	if err != nil {
+		return false
+	}
+
+	var match func(scopeDefs map[string]interface{}, path string) bool
+
+	match = func(scopeDefs map[string]interface{}, path string) bool {
+		for k, v := range scopeDefs {
+			if k == ruleScopeAllowFlag {
+				if v.(bool) && matcher.MatchString(path) {
+					return true
+				}
+				continue
+			}
+
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+
+			if match(v.(map[string]interface{}), childPath) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return match(rs.scopeDefs, "")
+}
+
 /*
 AddAll adds all given definitions to the rule scope.
 */
@@ -74,4 +74,36 @@ func TestRuleScope(t *testing.T) {
 		t.Error("Unexpected result")
 		return
 	}
+
+	if rs.IsAllowed("test.*") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	// Test glob scope paths
+
+	rs = NewRuleScope(map[string]bool{
+		"test.first.read":  true,
+		"test.first.write": false,
+		"test.second":      true,
+	})
+
+	if !rs.IsAllowed("test.*") || !rs.IsAllowed("test.first.*") || !rs.IsAllowed("test.second.*") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	if rs.IsAllowed("test.first.w*") || rs.IsAllowed("test.third.*") || rs.IsAllowed("foo.*") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	rs = NewRuleScope(map[string]bool{
+		"": true,
+	})
+
+	if !rs.IsAllowed("*") || !rs.IsAllowed("test.*") {
+		t.Error("Unexpected result")
+		return
+	}
 }
@@ -2,4 +2,7 @@ module github.com/rhedin/Abe_ecal
 
 go 1.25
 
-require github.com/rhedin/Abe_common v1.5.2
+require (
+	github.com/rhedin/Abe_common v1.5.2
+	golang.org/x/net v0.45.0
+)
@@ -16,6 +16,8 @@ package interpreter
 import (
 	"fmt"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +40,7 @@ type ecalDebugger struct {
 	callStackVsSnapshots       map[uint64][]map[string]interface{} // Call stack variable scope snapshots of threads
 	callStackGlobalVsSnapshots map[uint64][]map[string]interface{} // Call stack global variable scope snapshots of threads
 	sources                    map[string]bool                     // All known sources
+	sourceNodes                map[string]*parser.ASTNode          // First visited node of all known source lines
 	breakOnStart               bool                                // Flag to stop at the start of the next execution
 	breakOnError               bool                                // Flag to stop if an error occurs
 	globalScope                parser.Scope                        // Global variable scope which can be used to transfer data
@@ -46,6 +49,20 @@ type ecalDebugger struct {
 	mutexeOwners               map[string]uint64                   // A map of current mutex owners
 	mutexLog                   *datautil.RingBuffer                // A log of taken mutexes
 	threadpool                 *pool.ThreadPool                    // Reference to the thread pool of the processor
+	logger                     util.Logger                         // Reference to the logger of the runtime provider
+	watches                    map[uint64]map[string]string        // Watch expressions of threads with their last known value
+	watchListeners             map[string]util.WatchListener       // Listeners for changed watch expressions
+	traces                     map[uint64][]*TraceEntry            // Execution traces of threads
+}
+
+/*
+TraceEntry is a single visited node in the execution trace of a thread.
+*/
+type TraceEntry struct {
+	Node       *parser.ASTNode   // Visited node
+	Source     string            // Source of the visited node
+	Line       int               // Line of the visited node
+	VSSnapshot map[string]string // Names and types of the variables in the local scopes
 }
 
 /*
@@ -59,6 +76,7 @@ type interrogationState struct {
 	node         *parser.ASTNode   // Node on which the thread was last stopped
 	vs           parser.Scope      // Variable scope of the thread when it was last stopped
 	err          error             // Error which was returned by a function call
+	pausedAt     time.Time         // Time when the thread was last suspended
 }
 
 /*
@@ -76,6 +94,7 @@ const (
 	StepOver                         // Step over the next function
 	Resume                           // Resume execution - do not break again on the same line
 	Kill                             // Resume execution - and kill the thread on the next state change
+	Abort                            // Resume execution - and return an error on the next state change
 )
 
 /*
@@ -90,6 +109,7 @@ func newInterrogationState(node *parser.ASTNode, vs parser.Scope) *interrogation
 		node,
 		vs,
 		nil,
+		time.Now(),
 	}
 }
 
@@ -104,6 +124,7 @@ func NewECALDebugger(globalVS parser.Scope) util.ECALDebugger {
 		callStackVsSnapshots:       make(map[uint64][]map[string]interface{}),
 		callStackGlobalVsSnapshots: make(map[uint64][]map[string]interface{}),
 		sources:                    make(map[string]bool),
+		sourceNodes:                make(map[string]*parser.ASTNode),
 		breakOnStart:               false,
 		breakOnError:               true,
 		globalScope:                globalVS,
@@ -112,6 +133,9 @@ func NewECALDebugger(globalVS parser.Scope) util.ECALDebugger {
 		mutexeOwners:               nil,
 		mutexLog:                   nil,
 		threadpool:                 nil,
+		watches:                    make(map[uint64]map[string]string),
+		watchListeners:             make(map[string]util.WatchListener),
+		traces:                     make(map[uint64][]*TraceEntry),
 	}
 }
 
@@ -206,6 +230,28 @@ func (ed *ecalDebugger) SetThreadPool(tp *pool.ThreadPool) {
 	}
 }
 
+/*
+SetLogger sets the reference to the current used logger.
+*/
+func (ed *ecalDebugger) SetLogger(logger util.Logger) {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	if ed.logger == nil {
+		ed.logger = logger
+	}
+}
+
+/*
+Logger returns the current used logger.
+*/
+func (ed *ecalDebugger) Logger() util.Logger {
+	ed.lock.RLock()
+	defer ed.lock.RUnlock()
+
+	return ed.logger
+}
+
 /*
 VisitState is called for every state during the execution of a program.
 */
@@ -233,17 +279,43 @@ func (ed *ecalDebugger) VisitState(node *parser.ASTNode, vs parser.Scope, tid ui
 		ed.lock.RLock()
 		is, ok := ed.interrogationStates[tid]
 		_, sourceKnown := ed.sources[node.Token.Lsource]
+		_, lineKnown := ed.sourceNodes[targetIdentifier]
+		_, tracing := ed.traces[tid]
 		ed.lock.RUnlock()
 
+		if tracing {
+			entry := &TraceEntry{node, node.Token.Lsource, node.Token.Lline, ed.buildVsTypeSnapshot(vs)}
+
+			ed.lock.Lock()
+			if trace, ok := ed.traces[tid]; ok {
+				ed.traces[tid] = append(trace, entry)
+			}
+			ed.lock.Unlock()
+		}
+
 		if !sourceKnown {
 			ed.RecordSource(node.Token.Lsource)
 		}
 
+		if !lineKnown {
+
+			// The first visited node of a line is the outermost node of
+			// the statement on this line
+
+			ed.lock.Lock()
+			if _, ok := ed.sourceNodes[targetIdentifier]; !ok {
+				ed.sourceNodes[targetIdentifier] = node
+			}
+			ed.lock.Unlock()
+		}
+
 		if ok {
 
 			// The thread is being interrogated
 
 			switch is.cmd {
+			case Abort:
+				return ed.abortThread(node, tid)
 			case Resume, Kill:
 				if is.node.Token.Lline != node.Token.Lline {
 
@@ -265,10 +337,17 @@ func (ed *ecalDebugger) VisitState(node *parser.ASTNode, vs parser.Scope, tid ui
 					is.node = node
 					is.vs = vs
 					is.running = false
+					is.pausedAt = time.Now()
+
+					ed.checkWatches(tid, vs)
 
 					is.cond.L.Lock()
 					is.cond.Wait()
 					is.cond.L.Unlock()
+
+					if is.cmd == Abort {
+						return ed.abortThread(node, tid)
+					}
 				}
 			}
 
@@ -284,15 +363,34 @@ func (ed *ecalDebugger) VisitState(node *parser.ASTNode, vs parser.Scope, tid ui
 			ed.interrogationStates[tid] = is
 			ed.lock.Unlock()
 
+			ed.checkWatches(tid, vs)
+
 			is.cond.L.Lock()
 			is.cond.Wait()
 			is.cond.L.Unlock()
+
+			if is.cmd == Abort {
+				return ed.abortThread(node, tid)
+			}
 		}
 	}
 
 	return nil
 }
 
+/*
+abortThread removes the interrogation state of an aborted thread and returns
+the error which terminates it.
+*/
+func (ed *ecalDebugger) abortThread(node *parser.ASTNode, tid uint64) util.TraceableRuntimeError {
+	ed.lock.Lock()
+	delete(ed.interrogationStates, tid)
+	ed.lock.Unlock()
+
+	return util.NewRuntimeError(node.Token.Lsource, util.ErrAborted,
+		fmt.Sprintf("Thread %v was aborted by the debugger", tid), node).(util.TraceableRuntimeError)
+}
+
 /*
 VisitStepInState is called before entering a function call.
 */
@@ -352,10 +450,10 @@ func (ed *ecalDebugger) VisitStepOutState(node *parser.ASTNode, vs parser.Scope,
 	threadCallStackGlobalVs := ed.callStackGlobalVsSnapshots[tid]
 	lastIndex := len(threadCallStack) - 1
 
-	ok, cerr := threadCallStack[lastIndex].Equals(node, false) // Sanity check step in node must be the same as step out node
-	errorutil.AssertTrue(ok,
-		fmt.Sprintf("Unexpected callstack when stepping out - callstack: %v - funccall: %v - comparison error: %v",
-			threadCallStack, node, cerr))
+	diff := threadCallStack[lastIndex].Diff(node, false) // Sanity check step in node must be the same as step out node
+	errorutil.AssertTrue(diff == nil,
+		fmt.Sprintf("Unexpected callstack when stepping out - callstack: %v - funccall: %v - difference: %v",
+			threadCallStack, node, diff))
 
 	ed.callStacks[tid] = threadCallStack[:lastIndex] // Remove the last item
 	ed.callStackVsSnapshots[tid] = threadCallStackVs[:lastIndex]
@@ -375,6 +473,7 @@ func (ed *ecalDebugger) VisitStepOutState(node *parser.ASTNode, vs parser.Scope,
 			is.node = node
 			is.vs = vs
 			is.running = false
+			is.pausedAt = time.Now()
 		}
 
 		if is.err == nil {
@@ -417,6 +516,14 @@ func (ed *ecalDebugger) RecordSource(source string) {
 	ed.sources[source] = true
 }
 
+/*
+NotifySourceLoaded lets the debugger know that a source has been loaded. This
+makes the source known before any of its statements have been executed.
+*/
+func (ed *ecalDebugger) NotifySourceLoaded(source string) {
+	ed.RecordSource(source)
+}
+
 /*
 RecordThreadFinished lets the debugger know that a thread has finished.
 */
@@ -429,6 +536,7 @@ func (ed *ecalDebugger) RecordThreadFinished(tid uint64) {
 		delete(ed.callStacks, tid)
 		delete(ed.callStackVsSnapshots, tid)
 		delete(ed.callStackGlobalVsSnapshots, tid)
+		delete(ed.watches, tid)
 	}
 }
 
@@ -520,7 +628,7 @@ func (ed *ecalDebugger) InjectValue(threadID uint64, varName string, expression
 		// Eval expression
 
 		ast, err = parser.ParseWithRuntime("InjectValueExpression", expression,
-			NewECALRuntimeProvider("InjectValueExpression2", nil, nil))
+			NewECALRuntimeProvider("InjectValueExpression2"))
 
 		if err == nil {
 			if err = ast.Runtime.Validate(); err == nil {
@@ -538,6 +646,281 @@ func (ed *ecalDebugger) InjectValue(threadID uint64, varName string, expression
 	return err
 }
 
+/*
+EvalExpression evaluates an expression in the variable scope of a
+suspended thread and returns the result.
+*/
+func (ed *ecalDebugger) EvalExpression(threadID uint64, expression string) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Cannot find suspended thread %v", threadID)
+
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	is, ok := ed.interrogationStates[threadID]
+
+	if ok && !is.running {
+		res, err = ed.evalInScope(expression, is.vs)
+	}
+
+	return res, err
+}
+
+/*
+AddWatch registers a watch expression on a suspended thread.
+*/
+func (ed *ecalDebugger) AddWatch(threadID uint64, expression string) error {
+	err := fmt.Errorf("Cannot find suspended thread %v", threadID)
+
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	is, ok := ed.interrogationStates[threadID]
+
+	if ok && !is.running {
+		var ast *parser.ASTNode
+
+		// Make sure the expression can be parsed before registering it
+
+		if ast, err = parser.ParseWithRuntime("AddWatch", expression,
+			NewECALRuntimeProvider("AddWatch2")); err == nil {
+
+			if err = ast.Runtime.Validate(); err == nil {
+
+				if _, ok := ed.watches[threadID]; !ok {
+					ed.watches[threadID] = make(map[string]string)
+				}
+
+				ed.watches[threadID][expression] = ed.watchValueString(ed.evalInScope(expression, is.vs))
+			}
+		}
+	}
+
+	return err
+}
+
+/*
+RemoveWatch removes a watch expression from a thread.
+*/
+func (ed *ecalDebugger) RemoveWatch(threadID uint64, expression string) error {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	if _, ok := ed.watches[threadID][expression]; !ok {
+		return fmt.Errorf("Cannot find watch expression %v on thread %v", expression, threadID)
+	}
+
+	delete(ed.watches[threadID], expression)
+
+	if len(ed.watches[threadID]) == 0 {
+		delete(ed.watches, threadID)
+	}
+
+	return nil
+}
+
+/*
+Watches returns all active watch expressions.
+*/
+func (ed *ecalDebugger) Watches() interface{} {
+	ed.lock.RLock()
+	defer ed.lock.RUnlock()
+
+	res := make(map[string]interface{})
+
+	for tid, watches := range ed.watches {
+		var expressions []string
+
+		for expression := range watches {
+			expressions = append(expressions, expression)
+		}
+
+		sort.Strings(expressions)
+
+		res[fmt.Sprint(tid)] = expressions
+	}
+
+	return res
+}
+
+/*
+AddWatchListener adds a listener which is notified if the value of
+a watch expression has changed.
+*/
+func (ed *ecalDebugger) AddWatchListener(id string, listener util.WatchListener) {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+	ed.watchListeners[id] = listener
+}
+
+/*
+RemoveWatchListener removes a watch listener.
+*/
+func (ed *ecalDebugger) RemoveWatchListener(id string) {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+	delete(ed.watchListeners, id)
+}
+
+/*
+checkWatches evaluates all watch expressions of a thread which has just been
+suspended and notifies all watch listeners of changed values.
+*/
+func (ed *ecalDebugger) checkWatches(tid uint64, vs parser.Scope) {
+	type watchChange struct {
+		expression string
+		value      interface{}
+	}
+
+	var changes []watchChange
+	var listeners []util.WatchListener
+
+	ed.lock.Lock()
+
+	for expression, last := range ed.watches[tid] {
+		res, err := ed.evalInScope(expression, vs)
+
+		if current := ed.watchValueString(res, err); current != last {
+			ed.watches[tid][expression] = current
+
+			if err != nil {
+				res = fmt.Sprintf("#%v", err.Error())
+			}
+
+			changes = append(changes, watchChange{expression, scope.ConvertECALToJSONObject(res)})
+		}
+	}
+
+	for _, listener := range ed.watchListeners {
+		listeners = append(listeners, listener)
+	}
+
+	ed.lock.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].expression < changes[j].expression
+	})
+
+	// Notify listeners outside of the lock so they can query the debugger
+
+	for _, change := range changes {
+		for _, listener := range listeners {
+			listener(tid, change.expression, change.value)
+		}
+	}
+}
+
+/*
+watchValueString returns a comparable string representation of the result
+of a watch expression.
+*/
+func (ed *ecalDebugger) watchValueString(res interface{}, err error) string {
+	if err != nil {
+		return fmt.Sprintf("#%v", err.Error())
+	}
+
+	return fmt.Sprintf("%#v", scope.ConvertECALToJSONObject(res))
+}
+
+/*
+evalInScope evaluates an expression in a child scope of a given variable scope.
+*/
+func (ed *ecalDebugger) evalInScope(expression string, vs parser.Scope) (interface{}, error) {
+	var res interface{}
+
+	ast, err := parser.ParseWithRuntime("EvalExpression", expression,
+		NewECALRuntimeProvider("EvalExpression2"))
+
+	if err == nil {
+		if err = ast.Runtime.Validate(); err == nil {
+
+			evs := scope.NewScopeWithParent("EvalExpressionScope", vs)
+			res, err = ast.Runtime.Eval(evs, make(map[string]interface{}), 999)
+		}
+	}
+
+	return res, err
+}
+
+/*
+StartTrace starts recording all visited nodes of a thread.
+*/
+func (ed *ecalDebugger) StartTrace(threadID uint64) {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+	ed.traces[threadID] = make([]*TraceEntry, 0, 100)
+}
+
+/*
+StopTrace stops recording visited nodes of a thread and returns the recorded trace.
+*/
+func (ed *ecalDebugger) StopTrace(threadID uint64) []*TraceEntry {
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	trace := ed.traces[threadID]
+	delete(ed.traces, threadID)
+
+	return trace
+}
+
+/*
+Disassemble returns the AST of the statement at or near a given source line.
+*/
+func (ed *ecalDebugger) Disassemble(source string, line int) (interface{}, error) {
+	ed.lock.RLock()
+	defer ed.lock.RUnlock()
+
+	node, ok := ed.sourceNodes[fmt.Sprintf("%v:%v", source, line)]
+
+	if !ok {
+
+		// Check the call stacks and all visited statements of the source
+		// for the nearest line
+
+		var candidates []*parser.ASTNode
+
+		for _, callStack := range ed.callStacks {
+			candidates = append(candidates, callStack...)
+		}
+
+		for _, n := range ed.sourceNodes {
+			candidates = append(candidates, n)
+		}
+
+		distance := -1
+
+		for _, c := range candidates {
+			if c.Token.Lsource != source {
+				continue
+			}
+
+			d := c.Token.Lline - line
+			if d < 0 {
+				d = -d
+			}
+
+			if distance == -1 || d < distance || (d == distance && c.Token.Lline < node.Token.Lline) {
+				node = c
+				distance = d
+			}
+		}
+
+		if node == nil {
+			return nil, fmt.Errorf("Cannot find code for %v:%v", source, line)
+		}
+	}
+
+	code, err := parser.PrettyPrint(node)
+
+	return map[string]interface{}{
+		"source": fmt.Sprintf("%v:%v", node.Token.Lsource, node.Token.Lline),
+		"code":   code,
+		"ast":    node.String(),
+	}, err
+}
+
 /*
 Continue will continue a suspended thread.
 */
@@ -558,6 +941,8 @@ func (ed *ecalDebugger) Continue(threadID uint64, contType util.ContType) {
 			is.cmd = StepOut
 			stack := ed.callStacks[threadID]
 			is.stepOutStack = stack[:len(stack)-1]
+		case util.Abort:
+			is.cmd = Abort
 		}
 
 		is.running = true
@@ -588,6 +973,7 @@ func (ed *ecalDebugger) Status() interface{} {
 	for k := range ed.sources {
 		sources = append(sources, k)
 	}
+	sort.Strings(sources)
 	res["sources"] = sources
 
 	for k, v := range ed.callStacks {
@@ -598,6 +984,10 @@ func (ed *ecalDebugger) Status() interface{} {
 		if is, ok := ed.interrogationStates[k]; ok {
 			s["threadRunning"] = is.running
 			s["error"] = is.err
+
+			if !is.running {
+				s["pausedDurationMs"] = time.Since(is.pausedAt).Milliseconds()
+			}
 		}
 
 		threadStates[fmt.Sprint(k)] = s
@@ -651,12 +1041,30 @@ func (ed *ecalDebugger) Describe(threadID uint64) interface{} {
 			res["node"] = is.node.ToJSONObject()
 			res["vs"] = ed.buildVsSnapshot(is.vs)
 			res["vsGlobal"] = ed.buildGlobalVsSnapshot(is.vs)
+			res["scopeChain"] = ed.buildScopeChain(is.vs)
 		}
 	}
 
 	return res
 }
 
+/*
+buildScopeChain lists the variables of every scope level from the innermost
+scope to the global scope.
+*/
+func (ed *ecalDebugger) buildScopeChain(vs parser.Scope) []map[string]interface{} {
+	var chain []map[string]interface{}
+
+	for ; vs != nil; vs = vs.Parent() {
+		chain = append(chain, map[string]interface{}{
+			"name": vs.Name(),
+			"vs":   vs.ToJSONObject(),
+		})
+	}
+
+	return chain
+}
+
 func (ed *ecalDebugger) buildVsSnapshot(vs parser.Scope) map[string]interface{} {
 	vsValues := make(map[string]interface{})
 
@@ -674,6 +1082,24 @@ func (ed *ecalDebugger) buildVsSnapshot(vs parser.Scope) map[string]interface{}
 	return ed.MergeMaps(vsValues, vs.ToJSONObject())
 }
 
+/*
+buildVsTypeSnapshot collects the names and types of all variables in the
+local scopes. Conflicts are resolved as first-one-wins.
+*/
+func (ed *ecalDebugger) buildVsTypeSnapshot(vs parser.Scope) map[string]string {
+	vsTypes := make(map[string]string)
+
+	for s := vs; s != nil && s.Name() != scope.GlobalScope; s = s.Parent() {
+		for k, v := range scope.ToObject(s) {
+			if _, ok := vsTypes[fmt.Sprint(k)]; !ok {
+				vsTypes[fmt.Sprint(k)] = fmt.Sprintf("%T", v)
+			}
+		}
+	}
+
+	return vsTypes
+}
+
 func (ed *ecalDebugger) buildGlobalVsSnapshot(vs parser.Scope) map[string]interface{} {
 	vsValues := make(map[string]interface{})
 
@@ -715,8 +1141,39 @@ func (ed *ecalDebugger) prettyPrintCallStack(threadCallStack []*parser.ASTNode)
 	cs := []string{}
 	for _, s := range threadCallStack {
 		pp, _ := parser.PrettyPrint(s)
-		cs = append(cs, fmt.Sprintf("%v (%v:%v)",
-			pp, s.Token.Lsource, s.Token.Lline))
+
+		if s.Token != nil {
+			cs = append(cs, fmt.Sprintf("%v (%v:%v)",
+				pp, s.Token.Lsource, s.Token.Lline))
+		} else {
+
+			// Synthetic nodes have no source location
+
+			cs = append(cs, fmt.Sprintf("%v (%v)", pp, s.Runtime.Inspect()))
+		}
 	}
 	return cs
 }
+
+/*
+GetCurrentThreadID returns the ID of the goroutine which calls this function.
+The ID is parsed from the header of the goroutine's stack trace. The debugger
+does not use this ID - it tracks threads by the thread IDs which are given to
+the runtime components (sinks get their own ID from the thread pool).
+*/
+func GetCurrentThreadID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// The stack trace starts with "goroutine <id> [<state>]:"
+
+	fields := strings.Fields(strings.TrimPrefix(string(buf), "goroutine "))
+
+	if len(fields) > 0 {
+		if id, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			return id
+		}
+	}
+
+	return 0
+}
@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/util"
 )
 
@@ -35,7 +36,15 @@ var DebugCommandsMap = map[string]util.DebugCommand{
 	"status":       &statusCommand{&inbuildDebugCommand{}},
 	"extract":      &extractCommand{&inbuildDebugCommand{}},
 	"inject":       &injectCommand{&inbuildDebugCommand{}},
+	"eval":         &evalCommand{&inbuildDebugCommand{}},
+	"set":          &setCommand{&inbuildDebugCommand{}},
+	"get":          &getCommand{&inbuildDebugCommand{}},
+	"watch":        &watchCommand{&inbuildDebugCommand{}},
+	"unwatch":      &unwatchCommand{&inbuildDebugCommand{}},
+	"watches":      &watchesCommand{&inbuildDebugCommand{}},
+	"disassemble":  &disassembleCommand{&inbuildDebugCommand{}},
 	"lockstate":    &lockstateCommand{&inbuildDebugCommand{}},
+	"loglevel":     &loglevelCommand{&inbuildDebugCommand{}},
 }
 
 /*
@@ -229,7 +238,7 @@ func (c *contCommand) Run(debugger util.ECALDebugger, args []string) (interface{
 	var cmd util.ContType
 
 	if len(args) != 2 {
-		return nil, fmt.Errorf("Need a thread ID and a command Resume, StepIn, StepOver or StepOut")
+		return nil, fmt.Errorf("Need a thread ID and a command Resume, StepIn, StepOver, StepOut or Abort")
 	}
 
 	threadID, err := c.AssertNumParam(1, args[0])
@@ -245,8 +254,10 @@ func (c *contCommand) Run(debugger util.ECALDebugger, args []string) (interface{
 			cmd = util.StepOver
 		case "stepout":
 			cmd = util.StepOut
+		case "abort":
+			cmd = util.Abort
 		default:
-			return nil, fmt.Errorf("Invalid command %v - must be resume, stepin, stepover or stepout", cmdString)
+			return nil, fmt.Errorf("Invalid command %v - must be resume, stepin, stepover, stepout or abort", cmdString)
 		}
 
 		debugger.Continue(threadID, cmd)
@@ -259,7 +270,7 @@ func (c *contCommand) Run(debugger util.ECALDebugger, args []string) (interface{
 DocString returns a descriptive text about this command.
 */
 func (c *contCommand) DocString() string {
-	return "Continues a suspended thread. Specify <threadID> <Resume | StepIn | StepOver | StepOut>"
+	return "Continues a suspended thread. Specify <threadID> <Resume | StepIn | StepOver | StepOut | Abort>"
 }
 
 // describe
@@ -406,6 +417,262 @@ func (c *injectCommand) DocString() string {
 	return "Copies a value from the global variable scope into a suspended thread."
 }
 
+// eval
+// ====
+
+/*
+evalCommand evaluates an expression in the variable scope of a
+suspended thread
+*/
+type evalCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *evalCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	var res interface{}
+
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Need a thread ID and an expression")
+	}
+
+	threadID, err := c.AssertNumParam(1, args[0])
+
+	if err == nil {
+		if res, err = debugger.EvalExpression(threadID, strings.Join(args[1:], " ")); err == nil {
+			res = scope.ConvertECALToJSONObject(res)
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *evalCommand) DocString() string {
+	return "Evaluates an expression in the variable scope of a suspended thread."
+}
+
+// set
+// ===
+
+/*
+setCommand sets a variable in a suspended thread to the value of an expression
+*/
+type setCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *setCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("Need a thread ID, a variable name and an expression")
+	}
+
+	threadID, err := c.AssertNumParam(1, args[0])
+
+	if err == nil {
+		if !parser.NamePattern.MatchString(args[1]) {
+			err = fmt.Errorf("Variable names may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character")
+		}
+
+		if err == nil {
+			err = debugger.InjectValue(threadID, args[1], strings.Join(args[2:], " "))
+		}
+	}
+
+	return nil, err
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *setCommand) DocString() string {
+	return "Sets a variable in a suspended thread to the value of an expression."
+}
+
+// get
+// ===
+
+/*
+getCommand returns the value of a variable in a suspended thread
+*/
+type getCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *getCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	var res interface{}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("Need a thread ID and a variable name")
+	}
+
+	threadID, err := c.AssertNumParam(1, args[0])
+
+	if err == nil {
+		if !parser.NamePattern.MatchString(args[1]) {
+			err = fmt.Errorf("Variable names may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character")
+		}
+
+		if err == nil {
+			if res, err = debugger.EvalExpression(threadID, args[1]); err == nil {
+				res = scope.ConvertECALToJSONObject(res)
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *getCommand) DocString() string {
+	return "Returns the value of a variable in a suspended thread."
+}
+
+// watch
+// =====
+
+/*
+watchCommand registers a watch expression on a suspended thread
+*/
+type watchCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *watchCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Need a thread ID and an expression")
+	}
+
+	threadID, err := c.AssertNumParam(1, args[0])
+
+	if err == nil {
+		err = debugger.AddWatch(threadID, strings.Join(args[1:], " "))
+	}
+
+	return nil, err
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *watchCommand) DocString() string {
+	return "Registers a watch expression on a suspended thread. Changed values are reported when the thread is suspended again."
+}
+
+// unwatch
+// =======
+
+/*
+unwatchCommand removes a watch expression from a thread
+*/
+type unwatchCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *unwatchCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Need a thread ID and an expression")
+	}
+
+	threadID, err := c.AssertNumParam(1, args[0])
+
+	if err == nil {
+		err = debugger.RemoveWatch(threadID, strings.Join(args[1:], " "))
+	}
+
+	return nil, err
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *unwatchCommand) DocString() string {
+	return "Removes a watch expression from a thread."
+}
+
+// watches
+// =======
+
+/*
+watchesCommand lists all active watch expressions
+*/
+type watchesCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *watchesCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	return debugger.Watches(), nil
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *watchesCommand) DocString() string {
+	return "Lists all active watch expressions."
+}
+
+// disassemble
+// ===========
+
+/*
+disassembleCommand shows the AST of the code at a given source line
+*/
+type disassembleCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *disassembleCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Need a source and a line")
+	}
+
+	line, err := c.AssertNumParam(2, args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return debugger.Disassemble(args[0], int(line))
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *disassembleCommand) DocString() string {
+	return "Shows the AST of the statement at or near a given source line."
+}
+
 // lockstate
 // =========
 
@@ -430,3 +697,40 @@ DocString returns a descriptive text about this command.
 func (c *lockstateCommand) DocString() string {
 	return "Inspects the locking state."
 }
+
+// loglevel
+// ========
+
+/*
+loglevelCommand shows or changes the current log level.
+*/
+type loglevelCommand struct {
+	*inbuildDebugCommand
+}
+
+/*
+Execute the debug command and return its result. It must be possible to
+convert the output data into a JSON string.
+*/
+func (c *loglevelCommand) Run(debugger util.ECALDebugger, args []string) (interface{}, error) {
+	lll, ok := debugger.Logger().(*util.LogLevelLogger)
+
+	if !ok {
+		return nil, fmt.Errorf("Current logger does not support log levels")
+	}
+
+	if len(args) > 0 {
+		if err := lll.SetLevel(args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return string(lll.Level()), nil
+}
+
+/*
+DocString returns a descriptive text about this command.
+*/
+func (c *loglevelCommand) DocString() string {
+	return "Shows or sets the current log level. Specify optionally <debug | info | error>"
+}
@@ -11,8 +11,10 @@
 package interpreter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -62,7 +64,7 @@ log("test3")
 
 	tid = waitForThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -129,6 +131,12 @@ log("test3")
     "source": "ECALEvalTest",
     "value": "log"
   },
+  "scopeChain": [
+    {
+      "name": "GlobalScope",
+      "vs": {}
+    }
+  ],
   "threadRunning": false,
   "vs": {},
   "vsGlobal": {}
@@ -155,7 +163,7 @@ test3`[1:] {
 	_, err = testDebugger.HandleInput("rmbreak ECALEvalTest:4")
 	errorutil.AssertOk(err)
 
-	out, err = testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err = getStableStatus()
 
 	outBytes, _ = json.MarshalIndent(out, "", "  ")
 	outString = string(outBytes)
@@ -180,7 +188,7 @@ test3`[1:] {
 	_, err = testDebugger.HandleInput("rmbreak ECALEvalTest")
 	errorutil.AssertOk(err)
 
-	out, err = testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err = getStableStatus()
 
 	outBytes, _ = json.MarshalIndent(out, "", "  ")
 	outString = string(outBytes)
@@ -230,7 +238,7 @@ log("test3")
 
 	waitForThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -301,7 +309,7 @@ log("test3")
 
 	waitForThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -320,55 +328,14 @@ log("test3")
         "err() (ECALEvalTest:7)"
       ],
       "error": {
-        "Data": null,
-        "Detail": "",
-        "Environment": {},
-        "Node": {
-          "Name": "identifier",
-          "Token": {
-            "ID": 7,
-            "Pos": 16,
-            "Val": "raise",
-            "Identifier": true,
-            "AllowEscapes": false,
-            "PrefixNewlines": 1,
-            "Lsource": "ECALEvalTest",
-            "Lline": 3,
-            "Lpos": 2
-          },
-          "Meta": null,
-          "Children": [
-            {
-              "Name": "funccall",
-              "Token": null,
-              "Meta": null,
-              "Children": [
-                {
-                  "Name": "string",
-                  "Token": {
-                    "ID": 5,
-                    "Pos": 22,
-                    "Val": "foo",
-                    "Identifier": false,
-                    "AllowEscapes": true,
-                    "PrefixNewlines": 0,
-                    "Lsource": "ECALEvalTest",
-                    "Lline": 3,
-                    "Lpos": 8
-                  },
-                  "Meta": null,
-                  "Children": [],
-                  "Runtime": {}
-                }
-              ],
-              "Runtime": {}
-            }
-          ],
-          "Runtime": {}
-        },
-        "Source": "ECALTestRuntime (ECALEvalTest)",
-        "Trace": null,
-        "Type": "foo"
+        "data": null,
+        "detail": "",
+        "environment": {},
+        "line": 3,
+        "pos": 2,
+        "source": "ECALTestRuntime (ECALEvalTest)",
+        "trace": [],
+        "type": "foo"
       },
       "threadRunning": false
     }
@@ -410,8 +377,8 @@ func TestConcurrentDebugging(t *testing.T) {
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 
-	erp := NewECALRuntimeProvider("ECALTestRuntime", nil, nil)
-	vs := scope.NewScope(scope.GlobalScope)
+	erp := NewECALRuntimeProvider("ECALTestRuntime")
+	vs := scope.NewGlobalScope()
 
 	go func() {
 		_, err = UnitTestEvalWithRuntimeProvider(`
@@ -454,7 +421,7 @@ mutex a { mutex a { log("test5") } }
 
 	waitForAllThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -1066,6 +1033,104 @@ finish`[1:] {
 	}
 }
 
+func TestAbortDebugging(t *testing.T) {
+	var err error
+	defer func() {
+		testDebugger = nil
+	}()
+
+	testDebugger = NewECALDebugger(nil)
+
+	code := `
+a := 1
+b := 2
+c := 3
+`
+
+	_, err = testDebugger.HandleInput("break ECALEvalTest:3")
+	errorutil.AssertOk(err)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(code, nil)
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	if state := getDebuggerState(tid, t); !strings.Contains(state, `"code": "b := 2"`) {
+		t.Error("Unexpected state:", state)
+		return
+	}
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v Abort", tid))
+	errorutil.AssertOk(err)
+
+	wg.Wait()
+
+	if err == nil || err.Error() != fmt.Sprintf("ECAL error in ECALEvalTest: "+
+		"Aborted (Thread %v was aborted by the debugger) (Line:3 Pos:3)", tid) {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, ok := testDebugger.(*ecalDebugger).interrogationStates[tid]; ok {
+		t.Error("Interrogation state of an aborted thread should be removed")
+		return
+	}
+
+	// An abort cannot be caught
+
+	code = `
+r := []
+try {
+  r := add(r, 1)
+  r := add(r, 2)
+} except e {
+  r := add(r, e.type)
+} finally {
+  r := add(r, "finally")
+}
+r := add(r, 3)
+`
+
+	_, err = testDebugger.HandleInput("rmbreak ECALEvalTest:3")
+	errorutil.AssertOk(err)
+
+	_, err = testDebugger.HandleInput("break ECALEvalTest:5")
+	errorutil.AssertOk(err)
+
+	vs := scope.NewScope(scope.GlobalScope)
+
+	wg = &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(code, vs)
+		wg.Done()
+	}()
+
+	tid = waitForThreadSuspension(t)
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v Abort", tid))
+	errorutil.AssertOk(err)
+
+	wg.Wait()
+
+	if err == nil || err.Error() != fmt.Sprintf("ECAL error in ECALEvalTest: "+
+		"Aborted (Thread %v was aborted by the debugger) (Line:5 Pos:5)", tid) {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("r"); fmt.Sprint(res) != "[1 finally]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func TestStepDebuggingWithImport(t *testing.T) {
 	var err error
 	defer func() {
@@ -1106,7 +1171,7 @@ log("finish: ", a)
 	wg.Add(1)
 
 	go func() {
-		_, err = UnitTestEvalAndASTAndImport(code, nil, "", il)
+		_, err = UnitTestEvalAndASTAndImportAndRuntimeProvider(code, nil, "", il, nil)
 		if err != nil {
 			t.Error(err)
 		}
@@ -1187,8 +1252,23 @@ finish: 1`[1:] {
 	}
 }
 
+/*
+getStableStatus returns the debugger status without timing dependent values.
+*/
+func getStableStatus() (interface{}, error) {
+	out, err := testDebugger.HandleInput("status")
+
+	if err == nil {
+		for _, threadState := range out.(map[string]interface{})["threads"].(map[string]map[string]interface{}) {
+			delete(threadState, "pausedDurationMs")
+		}
+	}
+
+	return out, err
+}
+
 func getDebuggerState(tid uint64, t *testing.T) string {
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 	if err != nil {
 		t.Error(err)
 		return ""
@@ -1214,6 +1294,134 @@ func getDebuggerState(tid uint64, t *testing.T) string {
 
 }
 
+func TestSetAndGetDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	vs := scope.NewGlobalScope()
+
+	testDebugger = NewECALDebugger(vs)
+
+	_, err = testDebugger.HandleInput("break ECALEvalTest:3")
+	errorutil.AssertOk(err)
+
+	var res interface{}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		res, err = UnitTestEval(`
+a := 1
+b := a * 2
+c := b
+c
+`, nil)
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("set %v a 20 + 1", tid))
+	errorutil.AssertOk(err)
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v StepOver", tid))
+	errorutil.AssertOk(err)
+
+	tid = waitForThreadSuspension(t)
+
+	out, err := testDebugger.HandleInput(fmt.Sprintf("get %v b", tid))
+	outBytes, _ := json.Marshal(out)
+
+	if err != nil || string(outBytes) != "42" {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid))
+	errorutil.AssertOk(err)
+
+	wg.Wait()
+
+	if res != 42. || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestScopeChainDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	vs := scope.NewGlobalScope()
+
+	testDebugger = NewECALDebugger(vs)
+
+	_, err = testDebugger.HandleInput("break ECALEvalTest:6")
+	errorutil.AssertOk(err)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(`
+a := 1
+func myfunc(x) {
+  b := 2
+  if true {
+    c := 3
+    log(a, b, c, x)
+  }
+}
+myfunc(4)
+`, vs)
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	out, err := testDebugger.HandleInput(fmt.Sprintf("describe %v", tid))
+	errorutil.AssertOk(err)
+
+	outBytes, _ := json.MarshalIndent(out.(map[string]interface{})["scopeChain"], "", "  ")
+
+	if string(outBytes) != `[
+  {
+    "name": "block: if (Line:5 Pos:3)",
+    "vs": {}
+  },
+  {
+    "name": "func: myfunc",
+    "vs": {
+      "b": 2,
+      "x": 4
+    }
+  },
+  {
+    "name": "GlobalScope",
+    "vs": {
+      "a": 1,
+      "myfunc": "ecal.function: myfunc (Line 3, Pos 1)"
+    }
+  }
+]` {
+		t.Error("Unexpected result:", string(outBytes))
+		return
+	}
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid))
+	errorutil.AssertOk(err)
+
+	wg.Wait()
+	errorutil.AssertOk(err)
+}
+
 func TestInjectAndExtractDebugging(t *testing.T) {
 	var err error
 
@@ -1221,7 +1429,7 @@ func TestInjectAndExtractDebugging(t *testing.T) {
 		testDebugger = nil
 	}()
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	testDebugger = NewECALDebugger(vs)
 
@@ -1252,7 +1460,7 @@ log("test3 b=", b)
 
 	tid := waitForThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -1360,6 +1568,21 @@ log("test3 b=", b)
     "source": "ECALEvalTest",
     "value": "log"
   },
+  "scopeChain": [
+    {
+      "name": "func: myfunc",
+      "vs": {
+        "a": 56
+      }
+    },
+    {
+      "name": "GlobalScope",
+      "vs": {
+        "b": 49,
+        "myfunc": "ecal.function: myfunc (Line 3, Pos 1)"
+      }
+    }
+  ],
   "threadRunning": false,
   "vs": {
     "a": 56
@@ -1383,6 +1606,22 @@ log("test3 b=", b)
 		return
 	}
 
+	out, err = testDebugger.HandleInput(fmt.Sprintf("eval %v a + b", tid))
+	outBytes, _ = json.Marshal(out)
+
+	if err != nil || string(outBytes) != "99" {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
+	out, err = testDebugger.HandleInput(fmt.Sprintf("eval %v [a, {\"x\": b}]", tid))
+	outBytes, _ = json.Marshal(out)
+
+	if err != nil || string(outBytes) != `[50,{"x":49}]` {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
 	// Continue until the end
 
 	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
@@ -1411,81 +1650,583 @@ test3 b=49`[1:] {
 	}
 }
 
-func TestSimpleStacktrace(t *testing.T) {
-
-	res, err := UnitTestEval(`
-func a() {
-	b()
-}
-func b() {
-	c()
-}
-func c() {
-	raise("testerror")
-}
-a()
-`, nil)
-
-	if err == nil {
-		t.Error("Unexpected result: ", res, err)
-		return
-	}
-
-	ss := err.(util.TraceableRuntimeError)
-
-	if out := fmt.Sprintf("%v\n  %v", err.Error(), strings.Join(ss.GetTraceString(), "\n  ")); out != `
-ECAL error in ECALTestRuntime (ECALEvalTest): testerror () (Line:9 Pos:2)
-  raise("testerror") (ECALEvalTest:9)
-  c() (ECALEvalTest:6)
-  b() (ECALEvalTest:3)
-  a() (ECALEvalTest:11)`[1:] {
-		t.Error("Unexpected output:", out)
-		return
-	}
-}
-
-func TestDebugDocstrings(t *testing.T) {
-	for k, v := range DebugCommandsMap {
-		if res := v.DocString(); res == "" {
-			t.Error("Docstring missing for ", k)
-			return
-		}
-	}
-}
-
-func TestDebuggingErrorInput(t *testing.T) {
+func TestWatchDebugging(t *testing.T) {
 	var err error
 
 	defer func() {
 		testDebugger = nil
 	}()
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	testDebugger = NewECALDebugger(vs)
 
-	if _, err = testDebugger.HandleInput("uuu"); err.Error() != `Unknown command: uuu` {
+	var notifications []string
+	var notificationsLock sync.Mutex
+
+	testDebugger.AddWatchListener("test", func(threadID uint64, expression string, value interface{}) {
+		notificationsLock.Lock()
+		defer notificationsLock.Unlock()
+		notifications = append(notifications, fmt.Sprintf("%v=%v", expression, value))
+	})
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:3"); err != nil {
 		t.Error("Unexpected result:", err)
 		return
 	}
 
-	if _, err = testDebugger.HandleInput("break"); err.Error() != `Need a break target (<source>:<line>) as first parameter` {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(`
+a := 1
+b := 2
+a := 3
+b := 4
+log("a=", a)
+`, vs)
+		if err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("watch %v a * 10", tid)); err != nil {
 		t.Error("Unexpected result:", err)
 		return
 	}
 
-	if _, err = testDebugger.HandleInput("break foo"); err.Error() != `Invalid break target - should be <source>:<line>` {
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("watch %v b", tid)); err != nil {
 		t.Error("Unexpected result:", err)
 		return
 	}
 
-	if _, err = testDebugger.HandleInput("rmbreak"); err.Error() != `Need a break target (<source>[:<line>]) as first parameter` {
-		t.Error("Unexpected result:", err)
+	out, err := testDebugger.HandleInput("watches")
+	outBytes, _ := json.Marshal(out)
+
+	if err != nil || string(outBytes) != fmt.Sprintf(`{"%v":["a * 10","b"]}`, tid) {
+		t.Error("Unexpected result:", string(outBytes), err)
 		return
 	}
 
-	if _, err = testDebugger.HandleInput("disablebreak"); err.Error() != `Need a break target (<source>:<line>) as first parameter` {
-		t.Error("Unexpected result:", err)
+	// Only b changes in the next step
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v stepover", tid))
+	errorutil.AssertOk(err)
+
+	tid = waitForThreadSuspension(t)
+
+	notificationsLock.Lock()
+	if res := fmt.Sprint(notifications); res != "[b=2]" {
+		t.Error("Unexpected result:", res)
+	}
+	notificationsLock.Unlock()
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("unwatch %v b", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("unwatch %v b", tid)); err == nil ||
+		err.Error() != fmt.Sprintf("Cannot find watch expression b on thread %v", tid) {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v stepover", tid))
+	errorutil.AssertOk(err)
+
+	tid = waitForThreadSuspension(t)
+
+	notificationsLock.Lock()
+	if res := fmt.Sprint(notifications); res != "[b=2 a * 10=30]" {
+		t.Error("Unexpected result:", res)
+	}
+	notificationsLock.Unlock()
+
+	testDebugger.RemoveWatchListener("test")
+
+	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg.Wait()
+
+	testDebugger.RecordThreadFinished(tid)
+
+	out, err = testDebugger.HandleInput("watches")
+	outBytes, _ = json.Marshal(out)
+
+	if err != nil || string(outBytes) != "{}" {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+}
+
+func TestDisassembleDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	vs := scope.NewGlobalScope()
+
+	testDebugger = NewECALDebugger(vs)
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:3"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(`
+a := 1
+b := [a, 2]
+log("b=", b)
+`, vs)
+		if err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	out, err := testDebugger.HandleInput("disassemble ECALEvalTest 3")
+	outBytes, _ := json.MarshalIndent(out, "", "  ")
+
+	if err != nil || string(outBytes) != `{
+  "ast": ":=\n  identifier: b\n  list\n    identifier: a\n    number: 2\n",
+  "code": "b := [a, 2]",
+  "source": "ECALEvalTest:3"
+}` {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
+	// Lines which have not been visited resolve to the nearest known line
+
+	out, err = testDebugger.HandleInput("disassemble ECALEvalTest 1")
+	outBytes, _ = json.Marshal(out.(map[string]interface{})["code"])
+
+	if err != nil || string(outBytes) != `"a := 1"` {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("disassemble foo 1"); err == nil || err.Error() != "Cannot find code for foo:1" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("disassemble ECALEvalTest"); err == nil || err.Error() != "Need a source and a line" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("disassemble ECALEvalTest x"); err == nil || err.Error() != "Parameter 2 should be a number" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg.Wait()
+}
+
+func TestLogLevelDebugging(t *testing.T) {
+	ml := util.NewMemoryLogger(10)
+	ll, _ := util.NewLogLevelLogger(ml, "info")
+
+	debugger := NewECALDebugger(nil)
+
+	if _, err := debugger.HandleInput("loglevel"); err == nil ||
+		err.Error() != "Current logger does not support log levels" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	debugger.SetLogger(ll)
+
+	if res, err := debugger.HandleInput("loglevel"); err != nil || res != "info" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ll.LogDebug("test1")
+
+	if res, err := debugger.HandleInput("loglevel Debug"); err != nil || res != "debug" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ll.LogDebug("test2")
+
+	if _, err := debugger.HandleInput("loglevel foo"); err == nil ||
+		err.Error() != "Invalid log level: foo" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if ml.String() != "debug: test2" {
+		t.Error("Unexpected result:", ml.String())
+		return
+	}
+}
+
+func TestTraceDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	vs := scope.NewGlobalScope()
+
+	testDebugger = NewECALDebugger(vs)
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:3"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(`
+a := 1
+func f(x) {
+  b := "foo"
+}
+f(a)
+`, vs)
+		if err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	testDebugger.(*ecalDebugger).StartTrace(tid)
+
+	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg.Wait()
+
+	var buf bytes.Buffer
+
+	for _, e := range testDebugger.(*ecalDebugger).StopTrace(tid) {
+		buf.WriteString(fmt.Sprintf("%v:%v %v %v\n", e.Source, e.Line, e.Node.Name, e.VSSnapshot))
+	}
+
+	if res := buf.String(); res != `
+ECALEvalTest:6 identifier map[]
+ECALEvalTest:6 identifier map[]
+ECALEvalTest:6 identifier map[]
+ECALEvalTest:4 := map[x:float64]
+ECALEvalTest:4 identifier map[x:float64]
+ECALEvalTest:4 string map[x:float64]
+`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if trace := testDebugger.(*ecalDebugger).StopTrace(tid); trace != nil {
+		t.Error("Unexpected result:", trace)
+		return
+	}
+}
+
+func TestPausedDurationDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	testDebugger = NewECALDebugger(nil)
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:2"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEval(`
+a := 1
+`, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	time.Sleep(50 * time.Millisecond)
+
+	threadState := testDebugger.(*ecalDebugger).Status().(map[string]interface{})["threads"].(map[string]map[string]interface{})[fmt.Sprint(tid)]
+
+	if d, ok := threadState["pausedDurationMs"].(int64); !ok || d < 50 {
+		t.Error("Unexpected result:", threadState)
+		return
+	}
+
+	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg.Wait()
+}
+
+func TestSourceLoadedDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	testDebugger = NewECALDebugger(nil)
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:3"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	testDebugger.NotifySourceLoaded("foo")
+
+	il := &util.MemoryImportLocator{Files: map[string]string{
+		"lib": "func f() {\n}",
+	}}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		_, err = UnitTestEvalAndASTAndImportAndRuntimeProvider(`
+import "lib" as lib
+a := 1
+`, nil, "", il, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+
+	tid := waitForThreadSuspension(t)
+
+	out, err := getStableStatus()
+	outBytes, _ := json.Marshal(out.(map[string]interface{})["sources"])
+
+	if err != nil || string(outBytes) != `["ECALEvalTest","foo","lib"]` {
+		t.Error("Unexpected result:", string(outBytes), err)
+		return
+	}
+
+	if _, err := testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", tid)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrentSinkDebugging(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	testDebugger = NewECALDebugger(nil)
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:5"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("break ECALEvalTest:10"); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+sink s1
+  kindmatch ["foo"],
+{
+  a := 1
+}
+sink s2
+  kindmatch ["bar"],
+{
+  b := 1
+}
+addEvent("e1", "foo", {})
+addEvent("e2", "bar", {})
+`, nil)
+	errorutil.AssertOk(err)
+
+	var threads map[string]map[string]interface{}
+
+	for i := 0; i < 100; i++ {
+		out, err := getStableStatus()
+		errorutil.AssertOk(err)
+
+		threads = out.(map[string]interface{})["threads"].(map[string]map[string]interface{})
+
+		suspended := 0
+		for _, status := range threads {
+			if r, ok := status["threadRunning"]; ok && !r.(bool) {
+				suspended++
+			}
+		}
+
+		if suspended == 2 {
+			break
+		}
+
+		time.Sleep(1 * time.Millisecond)
+	}
+
+	// Both sinks are suspended under their own thread ID
+
+	var code []string
+
+	for threadID, status := range threads {
+		if r, ok := status["threadRunning"]; ok && !r.(bool) {
+			threadIDNum, _ := strconv.ParseInt(threadID, 10, 0)
+			out, err := testDebugger.HandleInput(fmt.Sprintf("describe %v", threadIDNum))
+			errorutil.AssertOk(err)
+			code = append(code, fmt.Sprint(out.(map[string]interface{})["code"]))
+
+			_, err = testDebugger.HandleInput(fmt.Sprintf("cont %v Resume", threadIDNum))
+			errorutil.AssertOk(err)
+		}
+	}
+
+	sort.Strings(code)
+
+	if res := fmt.Sprint(code); res != "[a := 1 b := 1]" {
+		t.Error("Unexpected result:", res, threads)
+		return
+	}
+}
+
+func TestGetCurrentThreadID(t *testing.T) {
+	id := GetCurrentThreadID()
+
+	if id == 0 || id != GetCurrentThreadID() {
+		t.Error("Unexpected result:", id)
+		return
+	}
+
+	ids := make(chan uint64)
+
+	go func() {
+		ids <- GetCurrentThreadID()
+	}()
+
+	if otherID := <-ids; otherID == 0 || otherID == id {
+		t.Error("Unexpected result:", id, otherID)
+		return
+	}
+}
+
+func TestSimpleStacktrace(t *testing.T) {
+
+	res, err := UnitTestEval(`
+func a() {
+	b()
+}
+func b() {
+	c()
+}
+func c() {
+	raise("testerror")
+}
+a()
+`, nil)
+
+	if err == nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	ss := err.(util.TraceableRuntimeError)
+
+	if out := fmt.Sprintf("%v\n  %v", err.Error(), strings.Join(ss.GetTraceString(), "\n  ")); out != `
+ECAL error in ECALTestRuntime (ECALEvalTest): testerror () (Line:9 Pos:2)
+  raise("testerror") (ECALEvalTest:9)
+  c() (ECALEvalTest:6)
+  b() (ECALEvalTest:3)
+  a() (ECALEvalTest:11)`[1:] {
+		t.Error("Unexpected output:", out)
+		return
+	}
+}
+
+func TestDebugDocstrings(t *testing.T) {
+	for k, v := range DebugCommandsMap {
+		if res := v.DocString(); res == "" {
+			t.Error("Docstring missing for ", k)
+			return
+		}
+	}
+}
+
+func TestDebuggingErrorInput(t *testing.T) {
+	var err error
+
+	defer func() {
+		testDebugger = nil
+	}()
+
+	vs := scope.NewGlobalScope()
+
+	testDebugger = NewECALDebugger(vs)
+
+	if _, err = testDebugger.HandleInput("uuu"); err.Error() != `Unknown command: uuu` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("break"); err.Error() != `Need a break target (<source>:<line>) as first parameter` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("break foo"); err.Error() != `Invalid break target - should be <source>:<line>` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("rmbreak"); err.Error() != `Need a break target (<source>[:<line>]) as first parameter` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("disablebreak"); err.Error() != `Need a break target (<source>:<line>) as first parameter` {
+		t.Error("Unexpected result:", err)
 		return
 	}
 
@@ -1517,7 +2258,7 @@ log("test3")
 
 	tid := waitForThreadSuspension(t)
 
-	out, err := testDebugger.HandleInput(fmt.Sprintf("status"))
+	out, err := getStableStatus()
 
 	outBytes, _ := json.MarshalIndent(out, "", "  ")
 	outString := string(outBytes)
@@ -1557,11 +2298,11 @@ func TestDebuggingErrorInput2(t *testing.T) {
 		testDebugger = nil
 	}()
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	testDebugger = NewECALDebugger(vs)
 
-	if _, err = testDebugger.HandleInput("cont foo"); err.Error() != `Need a thread ID and a command Resume, StepIn, StepOver or StepOut` {
+	if _, err = testDebugger.HandleInput("cont foo"); err.Error() != `Need a thread ID and a command Resume, StepIn, StepOver, StepOut or Abort` {
 		t.Error("Unexpected result:", err)
 		return
 	}
@@ -1571,7 +2312,7 @@ func TestDebuggingErrorInput2(t *testing.T) {
 		return
 	}
 
-	if _, err = testDebugger.HandleInput("cont 99 bar"); err.Error() != `Invalid command bar - must be resume, stepin, stepover or stepout` {
+	if _, err = testDebugger.HandleInput("cont 99 bar"); err.Error() != `Invalid command bar - must be resume, stepin, stepover, stepout or abort` {
 		t.Error("Unexpected result:", err)
 		return
 	}
@@ -1596,6 +2337,71 @@ func TestDebuggingErrorInput2(t *testing.T) {
 		return
 	}
 
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("eval %v", tid)); err.Error() != `Need a thread ID and an expression` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("eval 123 a"); err.Error() != `Cannot find suspended thread 123` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("eval %v a +", tid)); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("set %v foo", tid)); err.Error() != `Need a thread ID, a variable name and an expression` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("set %v _foo 1", tid)); err.Error() != `Variable names may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("set 123 foo 1"); err.Error() != `Cannot find suspended thread 123` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("get %v", tid)); err.Error() != `Need a thread ID and a variable name` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("get %v _foo", tid)); err.Error() != `Variable names may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("get 123 foo"); err.Error() != `Cannot find suspended thread 123` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("watch %v", tid)); err.Error() != `Need a thread ID and an expression` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput("watch 123 a"); err.Error() != `Cannot find suspended thread 123` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("watch %v a +", tid)); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err = testDebugger.HandleInput(fmt.Sprintf("unwatch %v", tid)); err.Error() != `Need a thread ID and an expression` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
 	testDebugger.(*ecalDebugger).globalScope = nil
 
 	if _, err = testDebugger.HandleInput(fmt.Sprintf("extract %v foo foo", tid)); err.Error() != `Cannot access global scope` {
@@ -11,13 +11,21 @@
 package interpreter
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rhedin/Abe_common/errorutil"
+	"github.com/rhedin/Abe_common/sortutil"
+	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_common/timeutil"
 	"github.com/rhedin/Abe_ecal/engine"
 	"github.com/rhedin/Abe_ecal/parser"
@@ -30,24 +38,111 @@ import (
 InbuildFuncMap contains the mapping of inbuild functions.
 */
 var InbuildFuncMap = map[string]util.ECALFunction{
-	"range":           &rangeFunc{&inbuildBaseFunc{}},
-	"new":             &newFunc{&inbuildBaseFunc{}},
-	"type":            &typeFunc{&inbuildBaseFunc{}},
-	"len":             &lenFunc{&inbuildBaseFunc{}},
-	"del":             &delFunc{&inbuildBaseFunc{}},
-	"add":             &addFunc{&inbuildBaseFunc{}},
-	"concat":          &concatFunc{&inbuildBaseFunc{}},
-	"now":             &nowFunc{&inbuildBaseFunc{}},
-	"rand":            &randFunc{&inbuildBaseFunc{}},
-	"timestamp":       &timestampFunc{&inbuildBaseFunc{}},
-	"dumpenv":         &dumpenvFunc{&inbuildBaseFunc{}},
-	"doc":             &docFunc{&inbuildBaseFunc{}},
-	"sleep":           &sleepFunc{&inbuildBaseFunc{}},
-	"raise":           &raise{&inbuildBaseFunc{}},
-	"addEvent":        &addevent{&inbuildBaseFunc{}},
-	"addEventAndWait": &addeventandwait{&addevent{&inbuildBaseFunc{}}},
-	"setCronTrigger":  &setCronTrigger{&inbuildBaseFunc{}},
-	"setPulseTrigger": &setPulseTrigger{&inbuildBaseFunc{}},
+	"range":            &rangeFunc{&inbuildBaseFunc{}},
+	"enumerate":        &enumerateFunc{&inbuildBaseFunc{}},
+	"zipIter":          &zipIterFunc{&inbuildBaseFunc{}},
+	"mapKeys":          &mapIterFunc{&inbuildBaseFunc{}, false},
+	"mapValues":        &mapIterFunc{&inbuildBaseFunc{}, true},
+	"unsorted":         &unsortedFunc{&inbuildBaseFunc{}},
+	"new":              &newFunc{&inbuildBaseFunc{}},
+	"instanceof":       &instanceofFunc{&inbuildBaseFunc{}},
+	"type":             &typeFunc{&inbuildBaseFunc{}},
+	"len":              &lenFunc{&inbuildBaseFunc{}},
+	"del":              &delFunc{&inbuildBaseFunc{}},
+	"add":              &addFunc{&inbuildBaseFunc{}},
+	"concat":           &concatFunc{&inbuildBaseFunc{}},
+	"clone":            &cloneFunc{&inbuildBaseFunc{}},
+	"filter":           &filterFunc{&inbuildBaseFunc{}},
+	"mapFunc":          &mapFunc{&inbuildBaseFunc{}},
+	"reduce":           &reduceFunc{&inbuildBaseFunc{}},
+	"groupBy":          &groupByFunc{&inbuildBaseFunc{}},
+	"once":             &onceFunc{&inbuildBaseFunc{}},
+	"memoize":          &memoizeFunc{&inbuildBaseFunc{}},
+	"parallel":         &parallelFunc{&inbuildBaseFunc{}},
+	"newLock":          &newLockFunc{&inbuildBaseFunc{}, false},
+	"newRWLock":        &newLockFunc{&inbuildBaseFunc{}, true},
+	"newChannel":       &newChannelFunc{&inbuildBaseFunc{}},
+	"newCounter":       &newCounterFunc{&inbuildBaseFunc{}},
+	"newEventEmitter":  &newEventEmitterFunc{&inbuildBaseFunc{}},
+	"hasPrefix":        &hasPrefixFunc{&inbuildBaseFunc{}},
+	"hasSuffix":        &hasSuffixFunc{&inbuildBaseFunc{}},
+	"compile":          &compileFunc{&inbuildBaseFunc{}},
+	"call":             &callFunc{&inbuildBaseFunc{}},
+	"now":              &nowFunc{&inbuildBaseFunc{}},
+	"rand":             &randFunc{&inbuildBaseFunc{}},
+	"timestamp":        &timestampFunc{&inbuildBaseFunc{}},
+	"dumpenv":          &dumpenvFunc{&inbuildBaseFunc{}},
+	"doc":              &docFunc{&inbuildBaseFunc{}},
+	"sleep":            &sleepFunc{&inbuildBaseFunc{}},
+	"retry":            &retryFunc{&inbuildBaseFunc{}, false},
+	"retryWithBackoff": &retryFunc{&inbuildBaseFunc{}, true},
+	"timeout":          &timeoutFunc{&inbuildBaseFunc{}},
+	"log":              &logFunc{&inbuildBaseFunc{}, "info"},
+	"debug":            &logFunc{&inbuildBaseFunc{}, "debug"},
+	"error":            &logFunc{&inbuildBaseFunc{}, "error"},
+	"warn":             &logFunc{&inbuildBaseFunc{}, "warn"},
+	"raise":            &raise{&inbuildBaseFunc{}},
+	"addEvent":         &addevent{&inbuildBaseFunc{}},
+	"addEventAndWait":  &addeventandwait{&addevent{&inbuildBaseFunc{}}},
+	"eventQueueDepth":  &eventQueueDepth{&inbuildBaseFunc{}},
+	"eventWorkerCount": &eventWorkerCount{&inbuildBaseFunc{}},
+	"eventHistory":     &eventHistory{&inbuildBaseFunc{}},
+	"setCronTrigger":   &setCronTrigger{&inbuildBaseFunc{}},
+	"setPulseTrigger":  &setPulseTrigger{&inbuildBaseFunc{}},
+}
+
+/*
+inbuildFuncMapLock protects InbuildFuncMap from concurrent modifications.
+*/
+var inbuildFuncMapLock = &sync.RWMutex{}
+
+/*
+RegisterInbuildFunc registers a new inbuild function. This allows host
+applications to add custom functions. An existing function with the same
+name is replaced.
+*/
+func RegisterInbuildFunc(name string, f util.ECALFunction) {
+	inbuildFuncMapLock.Lock()
+	defer inbuildFuncMapLock.Unlock()
+
+	InbuildFuncMap[name] = f
+}
+
+/*
+UnregisterInbuildFunc removes an inbuild function.
+*/
+func UnregisterInbuildFunc(name string) {
+	inbuildFuncMapLock.Lock()
+	defer inbuildFuncMapLock.Unlock()
+
+	delete(InbuildFuncMap, name)
+}
+
+/*
+InbuildFuncs returns a copy of the mapping of all inbuild functions.
+*/
+func InbuildFuncs() map[string]util.ECALFunction {
+	inbuildFuncMapLock.RLock()
+	defer inbuildFuncMapLock.RUnlock()
+
+	res := make(map[string]util.ECALFunction, len(InbuildFuncMap))
+	for k, v := range InbuildFuncMap {
+		res[k] = v
+	}
+
+	return res
+}
+
+/*
+lookupInbuildFunc looks up an inbuild function by name.
+*/
+func lookupInbuildFunc(name string) (util.ECALFunction, bool) {
+	inbuildFuncMapLock.RLock()
+	defer inbuildFuncMapLock.RUnlock()
+
+	f, ok := InbuildFuncMap[name]
+
+	return f, ok
 }
 
 /*
@@ -129,6 +224,12 @@ func (rf *rangeFunc) Run(instanceID string, vs parser.Scope, is map[string]inter
 		err = fmt.Errorf("Need at least an end range as first parameter")
 	}
 
+	if lenargs == 1 {
+		if str, ok := args[0].(string); ok {
+			return rf.runRuneRange(instanceID, is, str)
+		}
+	}
+
 	if err == nil {
 
 		if stepVal, ok := is[instanceID+"step"]; ok {
@@ -180,11 +281,311 @@ func (rf *rangeFunc) Run(instanceID string, vs parser.Scope, is map[string]inter
 	return currVal, err
 }
 
+/*
+runRuneRange iterates over the characters of a string.
+*/
+func (rf *rangeFunc) runRuneRange(instanceID string, is map[string]interface{}, str string) (interface{}, error) {
+	var res interface{}
+	var err error
+
+	if runesVal, ok := is[instanceID+"runes"]; ok {
+
+		runes := runesVal.([]rune)
+		index := is[instanceID+"index"].(int)
+
+		is[instanceID+"index"] = index + 1
+
+		// Check for end of iteration
+
+		if index >= len(runes) {
+			err = util.ErrEndOfIteration
+		} else {
+			res = string(runes[index])
+		}
+
+	} else {
+
+		is[instanceID+"runes"] = []rune(str)
+		is[instanceID+"index"] = 0
+	}
+
+	if err == nil {
+		err = util.ErrIsIterator // Identify as iterator
+	}
+
+	return res, err
+}
+
 /*
 DocString returns a descriptive string.
 */
 func (rf *rangeFunc) DocString() (string, error) {
-	return "Iterates over number ranges. Parameters are start, end and step.", nil
+	return "Iterates over number ranges or the characters of a string. Parameters are start, end and step or a single string.", nil
+}
+
+// Enumerate
+// =========
+
+/*
+enumerateFunc is an iterator function which returns index and value pairs of a list.
+*/
+type enumerateFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *enumerateFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list as parameter")
+
+	if len(args) == 1 {
+
+		if listVal, ok := is[instanceID+"list"]; ok {
+
+			list := listVal.([]interface{})
+			index := is[instanceID+"index"].(int)
+
+			is[instanceID+"index"] = index + 1
+
+			// Check for end of iteration
+
+			if index >= len(list) {
+				err = util.ErrEndOfIteration
+			} else {
+				res = []interface{}{float64(index), list[index]}
+				err = nil
+			}
+
+		} else {
+			var list []interface{}
+
+			if list, err = rf.AssertListParam(1, args[0]); err == nil {
+				is[instanceID+"list"] = list
+				is[instanceID+"index"] = 0
+			}
+		}
+	}
+
+	if err == nil {
+		err = util.ErrIsIterator // Identify as iterator
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *enumerateFunc) DocString() (string, error) {
+	return "Iterates over a list returning index and value pairs.", nil
+}
+
+// ZipIter
+// =======
+
+/*
+zipIterFunc is an iterator function which returns the items of several lists
+with the same index together. The iteration stops with the shortest list.
+*/
+type zipIterFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *zipIterFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need at least two lists as parameters")
+
+	if len(args) > 1 {
+
+		if listsVal, ok := is[instanceID+"lists"]; ok {
+
+			lists := listsVal.([][]interface{})
+			index := is[instanceID+"index"].(int)
+
+			is[instanceID+"index"] = index + 1
+			err = nil
+
+			tuple := make([]interface{}, 0, len(lists))
+
+			for _, list := range lists {
+
+				// Check for end of iteration
+
+				if index >= len(list) {
+					err = util.ErrEndOfIteration
+					break
+				}
+
+				tuple = append(tuple, list[index])
+			}
+
+			if err == nil {
+				res = tuple
+			}
+
+		} else {
+			var list []interface{}
+
+			lists := make([][]interface{}, 0, len(args))
+			err = nil
+
+			for i, a := range args {
+				if err == nil {
+					if list, err = rf.AssertListParam(i+1, a); err == nil {
+						lists = append(lists, list)
+					}
+				}
+			}
+
+			if err == nil {
+				is[instanceID+"lists"] = lists
+				is[instanceID+"index"] = 0
+			}
+		}
+	}
+
+	if err == nil {
+		err = util.ErrIsIterator // Identify as iterator
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *zipIterFunc) DocString() (string, error) {
+	return "Iterates over several lists at once returning a list of all items with the same index.", nil
+}
+
+// MapKeys / MapValues
+// ===================
+
+/*
+mapIterFunc is an iterator function which returns the keys or the values of a
+map. The keys are sorted in the same way as in for-in loops over maps.
+*/
+type mapIterFunc struct {
+	*inbuildBaseFunc
+	values bool // Flag if the values instead of the keys should be returned
+}
+
+/*
+Run executes this function.
+*/
+func (rf *mapIterFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a map as parameter")
+
+	if len(args) == 1 {
+
+		if keysVal, ok := is[instanceID+"keys"]; ok {
+
+			keys := keysVal.([]interface{})
+			index := is[instanceID+"index"].(int)
+
+			is[instanceID+"index"] = index + 1
+
+			// Check for end of iteration
+
+			if index >= len(keys) {
+				err = util.ErrEndOfIteration
+			} else {
+				res = keys[index]
+				if rf.values {
+					res = is[instanceID+"map"].(map[interface{}]interface{})[res]
+				}
+				err = nil
+			}
+
+		} else {
+			var m map[interface{}]interface{}
+
+			if m, err = rf.AssertMapParam(1, args[0]); err == nil {
+				keys := make([]interface{}, 0, len(m))
+
+				for k := range m {
+					keys = append(keys, k)
+				}
+
+				// Try to sort according to string value
+
+				sortutil.InterfaceStrings(keys)
+
+				is[instanceID+"map"] = m
+				is[instanceID+"keys"] = keys
+				is[instanceID+"index"] = 0
+			}
+		}
+	}
+
+	if err == nil {
+		err = util.ErrIsIterator // Identify as iterator
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *mapIterFunc) DocString() (string, error) {
+	if rf.values {
+		return "Iterates over the values of a map in the order of the sorted keys.", nil
+	}
+	return "Iterates over the sorted keys of a map.", nil
+}
+
+// Unsorted
+// ========
+
+/*
+unsortedMap is a wrapper for a map which signals loops to iterate over the
+map without sorting its keys.
+*/
+type unsortedMap struct {
+	m map[interface{}]interface{}
+}
+
+/*
+unsortedFunc wraps a map for unordered iteration in loops.
+*/
+type unsortedFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *unsortedFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a map as parameter")
+
+	if len(args) == 1 {
+		var m map[interface{}]interface{}
+
+		if m, err = rf.AssertMapParam(1, args[0]); err == nil {
+			res = &unsortedMap{m}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *unsortedFunc) DocString() (string, error) {
+	return "Wraps a map so that a for loop iterates over it without sorting its keys.", nil
 }
 
 // New
@@ -229,7 +630,9 @@ func (rf *newFunc) Run(instanceID string, vs parser.Scope, is map[string]interfa
 }
 
 /*
-addSuperClasses adds super class functions to a given object.
+addSuperClasses adds super class functions to a given object. Returns the init
+function of the given template which should be used by subclasses as super
+function.
 */
 func (rf *newFunc) addSuperClasses(vs parser.Scope, is map[string]interface{},
 	obj map[interface{}]interface{}, template map[interface{}]interface{}) (interface{}, error) {
@@ -260,13 +663,17 @@ func (rf *newFunc) addSuperClasses(vs parser.Scope, is map[string]interface{},
 
 	for k, v := range template {
 
-		// Save previous init function
+		// Save previous init function and overridden super class methods
 
 		if funcVal, ok := v.(*function); ok {
 			newFunction := &function{funcVal.name, nil, obj, funcVal.declaration, funcVal.declarationVS}
 			if k == "init" {
-				newFunction.super = initSuperList
+				if initSuperList != nil {
+					newFunction.super = initSuperList
+				}
 				initFunc = newFunction
+			} else if superFunc, ok := obj[k].(*function); ok {
+				newFunction.super = map[interface{}]interface{}{k: superFunc}
 			}
 			obj[k] = newFunction
 		} else {
@@ -274,6 +681,19 @@ func (rf *newFunc) addSuperClasses(vs parser.Scope, is map[string]interface{},
 		}
 	}
 
+	// Inherit the init function of the first super class which provides one
+	// if the template has no init function of its own - this keeps the
+	// complete super chain reachable for further subclasses
+
+	if initFunc == nil {
+		for _, superInit := range initSuperList {
+			if superInit != nil {
+				initFunc = superInit
+				break
+			}
+		}
+	}
+
 	return initFunc, err
 }
 
@@ -284,48 +704,144 @@ func (rf *newFunc) DocString() (string, error) {
 	return "Creates a new object instance.", nil
 }
 
-// Type
-// =====
+// instanceof
+// ==========
 
 /*
-typeFunc returns the underlying types and values of an object.
+instanceofFunc checks if an object was created from a given template.
 */
-type typeFunc struct {
+type instanceofFunc struct {
 	*inbuildBaseFunc
 }
 
 /*
 Run executes this function.
 */
-func (rf *typeFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+func (rf *instanceofFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
 	var res interface{}
 
-	err := fmt.Errorf("Need a value as first parameter")
+	err := fmt.Errorf("Need an object and a template as parameters")
 
-	if len(args) > 0 {
-		res = fmt.Sprintf("%#v", args[0])
-		err = nil
+	if len(args) == 2 {
+		var obj, template map[interface{}]interface{}
+
+		if obj, err = rf.AssertMapParam(1, args[0]); err == nil {
+			if template, err = rf.AssertMapParam(2, args[1]); err == nil {
+				res = rf.sameMap(obj, template) || rf.createdFrom(obj, template) ||
+					rf.inSuperChain(obj, template)
+			}
+		}
 	}
 
 	return res, err
 }
 
 /*
-DocString returns a descriptive string.
+createdFrom checks if an object was created from a given template by new. This
+is the case if the object holds all properties of the template and all its
+functions are bound instances of the template functions. Templates without
+functions or super classes cannot be identified.
 */
-func (rf *typeFunc) DocString() (string, error) {
-	return "Returns the underlying types and values of an object.", nil
-}
+func (rf *instanceofFunc) createdFrom(obj map[interface{}]interface{}, template map[interface{}]interface{}) bool {
+	identified := false
 
-// Len
-// ===
+	for k, v := range template {
+		objVal, ok := obj[k]
+		if !ok {
+			return false
+		}
 
-/*
-lenFunc returns the size of a list or map.
-*/
-type lenFunc struct {
-	*inbuildBaseFunc
-}
+		if funcVal, ok := v.(*function); ok {
+			if objFunc, ok := objVal.(*function); !ok || objFunc.declaration != funcVal.declaration {
+				return false
+			}
+			identified = true
+		} else if superList, ok := v.([]interface{}); ok && k == "super" {
+			if objSuperList, ok := objVal.([]interface{}); !ok || len(objSuperList) != len(superList) ||
+				(len(superList) > 0 && &objSuperList[0] != &superList[0]) {
+				return false
+			}
+			identified = true
+		}
+	}
+
+	return identified
+}
+
+/*
+inSuperChain checks if a template is part of the super classes of an object.
+*/
+func (rf *instanceofFunc) inSuperChain(obj map[interface{}]interface{}, template map[interface{}]interface{}) bool {
+
+	if superList, ok := obj["super"].([]interface{}); ok {
+		for _, superObj := range superList {
+			if superTemplate, ok := superObj.(map[interface{}]interface{}); ok {
+				if rf.sameMap(superTemplate, template) || rf.inSuperChain(superTemplate, template) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+sameMap checks if two maps are the same map instance.
+*/
+func (rf *instanceofFunc) sameMap(m1 map[interface{}]interface{}, m2 map[interface{}]interface{}) bool {
+	return reflect.ValueOf(m1).Pointer() == reflect.ValueOf(m2).Pointer()
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *instanceofFunc) DocString() (string, error) {
+	return "Checks if an object was created from a given template or one of its subclasses.", nil
+}
+
+// Type
+// =====
+
+/*
+typeFunc returns the underlying types and values of an object.
+*/
+type typeFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *typeFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a value as first parameter")
+
+	if len(args) > 0 {
+		res = fmt.Sprintf("%#v", args[0])
+		err = nil
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *typeFunc) DocString() (string, error) {
+	return "Returns the underlying types and values of an object.", nil
+}
+
+// Len
+// ===
+
+/*
+lenFunc returns the size of a list or map.
+*/
+type lenFunc struct {
+	*inbuildBaseFunc
+}
 
 /*
 Run executes this function.
@@ -401,45 +917,1286 @@ func (rf *delFunc) Run(instanceID string, vs parser.Scope, is map[string]interfa
 /*
 DocString returns a descriptive string.
 */
-func (rf *delFunc) DocString() (string, error) {
-	return "Removes an item from a list or map.", nil
+func (rf *delFunc) DocString() (string, error) {
+	return "Removes an item from a list or map.", nil
+}
+
+// Add
+// ===
+
+/*
+addFunc adds an element to a list.
+*/
+type addFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *addFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list as first parameter and a value as second parameter")
+
+	if len(args) > 1 {
+		var argList []interface{}
+
+		if argList, err = rf.AssertListParam(1, args[0]); err == nil {
+			if len(args) == 3 {
+				var index float64
+
+				if index, err = rf.AssertNumParam(3, args[2]); err == nil {
+					argList = append(argList, 0)
+					copy(argList[int(index+1):], argList[int(index):])
+					argList[int(index)] = args[1]
+					res = argList
+				}
+			} else {
+				res = append(argList, args[1])
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *addFunc) DocString() (string, error) {
+	return "Adds an item to a list. The item is added at the optionally given index or at the end if no index is specified.", nil
+}
+
+// Concat
+// ======
+
+/*
+concatFunc joins one or more lists together.
+*/
+type concatFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *concatFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need at least two lists as parameters")
+
+	if len(args) > 1 {
+		var argList []interface{}
+
+		resList := make([]interface{}, 0)
+		err = nil
+
+		for _, a := range args {
+			if err == nil {
+				if argList, err = rf.AssertListParam(1, a); err == nil {
+					resList = append(resList, argList...)
+				}
+			}
+		}
+
+		if err == nil {
+			res = resList
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *concatFunc) DocString() (string, error) {
+	return "Joins one or more lists together. The result is a new list.", nil
+}
+
+// Clone
+// =====
+
+/*
+cloneFunc creates a shallow copy of a list or map.
+*/
+type cloneFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *cloneFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list or a map as parameter")
+
+	if len(args) == 1 {
+		switch val := args[0].(type) {
+		case []interface{}:
+			resList := make([]interface{}, len(val))
+			copy(resList, val)
+			res, err = resList, nil
+
+		case map[interface{}]interface{}:
+			resMap := make(map[interface{}]interface{}, len(val))
+			for k, v := range val {
+				resMap[k] = v
+			}
+			res, err = resMap, nil
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *cloneFunc) DocString() (string, error) {
+	return "Creates a shallow copy of a list or map. Nested values are shared with the original.", nil
+}
+
+// Filter
+// ======
+
+/*
+filterFunc returns all items of a list for which a given predicate function
+returns a true value.
+*/
+type filterFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *filterFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list and a predicate function as parameters")
+
+	if len(args) == 2 {
+		var list []interface{}
+
+		if list, err = rf.AssertListParam(1, args[0]); err == nil {
+			predicate, ok := args[1].(util.ECALFunction)
+
+			if !ok {
+				err = fmt.Errorf("Parameter 2 should be a function")
+			}
+
+			resList := make([]interface{}, 0)
+
+			for _, item := range list {
+				var ret interface{}
+
+				if err == nil {
+					ret, err = predicate.Run(fmt.Sprintf("%v:filter", instanceID), vs,
+						make(map[string]interface{}), tid, []interface{}{item})
+
+					if err == nil && ret != nil && ret != false && ret != 0 {
+						resList = append(resList, item)
+					}
+				}
+			}
+
+			if err == nil {
+				res = resList
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *filterFunc) DocString() (string, error) {
+	return "Returns a new list with all items of a list for which a predicate function returns true.", nil
+}
+
+// MapFunc
+// =======
+
+/*
+mapFunc returns a list with the results of a transform function applied to all
+items of a given list.
+*/
+type mapFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *mapFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list and a transform function as parameters")
+
+	if len(args) == 2 {
+		var list []interface{}
+
+		if list, err = rf.AssertListParam(1, args[0]); err == nil {
+			transform, ok := args[1].(util.ECALFunction)
+
+			if !ok {
+				err = fmt.Errorf("Parameter 2 should be a function")
+			}
+
+			resList := make([]interface{}, 0, len(list))
+
+			for _, item := range list {
+				var ret interface{}
+
+				if err == nil {
+					ret, err = transform.Run(fmt.Sprintf("%v:mapFunc", instanceID), vs,
+						make(map[string]interface{}), tid, []interface{}{item})
+
+					if err == nil {
+						resList = append(resList, ret)
+					}
+				}
+			}
+
+			if err == nil {
+				res = resList
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *mapFunc) DocString() (string, error) {
+	return "Returns a new list with the results of a transform function applied to each item of a list.", nil
+}
+
+// Reduce
+// ======
+
+/*
+reduceFunc reduces a list to a single value by calling an accumulator function
+with the current accumulated value and each item of the list.
+*/
+type reduceFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *reduceFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list, an accumulator function and an initial value as parameters")
+
+	if len(args) == 3 {
+		var list []interface{}
+
+		if list, err = rf.AssertListParam(1, args[0]); err == nil {
+			accumulator, ok := args[1].(util.ECALFunction)
+
+			if !ok {
+				err = fmt.Errorf("Parameter 2 should be a function")
+			}
+
+			acc := args[2]
+
+			for _, item := range list {
+				if err == nil {
+					acc, err = accumulator.Run(fmt.Sprintf("%v:reduce", instanceID), vs,
+						make(map[string]interface{}), tid, []interface{}{acc, item})
+				}
+			}
+
+			if err == nil {
+				res = acc
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *reduceFunc) DocString() (string, error) {
+	return "Reduces a list to a single value using an accumulator function and an initial value.", nil
+}
+
+// GroupBy
+// =======
+
+/*
+groupByFunc groups the items of a list by a key which is calculated by a given
+key function.
+*/
+type groupByFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *groupByFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list and a key function as parameters")
+
+	if len(args) == 2 {
+		var list []interface{}
+
+		if list, err = rf.AssertListParam(1, args[0]); err == nil {
+			keyFunc, ok := args[1].(util.ECALFunction)
+
+			if !ok {
+				err = fmt.Errorf("Parameter 2 should be a function")
+			}
+
+			resMap := make(map[interface{}]interface{})
+
+			for _, item := range list {
+				var key interface{}
+
+				if err == nil {
+					key, err = keyFunc.Run(fmt.Sprintf("%v:groupBy", instanceID), vs,
+						make(map[string]interface{}), tid, []interface{}{item})
+
+					if err == nil {
+
+						// Lists and maps cannot be used as map keys
+
+						switch key.(type) {
+						case []interface{}, map[interface{}]interface{}:
+							key = fmt.Sprint(key)
+						}
+
+						group, _ := resMap[key].([]interface{})
+						resMap[key] = append(group, item)
+					}
+				}
+			}
+
+			if err == nil {
+				res = resMap
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *groupByFunc) DocString() (string, error) {
+	return "Groups the items of a list into a map of lists using the keys returned by a key function.", nil
+}
+
+// Once
+// ====
+
+/*
+onceFunc wraps a function so it is only executed once.
+*/
+type onceFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *onceFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a function as parameter")
+
+	if len(args) == 1 {
+		if f, ok := args[0].(util.ECALFunction); ok {
+			res = &onceFunction{f: f}
+			err = nil
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *onceFunc) DocString() (string, error) {
+	return "Wraps a function so it is only executed on the first call. Subsequent calls return the first result.", nil
+}
+
+/*
+onceFunction is a function wrapper which executes the wrapped function only
+on the first call and returns the cached result on all subsequent calls.
+*/
+type onceFunction struct {
+	f    util.ECALFunction // Wrapped function
+	once sync.Once         // Guard for the single execution
+	res  interface{}       // Cached result
+	err  error             // Cached error
+}
+
+/*
+Run executes the wrapped function if it has not been executed before.
+*/
+func (of *onceFunction) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	of.once.Do(func() {
+		of.res, of.err = of.f.Run(instanceID, vs, is, tid, args)
+	})
+
+	return of.res, of.err
+}
+
+/*
+DocString returns the descriptive string of the wrapped function.
+*/
+func (of *onceFunction) DocString() (string, error) {
+	return of.f.DocString()
+}
+
+/*
+String returns a string representation of this function wrapper.
+*/
+func (of *onceFunction) String() string {
+	return fmt.Sprintf("ecal.once: %v", of.f)
+}
+
+/*
+MarshalJSON returns a string representation of this function wrapper - a
+function cannot be JSON encoded.
+*/
+func (of *onceFunction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(of.String())
+}
+
+// Memoize
+// =======
+
+/*
+memoizeFunc wraps a function so its results are cached based on the given
+arguments.
+*/
+type memoizeFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *memoizeFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a function and optionally a maximum cache size as parameters")
+
+	if len(args) == 1 || len(args) == 2 {
+		if f, ok := args[0].(util.ECALFunction); ok {
+			var maxSize float64
+
+			err = nil
+
+			if len(args) == 2 {
+				if maxSize, err = rf.AssertNumParam(2, args[1]); err == nil && maxSize < 1 {
+					err = fmt.Errorf("Maximum cache size must be at least 1")
+				}
+			}
+
+			if err == nil {
+				res = &memoizeFunction{f: f, maxSize: int(maxSize),
+					cache: make(map[string]interface{})}
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *memoizeFunc) DocString() (string, error) {
+	return "Wraps a function so its results are cached based on the given arguments. An optional second parameter limits the cache size.", nil
+}
+
+/*
+memoizeFunction is a function wrapper which caches the results of the wrapped
+function. If a maximum size is set then the least recently used entry is
+removed when the cache is full.
+*/
+type memoizeFunction struct {
+	f       util.ECALFunction      // Wrapped function
+	maxSize int                    // Maximum size of the cache (0 is unlimited)
+	cache   map[string]interface{} // Cached results
+	order   []string               // Cache keys from least to most recently used
+	lock    sync.RWMutex           // Lock for the cache
+}
+
+/*
+Run executes the wrapped function if there is no cached result for the given
+arguments.
+*/
+func (mf *memoizeFunction) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	key := fmt.Sprintf("%#v", args)
+
+	mf.lock.RLock()
+	res, ok := mf.cache[key]
+	mf.lock.RUnlock()
+
+	if ok {
+		if mf.maxSize > 0 {
+			mf.lock.Lock()
+
+			// The entry might have been evicted in the meantime
+
+			if _, ok := mf.cache[key]; ok {
+				mf.touch(key)
+			}
+
+			mf.lock.Unlock()
+		}
+
+		return res, nil
+	}
+
+	res, err := mf.f.Run(instanceID, vs, is, tid, args)
+
+	if err == nil {
+		mf.lock.Lock()
+		defer mf.lock.Unlock()
+
+		if _, ok := mf.cache[key]; !ok && mf.maxSize > 0 && len(mf.cache) >= mf.maxSize {
+			delete(mf.cache, mf.order[0])
+			mf.order = mf.order[1:]
+		}
+
+		mf.cache[key] = res
+
+		if mf.maxSize > 0 {
+			mf.touch(key)
+		}
+	}
+
+	return res, err
+}
+
+/*
+touch marks a cache key as most recently used.
+*/
+func (mf *memoizeFunction) touch(key string) {
+	for i, k := range mf.order {
+		if k == key {
+			mf.order = append(mf.order[:i], mf.order[i+1:]...)
+			break
+		}
+	}
+
+	mf.order = append(mf.order, key)
+}
+
+/*
+DocString returns the descriptive string of the wrapped function.
+*/
+func (mf *memoizeFunction) DocString() (string, error) {
+	return mf.f.DocString()
+}
+
+/*
+String returns a string representation of this function wrapper.
+*/
+func (mf *memoizeFunction) String() string {
+	return fmt.Sprintf("ecal.memoize: %v", mf.f)
+}
+
+/*
+MarshalJSON returns a string representation of this function wrapper - a
+function cannot be JSON encoded.
+*/
+func (mf *memoizeFunction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mf.String())
+}
+
+// Parallel
+// ========
+
+/*
+parallelFunc executes a list of functions concurrently and returns their
+results in the original order.
+*/
+type parallelFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *parallelFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a list of functions as parameter")
+
+	if len(args) == 1 {
+		var funcList []interface{}
+
+		if funcList, err = rf.AssertListParam(1, args[0]); err == nil {
+			funcs := make([]util.ECALFunction, len(funcList))
+
+			for i, f := range funcList {
+				var ok bool
+
+				if funcs[i], ok = f.(util.ECALFunction); !ok && err == nil {
+					err = fmt.Errorf("Item %v of parameter 1 should be a function", i+1)
+				}
+			}
+
+			if err == nil {
+				var wg sync.WaitGroup
+
+				erp := is["erp"].(*ECALRuntimeProvider)
+				results := make([]interface{}, len(funcs))
+				errs := make([]error, len(funcs))
+
+				for i, f := range funcs {
+					wg.Add(1)
+
+					go func(i int, f util.ECALFunction, ftid uint64) {
+						defer wg.Done()
+						results[i], errs[i] = f.Run(fmt.Sprintf("%v:parallel:%v", instanceID, i),
+							vs, make(map[string]interface{}), ftid, nil)
+					}(i, f, erp.NewThreadID())
+				}
+
+				wg.Wait()
+
+				var errMsgs []string
+				errData := make(map[interface{}]interface{})
+
+				for i, ferr := range errs {
+					if ferr != nil {
+						errMsgs = append(errMsgs, ferr.Error())
+						errData[float64(i)] = ferr.Error()
+					}
+				}
+
+				if len(errMsgs) > 0 {
+					node := is["astnode"].(*parser.ASTNode)
+
+					err = &util.RuntimeErrorWithDetail{
+						RuntimeError: erp.NewRuntimeError(util.ErrRuntimeError,
+							fmt.Sprintf("%v of %v functions failed: %v", len(errMsgs), len(funcs),
+								strings.Join(errMsgs, "; ")), node).(*util.RuntimeError),
+						Environment: vs,
+						Data:        errData,
+					}
+				} else {
+					res = results
+				}
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *parallelFunc) DocString() (string, error) {
+	return "Executes a list of functions concurrently and returns their results in the original order.", nil
+}
+
+// newLock / newRWLock
+// ===================
+
+/*
+newLockFunc creates a new lock object. The lock object is a map which holds
+the methods of a mutex or a readers / writer mutex.
+*/
+type newLockFunc struct {
+	*inbuildBaseFunc
+	rw bool // Flag if a readers / writer lock should be created
+}
+
+/*
+Run executes this function.
+*/
+func (rf *newLockFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+
+	l := &lockObject{}
+
+	if rf.rw {
+		return map[interface{}]interface{}{
+			"lock":    newLockMethod("Acquires the write lock.", l.lock),
+			"unlock":  newLockMethod("Releases the write lock.", l.unlock),
+			"rlock":   newLockMethod("Acquires a read lock.", l.rlock),
+			"runlock": newLockMethod("Releases a read lock.", l.runlock),
+		}, nil
+	}
+
+	return map[interface{}]interface{}{
+		"lock":   newLockMethod("Acquires the lock.", l.lock),
+		"unlock": newLockMethod("Releases the lock.", l.unlock),
+	}, nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *newLockFunc) DocString() (string, error) {
+	if rf.rw {
+		return "Creates a new readers / writer lock object with the methods rlock, runlock, lock and unlock.", nil
+	}
+	return "Creates a new lock object with the methods lock and unlock.", nil
+}
+
+/*
+lockObject is a readers / writer lock which keeps track if it is held so
+releasing a free lock results in an error.
+*/
+type lockObject struct {
+	l       sync.RWMutex // Underlying lock
+	held    int32        // Flag if the write lock is held
+	readers int32        // Number of held read locks
+}
+
+/*
+lock acquires the write lock.
+*/
+func (lo *lockObject) lock() error {
+	lo.l.Lock()
+	atomic.StoreInt32(&lo.held, 1)
+	return nil
+}
+
+/*
+unlock releases the write lock.
+*/
+func (lo *lockObject) unlock() error {
+	if !atomic.CompareAndSwapInt32(&lo.held, 1, 0) {
+		return fmt.Errorf("Lock is not held")
+	}
+	lo.l.Unlock()
+	return nil
+}
+
+/*
+rlock acquires a read lock.
+*/
+func (lo *lockObject) rlock() error {
+	lo.l.RLock()
+	atomic.AddInt32(&lo.readers, 1)
+	return nil
+}
+
+/*
+runlock releases a read lock.
+*/
+func (lo *lockObject) runlock() error {
+	for {
+		readers := atomic.LoadInt32(&lo.readers)
+
+		if readers < 1 {
+			return fmt.Errorf("Read lock is not held")
+		}
+
+		if atomic.CompareAndSwapInt32(&lo.readers, readers, readers-1) {
+			break
+		}
+	}
+	lo.l.RUnlock()
+	return nil
+}
+
+/*
+newLockMethod creates a new object method for a lock operation.
+*/
+func newLockMethod(doc string, op func() error) *objectMethod {
+	return &objectMethod{doc, func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+		return nil, op()
+	}}
+}
+
+/*
+objectMethodFunc is the implementation of an object method.
+*/
+type objectMethodFunc func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error)
+
+/*
+objectMethod is a method of an object which is implemented in Go.
+*/
+type objectMethod struct {
+	doc    string           // Description of the method
+	method objectMethodFunc // Method implementation
+}
+
+/*
+Run executes the method.
+*/
+func (om *objectMethod) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	return om.method(vs, tid, args)
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (om *objectMethod) DocString() (string, error) {
+	return om.doc, nil
+}
+
+/*
+String returns a string representation of this method.
+*/
+func (om *objectMethod) String() string {
+	return "ecal.method"
+}
+
+/*
+MarshalJSON returns a string representation of this method - a function
+cannot be JSON encoded.
+*/
+func (om *objectMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(om.String())
+}
+
+// newChannel
+// ==========
+
+/*
+newChannelFunc creates a new channel object. The channel object is a map
+which holds the methods of the channel.
+*/
+type newChannelFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *newChannelFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+	var capacity float64
+	var err error
+
+	if len(args) > 0 {
+		if capacity, err = rf.AssertNumParam(1, args[0]); err == nil && capacity < 0 {
+			err = fmt.Errorf("Channel capacity must not be negative")
+		}
+	}
+
+	if err == nil {
+		c := make(chan interface{}, int(capacity))
+
+		res = map[interface{}]interface{}{
+			"send": &objectMethod{"Sends a value. Blocks if the channel is full.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (res interface{}, err error) {
+					if len(args) != 1 {
+						return nil, fmt.Errorf("Need a value as parameter")
+					}
+
+					defer func() {
+						if recover() != nil {
+							err = fmt.Errorf("Channel is closed")
+						}
+					}()
+
+					c <- args[0]
+
+					return nil, nil
+				}},
+
+			"recv": &objectMethod{"Receives a value. Blocks until a value is available.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					if val, ok := <-c; ok {
+						return val, nil
+					}
+					return nil, util.ErrEndOfIteration
+				}},
+
+			"tryRecv": &objectMethod{"Receives a value if one is available otherwise returns null.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					select {
+					case val, ok := <-c:
+						if !ok {
+							return nil, util.ErrEndOfIteration
+						}
+						return val, nil
+					default:
+						return nil, nil
+					}
+				}},
+
+			"close": &objectMethod{"Closes the channel.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (res interface{}, err error) {
+					defer func() {
+						if recover() != nil {
+							err = fmt.Errorf("Channel is already closed")
+						}
+					}()
+
+					close(c)
+
+					return nil, nil
+				}},
+
+			"len": &objectMethod{"Returns the number of values in the channel.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					return float64(len(c)), nil
+				}},
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *newChannelFunc) DocString() (string, error) {
+	return "Creates a new channel object with the methods send, recv, tryRecv, close and len.", nil
+}
+
+// newCounter
+// ==========
+
+/*
+newCounterFunc creates a new counter object. The counter object is a map
+which holds the methods of an atomic counter.
+*/
+type newCounterFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *newCounterFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+	var initial float64
+	var err error
+
+	if len(args) > 0 {
+		initial, err = rf.AssertNumParam(1, args[0])
+	}
+
+	if err == nil {
+		c := &atomic.Int64{}
+		c.Store(int64(initial))
+
+		res = map[interface{}]interface{}{
+			"increment": &objectMethod{"Increments the counter by 1 and returns the new value.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					return float64(c.Add(1)), nil
+				}},
+
+			"decrement": &objectMethod{"Decrements the counter by 1 and returns the new value.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					return float64(c.Add(-1)), nil
+				}},
+
+			"add": &objectMethod{"Adds a number to the counter and returns the new value.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					if len(args) != 1 {
+						return nil, fmt.Errorf("Need a number as parameter")
+					}
+
+					n, err := rf.AssertNumParam(1, args[0])
+					if err != nil {
+						return nil, err
+					}
+
+					return float64(c.Add(int64(n))), nil
+				}},
+
+			"get": &objectMethod{"Returns the current value of the counter.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					return float64(c.Load()), nil
+				}},
+
+			"reset": &objectMethod{"Resets the counter to its initial value.",
+				func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+					c.Store(int64(initial))
+					return nil, nil
+				}},
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *newCounterFunc) DocString() (string, error) {
+	return "Creates a new atomic counter object with the methods increment, decrement, add, get and reset.", nil
+}
+
+// newEventEmitter
+// ===============
+
+/*
+newEventEmitterFunc creates a new event emitter object. The event emitter
+calls registered handler functions synchronously when an event is emitted.
+*/
+type newEventEmitterFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+eventHandler is a handler function which was registered with an event emitter.
+*/
+type eventHandler struct {
+	handler util.ECALFunction // Handler function
+	once    bool              // Flag if the handler should be removed after the first call
+}
+
+/*
+Run executes this function.
+*/
+func (rf *newEventEmitterFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var lock sync.Mutex
+
+	handlers := make(map[string][]*eventHandler)
+
+	addHandler := func(once bool) objectMethodFunc {
+		return func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("Need an event name and a handler function as parameters")
+			}
+
+			handler, ok := args[1].(util.ECALFunction)
+			if !ok {
+				return nil, fmt.Errorf("Parameter 2 should be a function")
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			event := fmt.Sprint(args[0])
+			handlers[event] = append(handlers[event], &eventHandler{handler, once})
+
+			return nil, nil
+		}
+	}
+
+	return map[interface{}]interface{}{
+		"on":   &objectMethod{"Registers a handler function for an event.", addHandler(false)},
+		"once": &objectMethod{"Registers a handler function for an event which is removed after the first call.", addHandler(true)},
+
+		"off": &objectMethod{"Removes a handler function for an event.",
+			func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("Need an event name and a handler function as parameters")
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				event := fmt.Sprint(args[0])
+				remaining := make([]*eventHandler, 0, len(handlers[event]))
+
+				for _, h := range handlers[event] {
+					if h.handler != args[1] {
+						remaining = append(remaining, h)
+					}
+				}
+
+				handlers[event] = remaining
+
+				return nil, nil
+			}},
+
+		"emit": &objectMethod{"Calls all handler functions of an event with the given data.",
+			func(vs parser.Scope, tid uint64, args []interface{}) (interface{}, error) {
+				var data interface{}
+				var err error
+
+				if len(args) == 0 {
+					return nil, fmt.Errorf("Need an event name as parameter")
+				}
+
+				if len(args) > 1 {
+					data = args[1]
+				}
+
+				event := fmt.Sprint(args[0])
+
+				// Take a copy of the handler list so handlers can modify the registrations
+
+				lock.Lock()
+				eventHandlers := handlers[event]
+				remaining := make([]*eventHandler, 0, len(eventHandlers))
+
+				for _, h := range eventHandlers {
+					if !h.once {
+						remaining = append(remaining, h)
+					}
+				}
+
+				handlers[event] = remaining
+				lock.Unlock()
+
+				for _, h := range eventHandlers {
+					if err == nil {
+						_, err = h.handler.Run(fmt.Sprintf("%v:emit", instanceID), vs,
+							make(map[string]interface{}), tid, []interface{}{data})
+					}
+				}
+
+				return nil, err
+			}},
+	}, nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *newEventEmitterFunc) DocString() (string, error) {
+	return "Creates a new event emitter object with the methods on, once, off and emit.", nil
+}
+
+// hasPrefix
+// =========
+
+/*
+hasPrefixFunc checks if a string starts with a given prefix.
+*/
+type hasPrefixFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *hasPrefixFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a string and a prefix as parameters")
+
+	if len(args) == 2 {
+		res = strings.HasPrefix(fmt.Sprint(args[0]), fmt.Sprint(args[1]))
+		err = nil
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *hasPrefixFunc) DocString() (string, error) {
+	return "Checks if a string starts with a given prefix.", nil
+}
+
+// hasSuffix
+// =========
+
+/*
+hasSuffixFunc checks if a string ends with a given suffix.
+*/
+type hasSuffixFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *hasSuffixFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+
+	err := fmt.Errorf("Need a string and a suffix as parameters")
+
+	if len(args) == 2 {
+		res = strings.HasSuffix(fmt.Sprint(args[0]), fmt.Sprint(args[1]))
+		err = nil
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *hasSuffixFunc) DocString() (string, error) {
+	return "Checks if a string ends with a given suffix.", nil
+}
+
+// compile
+// =======
+
+/*
+compileFunc parses and validates a code string once and returns a compiled
+object which can be executed repeatedly with call.
+*/
+type compileFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *compileFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+	err := fmt.Errorf("Need a code string as parameter")
+
+	if len(args) > 0 {
+		var ast *parser.ASTNode
+
+		code := fmt.Sprint(args[0])
+		erp := is["erp"].(*ECALRuntimeProvider)
+
+		if ast, err = parser.ParseWithRuntime(fmt.Sprintf("Compiled code: %v", code), code, erp); err == nil {
+			if err = ast.Runtime.Validate(); err == nil {
+				res = &compiledECAL{code, ast}
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *compileFunc) DocString() (string, error) {
+	return "Compiles a code string into an object which can be executed with call.", nil
 }
 
-// Add
-// ===
-
 /*
-addFunc adds an element to a list.
+compiledECAL is a pre-parsed and validated piece of ECAL code.
 */
-type addFunc struct {
-	*inbuildBaseFunc
+type compiledECAL struct {
+	code string          // Source code
+	ast  *parser.ASTNode // Parsed and validated AST
 }
 
 /*
-Run executes this function.
+Run executes the compiled code in a new variable scope. The given arguments
+are available as $1, $2, etc.
 */
-func (rf *addFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
-	var res interface{}
+func (c *compiledECAL) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	cvs := scope.NewScope("compiled")
 
-	err := fmt.Errorf("Need a list as first parameter and a value as second parameter")
+	for i, arg := range args {
+		cvs.SetValue(fmt.Sprintf("$%v", i+1), arg)
+	}
 
-	if len(args) > 1 {
-		var argList []interface{}
+	res, err := c.ast.Runtime.Eval(cvs, make(map[string]interface{}), tid)
 
-		if argList, err = rf.AssertListParam(1, args[0]); err == nil {
-			if len(args) == 3 {
-				var index float64
+	// Check for return value (delivered as error object)
 
-				if index, err = rf.AssertNumParam(3, args[2]); err == nil {
-					argList = append(argList, 0)
-					copy(argList[int(index+1):], argList[int(index):])
-					argList[int(index)] = args[1]
-					res = argList
-				}
-			} else {
-				res = append(argList, args[1])
-			}
-		}
+	if rval, ok := err.(*returnValue); ok {
+		res = rval.returnValue
+		err = nil
 	}
 
 	return res, err
@@ -448,44 +2205,45 @@ func (rf *addFunc) Run(instanceID string, vs parser.Scope, is map[string]interfa
 /*
 DocString returns a descriptive string.
 */
-func (rf *addFunc) DocString() (string, error) {
-	return "Adds an item to a list. The item is added at the optionally given index or at the end if no index is specified.", nil
+func (c *compiledECAL) DocString() (string, error) {
+	return fmt.Sprintf("Compiled code: %v", c.code), nil
 }
 
-// Concat
-// ======
+/*
+String returns a string representation of this compiled code.
+*/
+func (c *compiledECAL) String() string {
+	return fmt.Sprintf("ecal.compiled: %v", c.code)
+}
 
 /*
-concatFunc joins one or more lists together.
+MarshalJSON returns a string representation of this compiled code - compiled
+code cannot be JSON encoded.
 */
-type concatFunc struct {
+func (c *compiledECAL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// call
+// ====
+
+/*
+callFunc executes compiled code.
+*/
+type callFunc struct {
 	*inbuildBaseFunc
 }
 
 /*
 Run executes this function.
 */
-func (rf *concatFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+func (rf *callFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
 	var res interface{}
+	err := fmt.Errorf("Need compiled code as first parameter")
 
-	err := fmt.Errorf("Need at least two lists as parameters")
-
-	if len(args) > 1 {
-		var argList []interface{}
-
-		resList := make([]interface{}, 0)
-		err = nil
-
-		for _, a := range args {
-			if err == nil {
-				if argList, err = rf.AssertListParam(1, a); err == nil {
-					resList = append(resList, argList...)
-				}
-			}
-		}
-
-		if err == nil {
-			res = resList
+	if len(args) > 0 {
+		if c, ok := args[0].(*compiledECAL); ok {
+			res, err = c.Run(instanceID, vs, is, tid, args[1:])
 		}
 	}
 
@@ -495,8 +2253,8 @@ func (rf *concatFunc) Run(instanceID string, vs parser.Scope, is map[string]inte
 /*
 DocString returns a descriptive string.
 */
-func (rf *concatFunc) DocString() (string, error) {
-	return "Joins one or more lists together. The result is a new list.", nil
+func (rf *callFunc) DocString() (string, error) {
+	return "Executes compiled code. Additional parameters are available as $1, $2, etc.", nil
 }
 
 // dumpenv
@@ -658,7 +2416,7 @@ func (rf *docFunc) Run(instanceID string, vs parser.Scope, is map[string]interfa
 
 				// Check for inbuild function
 
-				funcObj, ok = InbuildFuncMap[astring]
+				funcObj, ok = lookupInbuildFunc(astring)
 			}
 		}
 
@@ -714,6 +2472,210 @@ func (rf *sleepFunc) DocString() (string, error) {
 	return "Pauses the current thread for a number of micro seconds.", nil
 }
 
+// retry / retryWithBackoff
+// ========================
+
+/*
+retryFunc calls a function repeatedly until it succeeds or a maximum number of
+attempts is reached. There is a delay between the attempts which can optionally
+be increased by a backoff factor after each attempt.
+*/
+type retryFunc struct {
+	*inbuildBaseFunc
+	backoff bool // Flag if the delay should be increased by a backoff factor
+}
+
+/*
+Run executes this function.
+*/
+func (rf *retryFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+	var f util.ECALFunction
+	var attempts, delay float64
+
+	factor := 1.
+	numArgs := 3
+	err := fmt.Errorf("Need a function, maximum attempts and a delay in milliseconds as parameters")
+
+	if rf.backoff {
+		numArgs = 4
+		err = fmt.Errorf("Need a function, maximum attempts, an initial delay in milliseconds and a backoff factor as parameters")
+	}
+
+	if len(args) == numArgs {
+		var ok bool
+
+		if f, ok = args[0].(util.ECALFunction); !ok {
+			err = fmt.Errorf("Parameter 1 should be a function")
+		} else if attempts, err = rf.AssertNumParam(2, args[1]); err == nil {
+			if delay, err = rf.AssertNumParam(3, args[2]); err == nil && rf.backoff {
+				factor, err = rf.AssertNumParam(4, args[3])
+			}
+		}
+
+		if err == nil && attempts < 1 {
+			err = fmt.Errorf("Maximum attempts must be at least 1")
+		}
+
+		if err == nil {
+			for i := 0; i < int(attempts); i++ {
+				if i > 0 {
+					time.Sleep(time.Duration(delay * float64(time.Millisecond)))
+					delay *= factor
+				}
+
+				if res, err = f.Run(instanceID, vs, make(map[string]interface{}), tid, nil); err == nil {
+					break
+				}
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *retryFunc) DocString() (string, error) {
+	if rf.backoff {
+		return "Calls a function until it succeeds or a maximum number of attempts is reached. The delay between attempts is multiplied by a backoff factor after each attempt.", nil
+	}
+	return "Calls a function until it succeeds or a maximum number of attempts is reached.", nil
+}
+
+// timeout
+// =======
+
+/*
+timeoutFunc runs a function with a time limit.
+*/
+type timeoutFunc struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *timeoutFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	var res interface{}
+	var ms float64
+
+	err := fmt.Errorf("Need a function and a timeout in milliseconds as parameters")
+
+	if len(args) == 2 {
+		f, ok := args[0].(util.ECALFunction)
+
+		if !ok {
+			err = fmt.Errorf("Parameter 1 should be a function")
+		} else if ms, err = rf.AssertNumParam(2, args[1]); err == nil {
+			type result struct {
+				res interface{}
+				err error
+			}
+
+			parent, ok := is["context"].(context.Context)
+			if !ok {
+				parent = context.Background()
+			}
+
+			ctx, cancel := context.WithCancel(parent)
+			defer cancel()
+
+			// The function keeps the instance state of the caller (e.g. the
+			// monitor of a sink) and gets the cancellation context
+
+			fis := make(map[string]interface{})
+			for k, v := range is {
+				fis[k] = v
+			}
+			fis["context"] = ctx
+
+			resChan := make(chan result, 1)
+
+			go func() {
+				fres, ferr := f.Run(instanceID, vs, fis, tid, nil)
+				resChan <- result{fres, ferr}
+			}()
+
+			select {
+			case r := <-resChan:
+				res, err = r.res, r.err
+
+			case <-time.After(time.Duration(ms * float64(time.Millisecond))):
+				erp := is["erp"].(*ECALRuntimeProvider)
+				node := is["astnode"].(*parser.ASTNode)
+
+				err = erp.NewRuntimeError(util.ErrTimeout,
+					fmt.Sprintf("Function did not finish within %v ms", ms), node)
+			}
+		}
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *timeoutFunc) DocString() (string, error) {
+	return "Runs a function and raises a timeout error if it does not finish within a number of milliseconds.", nil
+}
+
+// log / debug / error / warn
+// ==========================
+
+/*
+logFunc writes a message to the logger of the runtime provider.
+*/
+type logFunc struct {
+	*inbuildBaseFunc
+	level string
+}
+
+/*
+Run executes this function.
+*/
+func (rf *logFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	erp := is["erp"].(*ECALRuntimeProvider)
+
+	// Convert non-string structures
+
+	for i, a := range args {
+		if _, ok := a.(string); !ok {
+			args[i] = stringutil.ConvertToPrettyString(a)
+		}
+	}
+
+	switch rf.level {
+	case "error":
+		erp.Logger.LogError(args...)
+	case "debug":
+		erp.Logger.LogDebug(args...)
+	case "warn":
+		erp.Logger.LogInfo(append([]interface{}{"warning: "}, args...)...)
+	default:
+		erp.Logger.LogInfo(args...)
+	}
+
+	return nil, nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *logFunc) DocString() (string, error) {
+	switch rf.level {
+	case "error":
+		return "Writes an error message to the log.", nil
+	case "debug":
+		return "Writes a debug message to the log.", nil
+	case "warn":
+		return "Writes a warning message to the log.", nil
+	}
+	return "Writes an info message to the log.", nil
+}
+
 // raise
 // =====
 
@@ -750,12 +2712,8 @@ func (rf *raise) Run(instanceID string, vs parser.Scope, is map[string]interface
 	erp := is["erp"].(*ECALRuntimeProvider)
 	node := is["astnode"].(*parser.ASTNode)
 
-	return nil, &util.RuntimeErrorWithDetail{
-		RuntimeError: erp.NewRuntimeError(err, detailMsg, node).(*util.RuntimeError),
-		Environment:  vs,
-		Data:         detail,
-	}
-
+	return nil, erp.NewRuntimeErrorBuilder(err, node).Detail(detailMsg).
+		Environment(vs).Data(detail).Build()
 }
 
 /*
@@ -822,7 +2780,17 @@ func (rf *addevent) addEvent(addFunc func(engine.Processor, *engine.Event, *engi
 				stateMap,
 			)
 
-			if len(args) > 3 {
+			if len(args) > 4 {
+				var schemaMap map[interface{}]interface{}
+
+				// Validate the state against an optional schema
+
+				if schemaMap, err = rf.AssertMapParam(5, args[4]); err == nil {
+					err = rf.validateState(stateMap, schemaMap)
+				}
+			}
+
+			if err == nil && len(args) > 3 && args[3] != nil {
 				var scopeMap map[interface{}]interface{}
 
 				// Add optional scope - if not specified it is { "": true }
@@ -848,6 +2816,55 @@ func (rf *addevent) addEvent(addFunc func(engine.Processor, *engine.Event, *engi
 	return res, err
 }
 
+/*
+validateState checks that an event state contains all fields of a given schema
+and that each field has the type which is defined in the schema.
+*/
+func (rf *addevent) validateState(stateMap map[interface{}]interface{}, schemaMap map[interface{}]interface{}) error {
+	var fields []string
+
+	schema := make(map[string]string)
+
+	for k, v := range schemaMap {
+		schema[fmt.Sprint(k)] = fmt.Sprint(v)
+		fields = append(fields, fmt.Sprint(k))
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		var ok bool
+
+		val, found := stateMap[field]
+
+		if !found {
+			return fmt.Errorf("Event state is missing field %v", field)
+		}
+
+		switch schema[field] {
+		case "string":
+			_, ok = val.(string)
+		case "number":
+			_, ok = val.(float64)
+		case "bool":
+			_, ok = val.(bool)
+		case "list":
+			_, ok = val.([]interface{})
+		case "map":
+			_, ok = val.(map[interface{}]interface{})
+		default:
+			return fmt.Errorf("Unknown type %v in event state schema - must be string, number, bool, list or map",
+				schema[field])
+		}
+
+		if !ok {
+			return fmt.Errorf("Event state field %v should be of type %v", field, schema[field])
+		}
+	}
+
+	return nil
+}
+
 /*
 DocString returns a descriptive string.
 */
@@ -884,18 +2901,21 @@ func (rf *addeventandwait) Run(instanceID string, vs parser.Scope, is map[string
 				errors := map[interface{}]interface{}{}
 				for k, v := range e.ErrorMap {
 
-					// Note: The variable scope of the sink (se.environment)
-					// was also captured - for now it is not exposed to the
-					// language environment
-
 					errorItem := map[interface{}]interface{}{
 						"error": v.Error(),
 					}
 
 					if se, ok := v.(*util.RuntimeErrorWithDetail); ok {
-						errorItem["type"] = se.Type.Error()
-						errorItem["detail"] = se.Detail
-						errorItem["data"] = se.Data
+
+						// Note: The JSON representation also contains the
+						// trace and the variable scope of the sink - for now
+						// these are not exposed to the language environment
+
+						jo := se.ToJSONObject()
+
+						for _, key := range []string{"type", "detail", "data"} {
+							errorItem[key] = jo[key]
+						}
 					}
 
 					errors[k] = errorItem
@@ -926,6 +2946,95 @@ func (rf *addeventandwait) DocString() (string, error) {
 		"return once the event cascade has finished.", nil
 }
 
+// eventQueueDepth
+// ===============
+
+/*
+eventQueueDepth returns the number of events which are waiting to be processed
+or are currently being processed.
+*/
+type eventQueueDepth struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *eventQueueDepth) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	erp := is["erp"].(*ECALRuntimeProvider)
+	return float64(erp.Processor.EventCount()), nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *eventQueueDepth) DocString() (string, error) {
+	return "Returns the number of events which are waiting to be processed or are currently being processed.", nil
+}
+
+// eventWorkerCount
+// ================
+
+/*
+eventWorkerCount returns the number of worker threads which are currently processing events.
+*/
+type eventWorkerCount struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *eventWorkerCount) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	erp := is["erp"].(*ECALRuntimeProvider)
+	return float64(erp.Processor.ActiveWorkers()), nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *eventWorkerCount) DocString() (string, error) {
+	return "Returns the number of worker threads which are currently processing events.", nil
+}
+
+// eventHistory
+// ============
+
+/*
+eventHistory returns the chain of events which triggered the current sink.
+*/
+type eventHistory struct {
+	*inbuildBaseFunc
+}
+
+/*
+Run executes this function.
+*/
+func (rf *eventHistory) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	res := make([]interface{}, 0)
+
+	if m, ok := is["monitor"]; ok {
+		for _, e := range m.(engine.Monitor).EventHistory() {
+			if e != nil {
+				res = append(res, map[interface{}]interface{}{
+					"name":  e.Name(),
+					"kind":  strings.Join(e.Kind(), engine.RuleKindSeparator),
+					"state": e.State(),
+				})
+			}
+		}
+	}
+
+	return res, nil
+}
+
+/*
+DocString returns a descriptive string.
+*/
+func (rf *eventHistory) DocString() (string, error) {
+	return "Returns the chain of events which triggered the current sink - starting with the first event.", nil
+}
+
 // setCronTrigger
 // ==============
 
@@ -11,15 +11,21 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_common/timeutil"
+	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/stdlib"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 func TestStdlib(t *testing.T) {
@@ -268,6 +274,196 @@ identifier: concat
 		return
 	}
 
+	res, err = UnitTestEval(
+		`a := {"x" : 1, "y" : {"z" : 2}}; b := clone(a); b.x := 10; b.y.z := 20; [a, b]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[map[x:1 y:map[z:20]] map[x:10 y:map[z:20]]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := [1, [2]]; b := clone(a); b[0] := 10; b[1][0] := 20; [a, b]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[[1 [20]] [10 [20]]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`clone("foo")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a list or a map as parameter) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[filter([1, 2, 3, 4, 5, 6], func(x) { return x % 2 == 0 }), filter([], func(x) { return true })]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[[2 4 6] []]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+isAdmin := func(u) {
+  return u.role == "admin"
+}
+filter([{"name" : "a", "role" : "admin"}, {"name" : "b", "role" : "user"}, {"name" : "c", "role" : "admin"}], isAdmin)
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[map[name:a role:admin] map[name:c role:admin]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`filter([1, 2], 1)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 2 should be a function) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`filter([1, 2], func(x) { raise("foo") })`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:1 Pos:26)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+l := func(x) {
+  c := 0
+  for ch in range(x) {
+    c := c + 1
+  }
+  return c
+}
+a := [1, 2, 3]
+[mapFunc(a, func(x) { return x * 2 }), mapFunc(["a", "bb", ""], l), mapFunc([], l), a]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[[2 4 6] [1 2 0] [] [1 2 3]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`mapFunc([1, 2], func(x) { raise("foo", "bar") })`, nil)
+
+	if _, ok := err.(*util.RuntimeErrorWithDetail); !ok || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo (bar) (Line:1 Pos:27)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`mapFunc([1, 2], func(x) { return x.y })`, nil)
+
+	if _, ok := err.(*util.RuntimeError); !ok || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (Variable x is not a container) (Line:1 Pos:34)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+toMap := func(acc, x) {
+  acc[x] := true
+  return acc
+}
+[
+  reduce([1, 2, 3, 4], func(acc, x) { return acc + x }, 0),
+  reduce(["a", "b", "c"], func(acc, x) { return "{{acc}}{{x}}" }, ""),
+  reduce(["a", "b"], toMap, {}),
+  reduce([], func(acc, x) { return acc + x }, 5)
+]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[10 abc map[a:true b:true] 5]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`reduce([1, 2], func(acc, x) { raise("foo", "bar") }, 0)`, nil)
+
+	if _, ok := err.(*util.RuntimeErrorWithDetail); !ok || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo (bar) (Line:1 Pos:31)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`reduce([1, 2], "foo", 0)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 2 should be a function) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+items := [
+  {"name" : "a", "category" : "x"},
+  {"name" : "b", "category" : "y"},
+  {"name" : "c", "category" : "x"}
+]
+category := func(i) {
+  return i.category
+}
+[
+  groupBy(items, category),
+  groupBy([1, 2, 3], func(i) { return i }),
+  groupBy([], category),
+  groupBy([[1], [1], [2]], func(i) { return i })
+]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[map[x:[map[category:x name:a] map[category:x name:c]] "+
+		"y:[map[category:y name:b]]] map[1:[1] 2:[2] 3:[3]] map[] map[[1]:[[1] [1]] [2]:[[2]]]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`groupBy([1, 2], func(i) { raise("foo") })`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:1 Pos:27)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[hasPrefix("Hans", "Ha"), hasSuffix("Hans", "ns"), hasPrefix("Hans", ""), hasSuffix("Hans", ""), hasPrefix("Hans", "ns"), hasSuffix(123, 3)]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[true true true true false true]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`hasPrefix("Hans")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a string and a prefix as parameters) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`hasSuffix("Hans", "s", "x")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a string and a suffix as parameters) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
 	res, err = UnitTestEvalAndAST(
 		`dumpenv()`, nil,
 		`
@@ -276,115 +472,979 @@ identifier: dumpenv
 `[1:])
 	errorutil.AssertOk(err)
 
-	if fmt.Sprint(res) != `GlobalScope {
-}` {
+	if fmt.Sprint(res) != `GlobalScope {
+}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`timestamp(now(), "GMT")`, nil,
+		`
+identifier: timestamp
+  funccall
+    identifier: now
+      funccall
+    string: 'GMT'
+`[1:])
+	errorutil.AssertOk(err)
+
+	if !strings.HasSuffix(fmt.Sprint(res), "GMT") {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`rand()`, nil,
+		`
+identifier: rand
+  funccall
+`[1:])
+	errorutil.AssertOk(err)
+
+	if !strings.Contains(fmt.Sprint(res), ".") {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`
+func foo() {
+	log("hello")
+}
+doc(foo)`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != `Declared function: foo (Line 2, Pos 1)` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`doc(len)`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != `Returns the size of a list or map.` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	stdlib.AddStdlibPkg("fmt", "fmt package")
+	stdlib.AddStdlibFunc("fmt", "Println",
+		stdlib.NewECALFunctionAdapter(reflect.ValueOf(fmt.Sprint), "foo"))
+
+	res, err = UnitTestEval(
+		`doc(fmt.Println)`, nil)
+	errorutil.AssertOk(err)
+
+	if res != "foo" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`
+/*
+Foo is my custom function.
+*/
+func foo() {
+	log("hello")
+}
+doc(foo)`, nil)
+
+	if err != nil || fmt.Sprint(res) != `Foo is my custom function.` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Negative case
+
+	res, err = UnitTestEvalAndAST(
+		`a.len([1,2,3])`, nil,
+		`
+identifier: a
+  identifier: len
+    funccall
+      list
+        number: 1
+        number: 2
+        number: 3
+`[1:])
+
+	if err == nil ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Unknown construct (Unknown function: len) (Line:1 Pos:3)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`sleep(10)`, nil)
+
+	if err != nil {
+		t.Error("Unexpected result: ", err)
+		return
+	}
+}
+
+func TestCompileAndCall(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`c := compile("$1 + $2 * 2"); [call(c, 1, 2), call(c, 3, 4), c(5, 6)]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[5 11 17]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := 1; c := compile("a := $1; return a * 2"); [call(c, 5), a]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[10 1]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`c := compile("$1 + 1"); [c, doc(c)]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[ecal.compiled: $1 + 1 Compiled code: $1 + 1]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`compile("$1 +")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parse error in Compiled code: $1 +: Unexpected end) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`call(compile("raise('foo')"))`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (Compiled code: raise('foo')): foo () (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`call("1 + 1")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need compiled code as first parameter) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`compile()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a code string as parameter) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+/*
+countingTestFunc is a test function which counts how often it was called.
+*/
+type countingTestFunc struct {
+	count int32
+}
+
+func (f *countingTestFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	time.Sleep(time.Millisecond)
+	return float64(atomic.AddInt32(&f.count, 1)), nil
+}
+
+func (f *countingTestFunc) DocString() (string, error) {
+	return "Counting test function", nil
+}
+
+func TestOnce(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`c := 0; f := once(func(x) { c := c + x; return c }); [f(1), f(2), f(3), c, f]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[1 1 1 1 ecal.once: ecal.function:  (Line 1, Pos 19)]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`f := once(func() { raise("foo") }); try { f() } except e { }; f()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:1 Pos:20)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`once(1)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a function as parameter) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	ctf := &countingTestFunc{}
+
+	res, err = (&onceFunc{&inbuildBaseFunc{}}).Run("", nil, nil, 0, []interface{}{ctf})
+	errorutil.AssertOk(err)
+
+	of := res.(util.ECALFunction)
+
+	if doc, err := of.DocString(); doc != "Counting test function" || err != nil {
+		t.Error("Unexpected result: ", doc, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, err := of.Run("", nil, nil, 0, nil); res != 1. || err != nil {
+				t.Error("Unexpected result: ", res, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if ctf.count != 1 {
+		t.Error("Unexpected number of calls:", ctf.count)
+		return
+	}
+}
+
+func TestMemoize(t *testing.T) {
+
+	res, err := UnitTestEval(`
+c := 0
+f := memoize(func(x) {
+  c := c + 1
+  return type(x)
+})
+[f(1), f(1), f(2), f("1"), f(2), c]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != `[1 1 2 "1" 2 3]` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+c := 0
+f := memoize(func(x) {
+  c := c + 1
+  return x
+}, 2)
+r := [f(1), f(2), c]
+r := add(r, f(1))  # 1 is now the most recently used entry
+r := add(r, c)
+r := add(r, f(3))  # Evicts 2
+r := add(r, c)
+r := add(r, f(1))
+r := add(r, c)
+r := add(r, f(2))
+r := add(r, c)
+r
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[1 2 2 1 2 3 3 1 3 2 4]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`memoize(func(x) { return x }, 0)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Maximum cache size must be at least 1) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`memoize(1)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a function and optionally a maximum cache size as parameters) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	ctf := &countingTestFunc{}
+
+	res, err = (&memoizeFunc{&inbuildBaseFunc{}}).Run("", nil, nil, 0, []interface{}{ctf})
+	errorutil.AssertOk(err)
+
+	mf := res.(util.ECALFunction)
+
+	if doc, err := mf.DocString(); doc != "Counting test function" || err != nil || fmt.Sprint(mf) != "ecal.memoize: &{0}" {
+		t.Error("Unexpected result: ", doc, err, mf)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if res, err := mf.Run("", nil, nil, 0, []interface{}{"foo"}); res != 1. || err != nil {
+			t.Error("Unexpected result: ", res, err)
+			return
+		}
+	}
+
+	// Test concurrent access with evictions
+
+	res, err = (&memoizeFunc{&inbuildBaseFunc{}}).Run("", nil, nil, 0, []interface{}{ctf, 2.})
+	errorutil.AssertOk(err)
+
+	mfn := res.(*memoizeFunction)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				mfn.Run("", nil, nil, 0, []interface{}{(i + j) % 4})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(mfn.cache) > 2 || len(mfn.order) != len(mfn.cache) {
+		t.Error("Unexpected cache state: ", mfn.cache, mfn.order)
+		return
+	}
+
+	for _, k := range mfn.order {
+		if _, ok := mfn.cache[k]; !ok {
+			t.Error("Unexpected cache state: ", mfn.cache, mfn.order)
+			return
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+
+	res, err := UnitTestEval(`
+c := 0
+f := func() {
+  c := c + 1
+  if c < 3 {
+    raise("fail", c)
+  }
+  return c
+}
+r := [retry(f, 3, 0)]
+c := 0
+r := add(r, retryWithBackoff(f, 5, 1, 2))
+r
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[3 3]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+c := 0
+f := func() {
+  c := c + 1
+  raise("fail", c)
+}
+r := []
+try {
+  retry(f, 2, 1)
+} except e {
+  r := add(r, e.detail)
+  r := add(r, c)
+}
+c := 0
+try {
+  retryWithBackoff(f, 3, 1, 1.5)
+} except e {
+  r := add(r, e.detail)
+}
+r
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[2 2 3]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`retry(func() { return 1 }, 0, 0)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Maximum attempts must be at least 1) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`retry(1, 1, 0)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a function) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`retryWithBackoff(func() { return 1 }, 1, 0)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need a function, maximum attempts, "+
+		"an initial delay in milliseconds and a backoff factor as parameters) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestParallel(t *testing.T) {
+
+	start := time.Now()
+
+	res, err := UnitTestEval(`
+parallel([
+  func() { sleep(100000); return "a" },
+  func() { sleep(50000); return "b" },
+  func() { sleep(100000); return "c" },
+  func() { return "d" }
+])
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[a b c d]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Error("Functions were not executed concurrently:", d)
+		return
+	}
+
+	res, err = UnitTestEval(`
+r := []
+try {
+  parallel([
+    func() { sleep(10000); return 1 },
+    func() { raise("foo") },
+    func() { raise("bar") },
+  ])
+} except e {
+  r := [e.detail, e.data]
+}
+r
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[2 of 3 functions failed: ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:6 Pos:14); "+
+		"ECAL error in ECALTestRuntime (ECALEvalTest): bar () (Line:7 Pos:14) "+
+		"map[1:ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:6 Pos:14) "+
+		"2:ECAL error in ECALTestRuntime (ECALEvalTest): bar () (Line:7 Pos:14)]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`parallel([func() { return 1 }, 2])`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Item 2 of parameter 1 should be a function) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`parallel([])`, nil)
+
+	if fmt.Sprint(res) != "[]" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestLocks(t *testing.T) {
+
+	res, err := UnitTestEval(`
+c := 0
+l := newLock()
+inc := func() {
+  for i in range(1, 20) {
+    l.lock()
+    v := c
+    sleep(100)
+    c := v + 1
+    l.unlock()
+  }
+}
+parallel([inc, inc, inc])
+c
+`, nil)
+	errorutil.AssertOk(err)
+
+	if res != 60. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+c := 0
+l := newRWLock()
+inc := func() {
+  for i in range(1, 20) {
+    l.lock()
+    v := c
+    sleep(100)
+    c := v + 1
+    l.unlock()
+  }
+}
+read := func() {
+  l.rlock()
+  sleep(100000)
+  v := c
+  l.runlock()
+  return v
+}
+parallel([inc, inc])
+[c, parallel([read, read])]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[40 [40 40]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	start := time.Now()
+
+	_, err = UnitTestEval(`
+l := newRWLock()
+read := func() {
+  l.rlock()
+  sleep(100000)
+  l.runlock()
+}
+parallel([read, read, read])
+`, nil)
+	errorutil.AssertOk(err)
+
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Error("Read locks were not held concurrently:", d)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newRWLock(); [newLock(), doc(l.runlock)]`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[map[lock:ecal.method unlock:ecal.method] Releases a read lock.]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newLock(); l.unlock()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Lock is not held) (Line:1 Pos:19)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newLock(); l.lock(); l.unlock(); l.unlock()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Lock is not held) (Line:1 Pos:41)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newRWLock(); l.runlock()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Read lock is not held) (Line:1 Pos:21)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newRWLock(); l.lock(); l.runlock()`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Read lock is not held) (Line:1 Pos:31)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`l := newRWLock(); l.rlock(); l.rlock(); l.runlock(); l.runlock(); l.lock(); l.unlock()`, nil)
+	errorutil.AssertOk(err)
+}
+
+func TestChannels(t *testing.T) {
+
+	res, err := UnitTestEval(`
+c := newChannel(1)
+producer := func() {
+  for i in range(1, 5) {
+    c.send(i)
+  }
+  c.close()
+}
+consumer := func() {
+  r := []
+  for true {
+    try {
+      r := add(r, c.recv())
+    } except e {
+      break
+    }
+  }
+  return r
+}
+parallel([producer, consumer])
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[<nil> [1 2 3 4 5]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+c := newChannel(2)
+r := [c.tryRecv(), c.len()]
+c.send("a")
+c.send("b")
+r := add(r, c.len())
+r := add(r, c.tryRecv())
+c.close()
+r := add(r, c.recv())
+try {
+  c.recv()
+} except e {
+  r := add(r, e.type)
+}
+try {
+  c.send(1)
+} except e {
+  r := add(r, e.detail)
+}
+try {
+  c.close()
+} except e {
+  r := add(r, e.detail)
+}
+r
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[<nil> 0 2 a b End of iteration was reached Channel is closed Channel is already closed]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`newChannel(-1)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Channel capacity must not be negative) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Test blocking behaviour
+
+	res, err = (&newChannelFunc{&inbuildBaseFunc{}}).Run("", nil, nil, 0, []interface{}{1})
+	errorutil.AssertOk(err)
+
+	c := res.(map[interface{}]interface{})
+
+	runMethod := func(name string, args ...interface{}) (interface{}, error) {
+		return c[name].(util.ECALFunction).Run("", nil, nil, 0, args)
+	}
+
+	received := make(chan interface{})
+
+	go func() {
+		res, _ := runMethod("recv")
+		received <- res
+	}()
+
+	select {
+	case res := <-received:
+		t.Error("Receive should block on an empty channel:", res)
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	runMethod("send", "foo")
+
+	if res := <-received; res != "foo" {
+		t.Error("Unexpected result: ", res)
+		return
+	}
+
+	runMethod("send", "bar")
+
+	sent := make(chan bool)
+
+	go func() {
+		runMethod("send", "baz")
+		sent <- true
+	}()
+
+	select {
+	case <-sent:
+		t.Error("Send should block on a full channel")
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if res, err := runMethod("recv"); res != "bar" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	<-sent
+
+	if res, err := runMethod("tryRecv"); res != "baz" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if _, err := runMethod("send"); err == nil || err.Error() != "Need a value as parameter" {
+		t.Error("Unexpected result: ", err)
+		return
+	}
+}
+
+func TestCounter(t *testing.T) {
+
+	res, err := UnitTestEval(`
+c := newCounter(5)
+r := [c.increment(), c.decrement(), c.decrement(), c.add(10), c.add(-2), c.get()]
+c.reset()
+r := add(r, c.get())
+d := newCounter()
+add(r, d.get())
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != "[6 5 4 14 12 12 5 0]" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
-	res, err = UnitTestEvalAndAST(
-		`timestamp(now(), "GMT")`, nil,
-		`
-identifier: timestamp
-  funccall
-    identifier: now
-      funccall
-    string: 'GMT'
-`[1:])
+	res, err = UnitTestEval(`c := newCounter(); c.add("a")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a number) (Line:1 Pos:22)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+c := newCounter()
+inc := func() {
+  for i in range(1, 100) {
+    c.increment()
+  }
+}
+f := []
+for i in range(1, 100) {
+  f := add(f, inc)
+}
+parallel(f)
+c.get()
+`, nil)
 	errorutil.AssertOk(err)
 
-	if !strings.HasSuffix(fmt.Sprint(res), "GMT") {
+	if res != 10000. {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
-	res, err = UnitTestEvalAndAST(
-		`rand()`, nil,
-		`
-identifier: rand
-  funccall
-`[1:])
+	res, err = (&newCounterFunc{&inbuildBaseFunc{}}).Run("", nil, nil, 0, nil)
 	errorutil.AssertOk(err)
 
-	if !strings.Contains(fmt.Sprint(res), ".") {
+	c := res.(map[interface{}]interface{})
+	inc := c["increment"].(util.ECALFunction)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				inc.Run("", nil, nil, 0, nil)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if res, err := c["get"].(util.ECALFunction).Run("", nil, nil, 0, nil); res != 10000. || err != nil {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
+}
 
-	res, err = UnitTestEval(
-		`
-func foo() {
-	log("hello")
+/*
+sleepTestFunc is a test function which sleeps until it is cancelled.
+*/
+type sleepTestFunc struct {
+	cancelled chan bool
 }
-doc(foo)`, nil)
+
+func (f *sleepTestFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	select {
+	case <-time.After(time.Second):
+		f.cancelled <- false
+	case <-is["context"].(context.Context).Done():
+		f.cancelled <- true
+	}
+	return nil, nil
+}
+
+func (f *sleepTestFunc) DocString() (string, error) {
+	return "Sleep test function", nil
+}
+
+func TestTimeout(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`timeout(func() { sleep(1000); return "foo" }, 1000)`, nil)
 	errorutil.AssertOk(err)
 
-	if fmt.Sprint(res) != `Declared function: foo (Line 2, Pos 1)` {
+	if res != "foo" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
 	res, err = UnitTestEval(
-		`doc(len)`, nil)
-	errorutil.AssertOk(err)
+		`timeout(func() { sleep(200000); return "foo" }, 10)`, nil)
 
-	if fmt.Sprint(res) != `Returns the size of a list or map.` {
+	if rerr, ok := err.(*util.RuntimeError); !ok || rerr.Type != util.ErrTimeout ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Timeout (Function did not finish within 10 ms) (Line:1 Pos:1)" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
-	stdlib.AddStdlibPkg("fmt", "fmt package")
-	stdlib.AddStdlibFunc("fmt", "Println",
-		stdlib.NewECALFunctionAdapter(reflect.ValueOf(fmt.Sprint), "foo"))
+	res, err = UnitTestEval(
+		`timeout(func() { raise("foo") }, 1000)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): foo () (Line:1 Pos:18)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
 
 	res, err = UnitTestEval(
-		`doc(fmt.Println)`, nil)
-	errorutil.AssertOk(err)
+		`timeout(1, 1000)`, nil)
 
-	if res != "foo" {
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a function) (Line:1 Pos:1)" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
+	vs := scope.NewGlobalScope()
+	stf := &sleepTestFunc{make(chan bool, 1)}
+	vs.SetValue("stub", stf)
+
 	res, err = UnitTestEval(
-		`
-/*
-Foo is my custom function.
-*/
-func foo() {
-	log("hello")
+		`timeout(stub, 10)`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Timeout (Function did not finish within 10 ms) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if !<-stf.cancelled {
+		t.Error("Context was not cancelled")
+		return
+	}
+
+	// Test that a cancelled ECAL function stops
+
+	res, err = UnitTestEval(`
+c := 0
+inc := func() {
+  c := c + 1
 }
-doc(foo)`, nil)
+try {
+  timeout(func() {
+    for true {
+      inc()
+      sleep(1000)
+    }
+  }, 10)
+} except e {
+}
+sleep(20000)
+c1 := c
+sleep(50000)
+[c1 > 0, c1 == c]
+`, nil)
+	errorutil.AssertOk(err)
 
-	if err != nil || fmt.Sprint(res) != `Foo is my custom function.` {
+	if fmt.Sprint(res) != "[true true]" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
+}
 
-	// Negative case
+func TestEventEmitter(t *testing.T) {
 
-	res, err = UnitTestEvalAndAST(
-		`a.len([1,2,3])`, nil,
-		`
-identifier: a
-  identifier: len
-    funccall
-      list
-        number: 1
-        number: 2
-        number: 3
-`[1:])
+	res, err := UnitTestEval(`
+r := []
+e := newEventEmitter()
+h1 := func(data) {
+  r := add(r, "h1:{{data}}")
+}
+h2 := func(data) {
+  r := add(r, "h2:{{data}}")
+}
+h3 := func(data) {
+  r := add(r, "h3:{{data}}")
+}
+e.on("foo", h1)
+e.on("foo", h2)
+e.once("foo", h3)
+e.on("bar", h1)
+e.emit("foo", 1)
+e.emit("foo", 2)
+e.off("foo", h1)
+e.emit("foo", 3)
+e.emit("bar", 4)
+e.emit("baz", 5)
+r
+`, nil)
+	errorutil.AssertOk(err)
 
-	if err == nil ||
-		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Unknown construct (Unknown function: len) (Line:1 Pos:3)" {
+	if fmt.Sprint(res) != "[h1:1 h2:1 h3:1 h1:2 h2:2 h2:3 h1:4]" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 
-	_, err = UnitTestEval(`sleep(10)`, nil)
+	res, err = UnitTestEval(`
+e := newEventEmitter()
+e.on("foo", func(data) { raise("fail", data) })
+e.emit("foo", 1)
+`, nil)
 
-	if err != nil {
-		t.Error("Unexpected result: ", err)
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): fail (1) (Line:3 Pos:26)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+e := newEventEmitter()
+e.on("foo", 1)
+`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 2 should be a function) (Line:3 Pos:3)" {
+		t.Error("Unexpected result: ", res, err)
 		return
 	}
 }
@@ -490,6 +1550,195 @@ setPulseTrigger(100, "pulseevent", "foo.bar")
 	}
 }
 
+func TestEventQueueDepth(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`
+sink test
+  kindmatch [ "foo.*" ],
+{
+	log("depth: ", eventQueueDepth() > 0, " workers: ", eventWorkerCount() > 0)
+}
+
+addEventAndWait("myevent", "foo.bar", {})
+[eventQueueDepth() >= 0, eventWorkerCount() >= 0]
+`, nil)
+
+	if err != nil || fmt.Sprint(res) != "[true true]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if testlogger.String() != "depth: true workers: true" {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+}
+
+func TestEventHistory(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`
+sink outer
+  kindmatch [ "outer.event" ],
+{
+	addEvent("innerevent", "inner.event", {"b" : 2})
+}
+
+sink inner
+  kindmatch [ "inner.event" ],
+{
+	for e in eventHistory() {
+		log(e.name, " ", e.kind, " ", e.state)
+	}
+}
+
+addEventAndWait("outerevent", "outer.event", {"a" : 1})
+eventHistory()
+`, nil)
+
+	if err != nil || fmt.Sprint(res) != "[]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if testlogger.String() != `
+outerevent outer.event {
+  "a": 1
+}
+innerevent inner.event {
+  "b": 2
+}`[1:] {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+
+	// The event cascade is kept inside functions which run with a timeout
+
+	res, err = UnitTestEval(
+		`
+sink outer
+  kindmatch [ "outer.event" ],
+{
+	timeout(func() {
+		addEvent("innerevent", "inner.event", {"b" : 2})
+	}, 10000)
+}
+
+sink inner
+  kindmatch [ "inner.event" ],
+{
+	timeout(func() {
+		for e in eventHistory() {
+			log(e.name)
+		}
+	}, 10000)
+}
+
+addEventAndWait("outerevent", "outer.event", {"a" : 1})
+`, nil)
+
+	if err != nil || testlogger.String() != "outerevent\ninnerevent" {
+		t.Error("Unexpected result:", res, err, testlogger.String())
+		return
+	}
+}
+
+func TestLogFunctions(t *testing.T) {
+
+	_, err := UnitTestEval(
+		`
+log("info ", 1)
+warn("disk ", {"a": 1})
+error("failed")
+debug("details")
+`, nil)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testlogger.String() != `
+info 1
+warning: disk {
+  "a": 1
+}
+error: failed
+debug: details`[1:] {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+
+	res, err := UnitTestEval(
+		`
+warn := func(msg) {
+  return "custom " + msg
+}
+log := func(msg) {
+  return "custom " + msg
+}
+[warn("foo"), log("bar")]
+`, nil)
+	errorutil.AssertOk(err)
+
+	if fmt.Sprint(res) != `[custom foo <nil>]` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+type testGreetFunc struct {
+	*inbuildBaseFunc
+}
+
+func (f *testGreetFunc) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("Need a name")
+	}
+	return fmt.Sprintf("Hello %v", args[0]), nil
+}
+
+func (f *testGreetFunc) DocString() (string, error) {
+	return "Greets someone.", nil
+}
+
+func TestRegisterInbuildFunc(t *testing.T) {
+	RegisterInbuildFunc("greet", &testGreetFunc{&inbuildBaseFunc{}})
+
+	erp := NewECALRuntimeProvider("ECALTestRuntime", WithLogger(util.NewMemoryLogger(10)))
+
+	ast, err := parser.ParseWithRuntime("ECALEvalTest", `greet("ECAL")`, erp)
+	if err == nil {
+		err = ast.Runtime.Validate()
+	}
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := ast.Runtime.Eval(scope.NewGlobalScope(), make(map[string]interface{}), erp.NewThreadID())
+
+	if err != nil || res != "Hello ECAL" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, ok := InbuildFuncs()["greet"]; !ok {
+		t.Error("Registered function should be listed")
+		return
+	}
+
+	UnregisterInbuildFunc("greet")
+
+	if _, err = UnitTestEval(`greet("ECAL")`, nil); err == nil ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Unknown construct (Unknown function: greet) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestDocstrings(t *testing.T) {
 	for k, v := range InbuildFuncMap {
 		if res, _ := v.DocString(); res == "" {
@@ -518,6 +1767,23 @@ func TestErrorConditions(t *testing.T) {
 		return
 	}
 
+	ef := &enumerateFunc{&inbuildBaseFunc{}}
+	eis := make(map[string]interface{})
+
+	for i, expected := range []string{"<nil> Function is an iterator", "[0 a] Function is an iterator",
+		"[1 b] Function is an iterator", "<nil> End of iteration was reached"} {
+
+		if res, err := ef.Run("", nil, eis, 0, []interface{}{[]interface{}{"a", "b"}}); fmt.Sprint(res, " ", err) != expected {
+			t.Error("Unexpected result:", i, res, err)
+			return
+		}
+	}
+
+	if _, err := ef.Run("", nil, nil, 0, nil); err == nil || err.Error() != "Need a list as parameter" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
 	rf := &rangeFunc{&inbuildBaseFunc{}}
 
 	if _, err := rf.Run("", nil, nil, 0, nil); err == nil || err.Error() != "Need at least an end range as first parameter" {
@@ -525,7 +1791,7 @@ func TestErrorConditions(t *testing.T) {
 		return
 	}
 
-	if _, err := rf.Run("", nil, nil, 0, []interface{}{"bob"}); err == nil || err.Error() != "Parameter 1 should be a number" {
+	if _, err := rf.Run("", nil, nil, 0, []interface{}{"bob", 1}); err == nil || err.Error() != "Parameter 1 should be a number" {
 		t.Error("Unexpected result:", err)
 		return
 	}
@@ -14,14 +14,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"sync"
 	"testing"
 
 	"github.com/rhedin/Abe_common/datautil"
 	"github.com/rhedin/Abe_common/timeutil"
+	"github.com/rhedin/Abe_ecal/ecaltest"
 	"github.com/rhedin/Abe_ecal/engine"
 	"github.com/rhedin/Abe_ecal/parser"
-	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/util"
 )
 
@@ -36,21 +35,15 @@ func TestMain(m *testing.M) {
 
 	// Check if all nodes have been tested
 
-	for n := range providerMap {
-		if _, ok := usedNodes[n]; !ok {
-			fmt.Println("Not tested node: ", n)
-		}
+	for _, n := range testNodeCoverage.Uncovered() {
+		fmt.Println("Not tested node: ", n)
 	}
 
 	os.Exit(res)
 }
 
-// Used nodes map which is filled during unit testing. Prefilled only with nodes
-// which should not be encountered in ASTs.
-var usedNodes = map[string]bool{
-	parser.NodeEOF: true,
-}
-var usedNodesLock = &sync.Mutex{}
+// Node coverage which is filled during unit testing
+var testNodeCoverage = NewNodeCoverageTracker()
 
 // Debuggger to be used
 var testDebugger util.ECALDebugger
@@ -68,7 +61,7 @@ func UnitTestEval(input string, vs parser.Scope) (interface{}, error) {
 	return UnitTestEvalAndAST(input, vs, "")
 }
 func UnitTestEvalAndAST(input string, vs parser.Scope, expectedAST string) (interface{}, error) {
-	return UnitTestEvalAndASTAndImport(input, vs, expectedAST, nil)
+	return UnitTestEvalAndASTAndImportAndRuntimeProvider(input, vs, expectedAST, nil, nil)
 }
 
 func UnitTestEvalWithRuntimeProvider(input string, vs parser.Scope,
@@ -76,33 +69,27 @@ func UnitTestEvalWithRuntimeProvider(input string, vs parser.Scope,
 	return UnitTestEvalAndASTAndImportAndRuntimeProvider(input, vs, "", nil, erp)
 }
 
-func UnitTestEvalAndASTAndImport(input string, vs parser.Scope, expectedAST string,
-	importLocator util.ECALImportLocator) (interface{}, error) {
-	return UnitTestEvalAndASTAndImportAndRuntimeProvider(input, vs, expectedAST, importLocator, nil)
-}
-
 func UnitTestEvalAndASTAndImportAndRuntimeProvider(input string, vs parser.Scope, expectedAST string,
 	importLocator util.ECALImportLocator, erp *ECALRuntimeProvider) (interface{}, error) {
 
-	var traverseAST func(n *parser.ASTNode)
+	var checkNodeNames func(n *parser.ASTNode)
 
-	traverseAST = func(n *parser.ASTNode) {
+	checkNodeNames = func(n *parser.ASTNode) {
 		if n.Name == "" {
 			panic(fmt.Sprintf("Node found with empty string name: %s", n))
 		}
-
-		usedNodesLock.Lock()
-		usedNodes[n.Name] = true
-		usedNodesLock.Unlock()
 		for _, cn := range n.Children {
-			traverseAST(cn)
+			checkNodeNames(cn)
 		}
 	}
 
-	// Parse the input
+	trackNodes := func(ast *parser.ASTNode) {
+		checkNodeNames(ast)
+		testNodeCoverage.Track(ast)
+	}
 
 	if erp == nil {
-		erp = NewECALRuntimeProvider("ECALTestRuntime", importLocator, nil)
+		erp = NewECALRuntimeProvider("ECALTestRuntime", WithImportLocator(importLocator))
 	}
 
 	// Set debugger
@@ -124,59 +111,14 @@ func UnitTestEvalAndASTAndImportAndRuntimeProvider(input string, vs parser.Scope
 
 	testprocessor = erp.Processor
 
-	ast, err := parser.ParseWithRuntime("ECALEvalTest", input, erp)
-	if err != nil {
-		return nil, err
-	}
-
-	traverseAST(ast)
-
-	if expectedAST != "" && ast.String() != expectedAST {
-		return nil, fmt.Errorf("Unexpected AST result:\n%v", ast.String())
-	}
-
-	// Validate input
-
-	if err := ast.Runtime.Validate(); err != nil {
-		return nil, err
-	}
-
-	if vs == nil {
-		vs = scope.NewScope(scope.GlobalScope)
-	}
-
-	return ast.Runtime.Eval(vs, make(map[string]interface{}), erp.NewThreadID())
+	return evalTestInput(erp, input, vs, expectedAST, trackNodes)
 }
 
 /*
 addLogFunction adds a simple log function to a given Scope.
 */
 func addLogFunction(vs parser.Scope) *datautil.RingBuffer {
-	buf := datautil.NewRingBuffer(20)
-	vs.SetValue("testlog", &TestLogger{buf})
-	return buf
-}
-
-/*
-TestLogger is a simple logger function which can be added to tess.
-*/
-type TestLogger struct {
-	buf *datautil.RingBuffer
-}
-
-func (tl *TestLogger) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
-	tl.buf.Add(fmt.Sprint(args...))
-	return nil, nil
-}
-
-func (tl *TestLogger) DocString() (string, error) {
-	return "testlogger docstring", nil
-}
-
-func (tl *TestLogger) String() string {
-	return "TestLogger"
-}
-
-func (tl *TestLogger) MarshalJSON() ([]byte, error) {
-	return []byte(tl.String()), nil
+	tl := ecaltest.NewTestLogger()
+	vs.SetValue(ecaltest.TestLoggerFunctionName, tl)
+	return tl.Buffer()
 }
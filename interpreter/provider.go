@@ -14,7 +14,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rhedin/Abe_common/datautil"
 	"github.com/rhedin/Abe_common/timeutil"
@@ -74,10 +76,20 @@ var providerMap = map[string]ecalRuntimeNew{
 	parser.NodeMODINT: modintOpRuntimeInst,
 	parser.NodeDIVINT: divintOpRuntimeInst,
 
+	// Conditional operators
+
+	parser.NodeNULLCOALESCE: nullcoalesceOpRuntimeInst,
+	parser.NodeTERNARY:      ternaryRuntimeInst,
+
+	// Spread operator
+
+	parser.NodeSPREAD: spreadRuntimeInst,
+
 	// Assignment statement
 
-	parser.NodeASSIGN: assignmentRuntimeInst,
-	parser.NodeLET:    letRuntimeInst,
+	parser.NodeASSIGN:             assignmentRuntimeInst,
+	parser.NodeNULLCOALESCEASSIGN: nullcoalesceAssignmentRuntimeInst,
+	parser.NodeLET:                letRuntimeInst,
 
 	// Import statement
 
@@ -154,39 +166,165 @@ type ECALRuntimeProvider struct {
 	MutexesMutex  *sync.Mutex            // Mutex for mutexes map
 	Cron          *timeutil.Cron         // Cron object for scheduled execution
 	Debugger      util.ECALDebugger      // Optional: ECAL Debugger object
+	Budget        int64                  // Optional: Maximum number of evaluation steps (0 for unlimited)
+	Sandbox       []string               // Optional: Allowed stdlib packages and functions (nil for all)
+
+	budgetUsed *int64 // Number of evaluation steps used so far
+}
+
+/*
+Option is an option for the construction of an ECAL runtime provider.
+*/
+type Option func(erp *ECALRuntimeProvider)
+
+/*
+WithImportLocator sets the locator object for imports. By default imports are
+located in the directory of the executable.
+*/
+func WithImportLocator(il util.ECALImportLocator) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.ImportLocator = il
+	}
+}
+
+/*
+WithLogger sets the logger object for log messages. By default log messages
+are kept in a memory logger.
+*/
+func WithLogger(l util.Logger) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.Logger = l
+	}
+}
+
+/*
+WithProcessor sets the processor of the ECA engine. By default a new processor
+is created.
+*/
+func WithProcessor(p engine.Processor) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.Processor = p
+	}
+}
+
+/*
+WithDebugger sets the debugger object.
+*/
+func WithDebugger(d util.ECALDebugger) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.Debugger = d
+	}
+}
+
+/*
+WithBudget sets the maximum number of evaluation steps which may be used by
+all code running on the runtime provider. Each evaluated AST node is one step.
+*/
+func WithBudget(n int64) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.Budget = n
+	}
+}
+
+/*
+WithSandbox restricts the stdlib access of ECAL code to the given whitelist.
+Each entry is either a package name (e.g. math) or a fully qualified function
+or constant name (e.g. fmt.Sprint).
+*/
+func WithSandbox(whitelist []string) Option {
+	return func(erp *ECALRuntimeProvider) {
+		erp.Sandbox = nil
+		if whitelist != nil {
+			erp.Sandbox = append([]string{}, whitelist...)
+		}
+	}
 }
 
 /*
 NewECALRuntimeProvider returns a new instance of a ECAL runtime provider.
 */
-func NewECALRuntimeProvider(name string, importLocator util.ECALImportLocator, logger util.Logger) *ECALRuntimeProvider {
+func NewECALRuntimeProvider(name string, opts ...Option) *ECALRuntimeProvider {
+	var used int64
 
-	if importLocator == nil {
+	erp := &ECALRuntimeProvider{Name: name, Mutexes: make(map[string]*sync.Mutex),
+		MutexLog: datautil.NewRingBuffer(1024), MutexeOwners: make(map[string]uint64),
+		MutexesMutex: &sync.Mutex{}, budgetUsed: &used}
+
+	for _, opt := range opts {
+		opt(erp)
+	}
+
+	if erp.ImportLocator == nil {
 
 		// By default imports are located in the current directory
 
-		importLocator = &util.FileImportLocator{Root: filepath.Dir(os.Args[0])}
+		erp.ImportLocator = &util.FileImportLocator{Root: filepath.Dir(os.Args[0])}
 	}
 
-	if logger == nil {
+	if erp.Logger == nil {
 
 		// By default we just have a memory logger
 
-		logger = util.NewMemoryLogger(100)
+		erp.Logger = util.NewMemoryLogger(100)
 	}
 
-	proc := engine.NewProcessor(config.Int(config.WorkerCount))
+	if erp.Processor == nil {
+		proc := engine.NewProcessor(config.Int(config.WorkerCount))
+
+		// By default ECAL should stop the triggering sequence of sinks after the
+		// first sink that returns a sinkerror.
+
+		proc.SetFailOnFirstErrorInTriggerSequence(true)
+
+		erp.Processor = proc
+	}
+
+	erp.Cron = timeutil.NewCron()
+	erp.Cron.Start()
+
+	return erp
+}
+
+/*
+Clone returns a new runtime provider which has the same configuration as this
+one. The new runtime provider has its own processor, cron object, mutexes and
+evaluation budget. A debugger is not carried over.
+*/
+func (erp *ECALRuntimeProvider) Clone(name string) *ECALRuntimeProvider {
+	return NewECALRuntimeProvider(name, WithImportLocator(erp.ImportLocator),
+		WithLogger(erp.Logger), WithBudget(erp.Budget), WithSandbox(erp.Sandbox))
+}
+
+/*
+useBudget uses one evaluation step of the budget. Returns an error if the
+budget has been exceeded.
+*/
+func (erp *ECALRuntimeProvider) useBudget(node *parser.ASTNode) error {
+	if erp.Budget > 0 && atomic.AddInt64(erp.budgetUsed, 1) > erp.Budget {
+		return erp.NewRuntimeError(util.ErrBudgetExceeded,
+			fmt.Sprintf("Maximum of %v evaluation steps was reached", erp.Budget), node)
+	}
+	return nil
+}
 
-	// By default ECAL should stop the triggering sequence of sinks after the
-	// first sink that returns a sinkerror.
+/*
+sandboxAllows checks if a given stdlib function or constant name may be
+accessed according to the sandbox whitelist.
+*/
+func (erp *ECALRuntimeProvider) sandboxAllows(name string) bool {
+	if erp.Sandbox == nil {
+		return true
+	}
 
-	proc.SetFailOnFirstErrorInTriggerSequence(true)
+	pkg := strings.SplitN(name, ".", 2)[0]
 
-	cron := timeutil.NewCron()
-	cron.Start()
+	for _, w := range erp.Sandbox {
+		if w == name || w == pkg {
+			return true
+		}
+	}
 
-	return &ECALRuntimeProvider{name, importLocator, logger, proc,
-		make(map[string]*sync.Mutex), datautil.NewRingBuffer(1024), make(map[string]uint64), &sync.Mutex{}, cron, nil}
+	return false
 }
 
 /*
@@ -205,11 +343,26 @@ func (erp *ECALRuntimeProvider) Runtime(node *parser.ASTNode) parser.Runtime {
 NewRuntimeError creates a new RuntimeError object.
 */
 func (erp *ECALRuntimeProvider) NewRuntimeError(t error, d string, node *parser.ASTNode) error {
+	return util.NewRuntimeError(erp.errorSource(node), t, d, node)
+}
+
+/*
+NewRuntimeErrorBuilder creates a new builder for a runtime error which
+occurred at a given AST node.
+*/
+func (erp *ECALRuntimeProvider) NewRuntimeErrorBuilder(t error, node *parser.ASTNode) *util.RuntimeErrorBuilder {
+	return util.NewRuntimeErrorBuilder(erp.errorSource(node), t).Node(node)
+}
+
+/*
+errorSource returns the source name for errors which occurred at a given AST node.
+*/
+func (erp *ECALRuntimeProvider) errorSource(node *parser.ASTNode) string {
 	source := erp.Name
 	if node.Token != nil {
 		source = fmt.Sprintf("%v (%v)", source, node.Token.Lsource)
 	}
-	return util.NewRuntimeError(source, t, d, node)
+	return source
 }
 
 /*
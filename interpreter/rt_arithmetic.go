@@ -11,9 +11,11 @@
 package interpreter
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 // Basic Arithmetic Operator Runtimes
@@ -47,16 +49,41 @@ func (rt *plusOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid ui
 			}, vs, is, tid)
 		}
 
-		// Use as operation
+		// Use as operation - if either operand is a string then both
+		// operands are concatenated as strings
 
-		res, err = rt.numOp(func(n1 float64, n2 float64) interface{} {
-			return n1 + n2
-		}, vs, is, tid)
+		var res1, res2 interface{}
+
+		if res1, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+			if res2, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
+				_, isStr1 := res1.(string)
+				_, isStr2 := res2.(string)
+
+				if isStr1 || isStr2 {
+					res = concatString(res1) + concatString(res2)
+				} else {
+					res, err = rt.applyNumOp(func(n1 float64, n2 float64) interface{} {
+						return n1 + n2
+					}, res1, res2)
+				}
+			}
+		}
 	}
 
 	return res, err
 }
 
+/*
+concatString returns the string representation of a value for string
+concatenation.
+*/
+func concatString(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
 type minusOpRuntime struct {
 	*operatorRuntime
 }
@@ -116,15 +143,62 @@ func (rt *timesOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid u
 	_, err := rt.baseRuntime.Eval(vs, is, tid)
 
 	if err == nil {
-
-		res, err = rt.numOp(func(n1 float64, n2 float64) interface{} {
-			return n1 * n2
-		}, vs, is, tid)
+		var res1, res2 interface{}
+
+		if res1, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+			if res2, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
+
+				// If either operand is a list then the list is repeated
+
+				if list, ok := res1.([]interface{}); ok {
+					res, err = rt.repeatList(list, res2, rt.node.Children[1])
+				} else if list, ok := res2.([]interface{}); ok {
+					res, err = rt.repeatList(list, res1, rt.node.Children[0])
+				} else {
+					res, err = rt.applyNumOp(func(n1 float64, n2 float64) interface{} {
+						return n1 * n2
+					}, res1, res2)
+				}
+			}
+		}
 	}
 
 	return res, err
 }
 
+/*
+maxRepeatedListSize is the maximum number of elements of a repeated list.
+*/
+const maxRepeatedListSize = 1 << 24
+
+/*
+repeatList returns a new list which contains the elements of a given list
+n times. The number of repetitions must be a non-negative integer.
+*/
+func (rt *timesOpRuntime) repeatList(list []interface{}, n interface{},
+	nNode *parser.ASTNode) (interface{}, error) {
+
+	nNum, ok := n.(float64)
+
+	if !ok || math.IsInf(nNum, 0) || nNum < 0 || nNum != math.Trunc(nNum) {
+		return nil, rt.erp.NewRuntimeError(util.ErrNotANumber,
+			rt.errorDetailString(nNode.Token, n), nNode)
+	}
+
+	if nNum > maxRepeatedListSize || (len(list) > 0 && nNum > float64(maxRepeatedListSize/len(list))) {
+		return nil, rt.erp.NewRuntimeError(util.ErrRuntimeError,
+			fmt.Sprintf("Repeated list would have more than %v elements", maxRepeatedListSize), nNode)
+	}
+
+	res := make([]interface{}, 0, len(list)*int(nNum))
+
+	for i := 0; i < int(nNum); i++ {
+		res = append(res, list...)
+	}
+
+	return res, nil
+}
+
 type divOpRuntime struct {
 	*operatorRuntime
 }
@@ -146,9 +220,9 @@ func (rt *divOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uin
 
 	if err == nil {
 
-		res, err = rt.numOp(func(n1 float64, n2 float64) interface{} {
+		res, err = rt.divOp(func(n1 float64, n2 float64) interface{} {
 			return n1 / n2
-		}, vs, is, tid)
+		}, false, vs, is, tid)
 	}
 
 	return res, err
@@ -175,9 +249,9 @@ func (rt *divintOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid
 
 	if err == nil {
 
-		res, err = rt.numOp(func(n1 float64, n2 float64) interface{} {
+		res, err = rt.divOp(func(n1 float64, n2 float64) interface{} {
 			return math.Floor(n1 / n2)
-		}, vs, is, tid)
+		}, false, vs, is, tid)
 	}
 
 	return res, err
@@ -204,9 +278,9 @@ func (rt *modintOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid
 
 	if err == nil {
 
-		res, err = rt.numOp(func(n1 float64, n2 float64) interface{} {
+		res, err = rt.divOp(func(n1 float64, n2 float64) interface{} {
 			return float64(int64(n1) % int64(n2))
-		}, vs, is, tid)
+		}, true, vs, is, tid)
 	}
 
 	return res, err
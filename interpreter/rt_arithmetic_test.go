@@ -11,7 +11,11 @@
 package interpreter
 
 import (
+	"math"
 	"testing"
+
+	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 func TestSimpleArithmetics(t *testing.T) {
@@ -142,3 +146,546 @@ modint
 		return
 	}
 }
+
+func TestOperatorPrecedence(t *testing.T) {
+
+	// Multiplication binds stronger than addition
+
+	res, err := UnitTestEvalAndAST(
+		`2 + 3 * 4`, nil,
+		`
+plus
+  number: 2
+  times
+    number: 3
+    number: 4
+`[1:])
+
+	if err != nil || res != 14. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`(2 + 3) * 4`, nil,
+		`
+times
+  plus
+    number: 2
+    number: 3
+  number: 4
+`[1:])
+
+	if err != nil || res != 20. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Operators of the same precedence are left associative
+
+	res, err = UnitTestEvalAndAST(
+		`10 - 4 - 3`, nil,
+		`
+minus
+  minus
+    number: 10
+    number: 4
+  number: 3
+`[1:])
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`8 / 2 / 2`, nil,
+		`
+div
+  div
+    number: 8
+    number: 2
+  number: 2
+`[1:])
+
+	if err != nil || res != 2. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`17 // 5 % 3 + 1`, nil,
+		`
+plus
+  modint
+    divint
+      number: 17
+      number: 5
+    number: 3
+  number: 1
+`[1:])
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Unary minus
+
+	res, err = UnitTestEvalAndAST(
+		`- -3`, nil,
+		`
+minus
+  minus
+    number: 3
+`[1:])
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`-2 * 3`, nil,
+		`
+times
+  minus
+    number: 2
+  number: 3
+`[1:])
+
+	if err != nil || res != -6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`- 3 + 5`, nil,
+		`
+plus
+  minus
+    number: 3
+  number: 5
+`[1:])
+
+	if err != nil || res != 2. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Boolean not
+
+	res, err = UnitTestEvalAndAST(
+		`not not true`, nil,
+		`
+not
+  not
+    true
+`[1:])
+
+	if err != nil || res != true {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`not 1 > 2 or false`, nil,
+		`
+or
+  not
+    >
+      number: 1
+      number: 2
+  false
+`[1:])
+
+	if err != nil || res != true {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Comparison chaining
+
+	res, err = UnitTestEvalAndAST(
+		`1 < 2 == true`, nil,
+		`
+==
+  <
+    number: 1
+    number: 2
+  true
+`[1:])
+
+	if err != nil || res != true {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Mixed arithmetic and boolean operators
+
+	res, err = UnitTestEvalAndAST(
+		`1 + 2 > 2 and 3 * 2 == 6`, nil,
+		`
+and
+  >
+    plus
+      number: 1
+      number: 2
+    number: 2
+  ==
+    times
+      number: 3
+      number: 2
+    number: 6
+`[1:])
+
+	if err != nil || res != true {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestIntegerDivisionAndModulo(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`7 // 2`, nil)
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`(-7) // 2`, nil)
+
+	if err != nil || res != -4. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 // -2`, nil)
+
+	if err != nil || res != -4. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`-7 // -2`, nil)
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`6 // 3`, nil)
+
+	if err != nil || res != 2. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 % 2`, nil)
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`(-7) % 2`, nil)
+
+	if err != nil || res != -1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 % -2`, nil)
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7.9 % 2.9`, nil)
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`0 % 5`, nil)
+
+	if err != nil || res != 0. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 / 0`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrDivisionByZero ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Division by zero (0) (Line:1 Pos:5)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 // 0`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrDivisionByZero ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Division by zero (0) (Line:1 Pos:6)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 % 0`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrDivisionByZero ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Division by zero (0) (Line:1 Pos:5)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`7 % 0.5`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrDivisionByZero ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Division by zero (0.5) (Line:1 Pos:5)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := 0; 7 / a`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrDivisionByZero ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Division by zero (a=0) (Line:1 Pos:13)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`"hello" + " " + "world"`, nil)
+
+	if err != nil || res != "hello world" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"count: " + 42`, nil)
+
+	if err != nil || res != "count: 42" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 + "px"`, nil)
+
+	if err != nil || res != "1px" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1.5 + "em"`, nil)
+
+	if err != nil || res != "1.5em" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"" + true`, nil)
+
+	if err != nil || res != "true" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a" + null`, nil)
+
+	if err != nil || res != "anull" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`null + "a"`, nil)
+
+	if err != nil || res != "nulla" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := "x"; b := 2; a + b + 1`, nil)
+
+	if err != nil || res != "x21" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 + 2 + "px"`, nil)
+
+	if err != nil || res != "3px" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 + 2`, nil)
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 + true`, nil)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrNotANumber {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestListRepetition(t *testing.T) {
+
+	res, err := UnitTestEval(
+		`[1, 2] * 0`, nil)
+
+	if err != nil || scope.EvalToString(res) != "[]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1, 2] * 1`, nil)
+
+	if err != nil || scope.EvalToString(res) != "[1,2]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1, 2] * 3`, nil)
+
+	if err != nil || scope.EvalToString(res) != "[1,2,1,2,1,2]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`3 * ["a"]`, nil)
+
+	if err != nil || scope.EvalToString(res) != `["a","a","a"]` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[] * 5`, nil)
+
+	if err != nil || scope.EvalToString(res) != "[]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[[1], {"a": 1}] * 2`, nil)
+
+	if err != nil || scope.EvalToString(res) != `[[1],{"a":1},[1],{"a":1}]` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := [1]; b := a * 2; a[0] := 5; b`, nil)
+
+	if err != nil || scope.EvalToString(res) != "[1,1]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1, 2] * 1.5`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number (1.5) (Line:1 Pos:10)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1, 2] * -1`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number (-) (Line:1 Pos:10)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`n := "2"; [1] * n`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number (n=2) (Line:1 Pos:17)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1] * [2]`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number ([) (Line:1 Pos:7)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	vs := scope.NewScope(scope.GlobalScope)
+	vs.SetValue("n", math.Inf(1))
+
+	res, err = UnitTestEval(
+		`[1] * n`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number (n=+Inf) (Line:1 Pos:7)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1] * 100000000000000000000`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Repeated list would have more than 16777216 elements) (Line:1 Pos:7)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[] * 100000000000000000000`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Repeated list would have more than 16777216 elements) (Line:1 Pos:6)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1, 2] * 10000000`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Repeated list would have more than 16777216 elements) (Line:1 Pos:10)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
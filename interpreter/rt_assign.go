@@ -86,7 +86,9 @@ func (rt *assignmentRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 
 		// Execute let statements on the right before evaluating the left side
 
-		if _, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+		_, err = rt.node.Children[0].Runtime.Eval(vs, is, tid)
+
+		if err = rt.wrapVarAccessError(err); err == nil {
 			var val interface{}
 
 			val, err = rt.node.Children[1].Runtime.Eval(vs, is, tid)
@@ -94,7 +96,9 @@ func (rt *assignmentRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 			if err == nil {
 				if len(rt.leftSide) == 1 {
 
-					err = rt.leftSide[0].Set(vs, is, tid, val)
+					if !isWildcard(rt.leftSide[0]) {
+						err = rt.wrapVarAccessError(rt.leftSide[0].Set(vs, is, tid, val))
+					}
 
 				} else if valList, ok := val.([]interface{}); ok {
 
@@ -109,6 +113,10 @@ func (rt *assignmentRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 
 						for i, v := range rt.leftSide {
 
+							if isWildcard(v) {
+								continue
+							}
+
 							if err = v.Set(vs, is, tid, valList[i]); err != nil {
 								err = rt.erp.NewRuntimeError(util.ErrVarAccess,
 									err.Error(), rt.node)
@@ -130,6 +138,78 @@ func (rt *assignmentRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 	return nil, err
 }
 
+/*
+isWildcard checks if a given left side of an assignment is the wildcard
+variable. Values assigned to the wildcard variable are discarded.
+*/
+func isWildcard(v *identifierRuntime) bool {
+	return v.node.Token.Val == parser.WildcardName && len(v.node.Children) == 0
+}
+
+/*
+wrapVarAccessError converts an error of a variable scope (e.g. an out of
+bounds list access) into a runtime error.
+*/
+func (rt *assignmentRuntime) wrapVarAccessError(err error) error {
+	if err != nil {
+		switch err.(type) {
+		case *util.RuntimeError, *util.RuntimeErrorWithDetail:
+		default:
+			err = rt.erp.NewRuntimeError(util.ErrVarAccess, err.Error(), rt.node)
+		}
+	}
+	return err
+}
+
+/*
+nullcoalesceAssignmentRuntime is the runtime component for assignments which
+only assign a value if the variable is currently null.
+*/
+type nullcoalesceAssignmentRuntime struct {
+	*assignmentRuntime
+}
+
+/*
+nullcoalesceAssignmentRuntimeInst returns a new runtime component instance.
+*/
+func nullcoalesceAssignmentRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &nullcoalesceAssignmentRuntime{&assignmentRuntime{newBaseRuntime(erp, node), nil}}
+}
+
+/*
+Validate this node and all its child nodes.
+*/
+func (rt *nullcoalesceAssignmentRuntime) Validate() error {
+	err := rt.assignmentRuntime.Validate()
+
+	if err == nil && len(rt.leftSide) != 1 {
+		err = rt.erp.NewRuntimeError(util.ErrVarAccess,
+			"Must have a variable on the left side of the assignment", rt.node)
+	}
+
+	return err
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *nullcoalesceAssignmentRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	_, err := rt.baseRuntime.Eval(vs, is, tid)
+
+	if err == nil {
+		var val interface{}
+
+		if val, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil && val == nil {
+
+			if val, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
+				err = rt.leftSide[0].Set(vs, is, tid, val)
+			}
+		}
+	}
+
+	return nil, err
+}
+
 /*
 letRuntime is the runtime component for let statements
 */
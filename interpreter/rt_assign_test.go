@@ -14,11 +14,12 @@ import (
 	"testing"
 
 	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 func TestSimpleAssignments(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEvalAndAST(
 		`let a := 42`, vs,
@@ -144,7 +145,7 @@ func TestSimpleAssignments(t *testing.T) {
 
 func TestComplexAssignments(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEvalAndAST(
 		`let [a, b] := ["test", [1,2,3]]`, vs,
@@ -259,9 +260,128 @@ statements
 
 }
 
+func TestNegativeIndexAssignments(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	if _, err := UnitTestEval(`l := [1, 2, 3, 4]; l[-1] := "x"`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("l"); scope.EvalToString(res) != `[1,2,3,"x"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	res, err := UnitTestEval(
+		`l[-2] := "y"; l`, vs)
+
+	if err != nil || scope.EvalToString(res) != `[1,2,"y","x"]` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`l[-4] := "z"; l`, vs)
+
+	if err != nil || scope.EvalToString(res) != `["z",2,"y","x"]` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`m := [[1, 2], [3, 4]]; m[-1][-2] := 5; m`, vs)
+
+	if err != nil || scope.EvalToString(res) != "[[1,2],[5,4]]" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`l[-5] := "x"`, vs)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrVarAccess ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (Out of bounds access to list l with index: -5) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`l[4] := "x"`, vs)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrVarAccess ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (Out of bounds access to list l with index: 4) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`m[-3][0] := "x"`, vs)
+
+	if err == nil || err.(*util.RuntimeError).Type != util.ErrVarAccess ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (Out of bounds access to list m with index: -3) (Line:1 Pos:1)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestWildcardAssignments(t *testing.T) {
+
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEvalAndAST(
+		`[a, _, c] := [1, 2, 3]`, vs,
+		`
+:=
+  list
+    identifier: a
+    identifier: _
+    identifier: c
+  list
+    number: 1
+    number: 2
+    number: 3
+`[1:])
+
+	if vsRes := vs.String(); vsRes != `GlobalScope {
+    a (float64) : 1
+    c (float64) : 3
+}` {
+		t.Error("Unexpected result: ", vsRes, res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`[_, _] := [4, 5]`, vs)
+
+	if vsRes := vs.String(); vsRes != `GlobalScope {
+    a (float64) : 1
+    c (float64) : 3
+}` {
+		t.Error("Unexpected result: ", vsRes, res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`_ := 1; _`, vs)
+
+	if vsRes := vs.String(); err != nil || res != nil || vsRes != `GlobalScope {
+    a (float64) : 1
+    c (float64) : 3
+}` {
+		t.Error("Unexpected result: ", vsRes, res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`[a, _] := [1, 2, 3]`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Invalid state (Assigned number of variables is different to number of values (2 variables vs 3 values)) (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestScopedDeclaration(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEval(`
 a := 5
@@ -315,3 +435,63 @@ foo()`, vs)
 		return
 	}
 }
+
+func TestNullCoalesceAssignment(t *testing.T) {
+
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEvalAndAST(`
+a ??= 1
+b := 0
+b ??= 2
+c := {}
+c.x ??= 3
+c.x ??= raise("shouldnotbeevaluated")
+let d ??= 4
+`, vs, `
+statements
+  ??=
+    identifier: a
+    number: 1
+  :=
+    identifier: b
+    number: 0
+  ??=
+    identifier: b
+    number: 2
+  :=
+    identifier: c
+    map
+  ??=
+    identifier: c
+      identifier: x
+    number: 3
+  ??=
+    identifier: c
+      identifier: x
+    identifier: raise
+      funccall
+        string: 'shouldnotbeevaluated'
+  ??=
+    let
+      identifier: d
+    number: 4
+`[1:])
+
+	if vsRes := vs.String(); err != nil || res != nil || vsRes != `GlobalScope {
+    a (float64) : 1
+    b (float64) : 0
+    c (map[interface {}]interface {}) : {"x":3}
+    d (float64) : 4
+}` {
+		t.Error("Unexpected result: ", vsRes, res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`[a, b] ??= [1, 2]`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (Must have a variable on the left side of the assignment) (Line:1 Pos:8)" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
@@ -12,11 +12,13 @@ package interpreter
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
 	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 // Basic Boolean Operator Runtimes
@@ -313,7 +315,8 @@ func (rt *notOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uin
 /*
 likeOpRuntime is the pattern matching operator. The syntax of the regular
 expressions accepted is the same general syntax used by Go, Perl, Python, and
-other languages.
+other languages. Compiled patterns are kept in the instance state so a pattern
+is only compiled once (e.g. when the operator is evaluated inside a loop).
 */
 type likeOpRuntime struct {
 	*operatorRuntime
@@ -327,7 +330,16 @@ func likeOpRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Ru
 }
 
 /*
-Eval evaluate this runtime component.
+likePattern is a compiled like pattern which is cached in the instance state.
+*/
+type likePattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+/*
+Eval evaluate this runtime component. Only the last used pattern is cached so
+dynamic patterns do not grow the instance state.
 */
 func (rt *likeOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
 	var res interface{}
@@ -338,17 +350,28 @@ func (rt *likeOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid ui
 		errorutil.AssertTrue(len(rt.node.Children) == 2,
 			fmt.Sprint("Operation requires 2 operands", rt.node))
 
-		str, err := rt.node.Children[0].Runtime.Eval(vs, is, tid)
-		if err == nil {
-			var pattern interface{}
+		var str, pattern interface{}
 
-			pattern, err = rt.node.Children[1].Runtime.Eval(vs, is, tid)
-			if err == nil {
+		if str, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+			if pattern, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
 				var re *regexp.Regexp
 
-				re, err = regexp.Compile(fmt.Sprint(pattern))
-				if err == nil {
+				patternString := fmt.Sprint(pattern)
+				patternKey := rt.instanceID + "pattern"
 
+				if cached, ok := is[patternKey].(*likePattern); ok && cached.pattern == patternString {
+					re = cached.re
+
+				} else if re, err = regexp.Compile(patternString); err == nil {
+					is[patternKey] = &likePattern{patternString, re}
+
+				} else {
+					err = rt.erp.NewRuntimeError(util.ErrInvalidConstruct,
+						fmt.Sprintf("Invalid regular expression %v: %v", patternString, err),
+						rt.node.Children[1])
+				}
+
+				if err == nil {
 					res = re.MatchString(fmt.Sprint(str))
 				}
 			}
@@ -414,6 +437,10 @@ func (rt *endswithOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 	return res, err
 }
 
+/*
+inOpRuntime is the membership operator. It checks if a value is an item of a
+list or a key of a map.
+*/
 type inOpRuntime struct {
 	*operatorRuntime
 }
@@ -434,19 +461,57 @@ func (rt *inOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 	_, err := rt.baseRuntime.Eval(vs, is, tid)
 
 	if err == nil {
-		res, err = rt.listOp(func(val interface{}, list []interface{}) interface{} {
-			for _, i := range list {
-				if val == i {
-					return true
+		var val, container interface{}
+
+		errorutil.AssertTrue(len(rt.node.Children) == 2,
+			fmt.Sprint("Operation requires 2 operands", rt.node))
+
+		if val, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+			if container, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
+
+				res = false
+
+				if list, ok := container.([]interface{}); ok {
+
+					for _, i := range list {
+
+						// Compare deeply as the items may be lists or maps
+
+						if reflect.DeepEqual(val, i) {
+							res = true
+							break
+						}
+					}
+
+				} else if m, ok := container.(map[interface{}]interface{}); ok {
+
+					// Compare the string representation as keys might be
+					// numbers or strings (e.g. when a map was read from JSON)
+
+					valString := fmt.Sprint(val)
+
+					for k := range m {
+						if fmt.Sprint(k) == valString {
+							res = true
+							break
+						}
+					}
+
+				} else {
+
+					err = rt.erp.NewRuntimeError(util.ErrNotAListOrMap,
+						rt.errorDetailString(rt.node.Children[1].Token, container), rt.node.Children[0])
 				}
 			}
-			return false
-		}, vs, is, tid)
+		}
 	}
 
 	return res, err
 }
 
+/*
+notinOpRuntime is the complement of the membership operator.
+*/
 type notinOpRuntime struct {
 	*inOpRuntime
 }
@@ -474,3 +539,87 @@ func (rt *notinOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid u
 
 	return res, err
 }
+
+// Conditional operators
+// =====================
+
+/*
+nullcoalesceOpRuntime is the null coalescing operator. It returns the left
+operand if it is not null otherwise the right operand. The right operand is
+only evaluated if the left operand is null.
+*/
+type nullcoalesceOpRuntime struct {
+	*operatorRuntime
+}
+
+/*
+nullcoalesceOpRuntimeInst returns a new runtime component instance.
+*/
+func nullcoalesceOpRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &nullcoalesceOpRuntime{&operatorRuntime{newBaseRuntime(erp, node)}}
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *nullcoalesceOpRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	var res interface{}
+
+	_, err := rt.baseRuntime.Eval(vs, is, tid)
+
+	if err == nil {
+		errorutil.AssertTrue(len(rt.node.Children) == 2,
+			fmt.Sprint("Operation requires 2 operands", rt.node))
+
+		if res, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil && res == nil {
+			res, err = rt.node.Children[1].Runtime.Eval(vs, is, tid)
+		}
+	}
+
+	return res, err
+}
+
+/*
+ternaryRuntime is the ternary conditional expression. It evaluates the first
+branch if the condition is true otherwise the second branch. Only the selected
+branch is evaluated.
+*/
+type ternaryRuntime struct {
+	*baseRuntime
+}
+
+/*
+ternaryRuntimeInst returns a new runtime component instance.
+*/
+func ternaryRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &ternaryRuntime{newBaseRuntime(erp, node)}
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *ternaryRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	var res interface{}
+
+	_, err := rt.baseRuntime.Eval(vs, is, tid)
+
+	if err == nil {
+		var cond interface{}
+
+		errorutil.AssertTrue(len(rt.node.Children) == 3,
+			fmt.Sprint("Operation requires 3 operands", rt.node))
+
+		if cond, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
+
+			// Use the same truth rules as the guard of a conditional statement
+
+			if cond != nil && cond != false && cond != 0 {
+				res, err = rt.node.Children[1].Runtime.Eval(vs, is, tid)
+			} else {
+				res, err = rt.node.Children[2].Runtime.Eval(vs, is, tid)
+			}
+		}
+	}
+
+	return res, err
+}
@@ -13,6 +13,10 @@ package interpreter
 import (
 	"fmt"
 	"testing"
+
+	"github.com/rhedin/Abe_common/errorutil"
+	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/scope"
 )
 
 func TestSimpleBoolean(t *testing.T) {
@@ -265,6 +269,60 @@ like
 		return
 	}
 
+	res, err = UnitTestEval(
+		`["Hans" like "^H.*s$", "Hans" like "^a", "xHansx" like "Hans", "foo" like "^f[a-z]+"]`, nil)
+
+	if fmt.Sprint(res) != "[true false true true]" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`c := 0; for i in ["Hans", "Hunz", "Horst"] { if i like "^H.n" { c := c + 1 } }; c`, nil)
+
+	if res != 2. || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"Hans" like "(H"`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Invalid construct "+
+		"(Invalid regular expression (H: error parsing regexp: missing closing ): `(H`) (Line:1 Pos:13)" {
+		t.Error(res, err)
+		return
+	}
+
+	ast, err := parser.ParseWithRuntime("", `"Hans" like "^H"`, NewECALRuntimeProvider(""))
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	is := make(map[string]interface{})
+
+	for i := 0; i < 2; i++ {
+		if res, err = ast.Runtime.Eval(scope.NewScope(""), is, 0); res != true || err != nil || len(is) != 1 {
+			t.Error("Unexpected result:", res, err, is)
+			return
+		}
+	}
+
+	ast, err = parser.ParseWithRuntime("", `"Hans" like p`, NewECALRuntimeProvider(""))
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	is = make(map[string]interface{})
+	vs := scope.NewScope("")
+
+	for i := 0; i < 3; i++ {
+		vs.SetValue("p", fmt.Sprintf("^H.*%v?", i))
+
+		if res, err = ast.Runtime.Eval(vs, is, 0); res != true || err != nil || len(is) != 1 {
+			t.Error("Unexpected result:", res, err, is)
+			return
+		}
+	}
+
 	res, err = UnitTestEvalAndAST(
 		`"Hans" hasprefix "Ha"`, nil,
 		`
@@ -323,6 +381,38 @@ in
 		return
 	}
 
+	res, err = UnitTestEval(
+		`[1, {"a" : 2}] in [1, [1, {"a" : 2}]]`, nil)
+
+	if fmt.Sprint(res) != "true" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := 0; if "a" in ["a", "b"] { a := 1 }; a`, nil)
+
+	if fmt.Sprint(res) != "1" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"c" in ["a", "b"] or 1 in [1]`, nil)
+
+	if fmt.Sprint(res) != "true" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 in 1`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a list nor a map (1) (Line:1 Pos:1)" {
+		t.Error(res, err)
+		return
+	}
+
 	res, err = UnitTestEvalAndAST(
 		`"NotHans" notin [1,2,"Hans"]`, nil,
 		`
@@ -338,4 +428,134 @@ notin
 		t.Error(res, err)
 		return
 	}
+
+	res, err = UnitTestEval(
+		`"Hans" notin [1,2,"Hans"]`, nil)
+
+	if fmt.Sprint(res) != "false" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`1 notin []`, nil)
+
+	if fmt.Sprint(res) != "true" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`[1 in {1 : "a"}, "1" in {1 : "a"}, "a" notin {1 : "a"}, "b" notin {"b" : 1}, 1 notin {}]`, nil)
+
+	if fmt.Sprint(res) != "[true true true false true]" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := "foo"; 1 notin a`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a list nor a map (a=foo) (Line:1 Pos:13)" {
+		t.Error(res, err)
+		return
+	}
+}
+
+func TestConditionalOperators(t *testing.T) {
+
+	res, err := UnitTestEvalAndAST(
+		`null ?? "default"`, nil,
+		`
+??
+  null
+  string: 'default'
+`[1:])
+
+	if fmt.Sprint(res) != "default" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`0 ?? "default"`, nil)
+
+	if res != 0. || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a ?? b ?? false`, nil)
+
+	if res != false || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"foo" ?? raise("shouldnotbeevaluated")`, nil)
+
+	if res != "foo" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`null ?? raise("evaluated")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): evaluated () (Line:1 Pos:9)" {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEvalAndAST(
+		`1 > 2 ? raise("shouldnotbeevaluated") : "foo"`, nil,
+		`
+ternary
+  >
+    number: 1
+    number: 2
+  identifier: raise
+    funccall
+      string: 'shouldnotbeevaluated'
+  string: 'foo'
+`[1:])
+
+	if res != "foo" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"x" ? "foo" : raise("shouldnotbeevaluated")`, nil)
+
+	if res != "foo" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`a := null; b := a ? 1 : a == null ? 2 : 3; b`, nil)
+
+	if res != 2. || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`m := {"a" : true ? 1 : 2, "b" : null ?? 3}; m`, nil)
+
+	if fmt.Sprint(res) != "map[a:1 b:3]" || err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`false ? 1 : raise("evaluated")`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): evaluated () (Line:1 Pos:13)" {
+		t.Error(res, err)
+		return
+	}
 }
@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_ecal/parser"
 	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/util"
@@ -78,13 +79,27 @@ funcRuntime is the runtime component for function declarations.
 */
 type funcRuntime struct {
 	*baseRuntime
+	loopVars []string // Variables of enclosing loops which are captured by value
 }
 
 /*
 funcRuntimeInst returns a new runtime component instance.
 */
 func funcRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
-	return &funcRuntime{newBaseRuntime(erp, node)}
+	return &funcRuntime{newBaseRuntime(erp, node), nil}
+}
+
+/*
+addLoopVars registers variables of an enclosing loop. The values of these
+variables are captured when the function is created so each loop iteration
+produces a closure with its own values.
+*/
+func (rt *funcRuntime) addLoopVars(names []string) {
+	for _, name := range names {
+		if name != parser.WildcardName && stringutil.IndexOf(name, rt.loopVars) == -1 {
+			rt.loopVars = append(rt.loopVars, name)
+		}
+	}
 }
 
 /*
@@ -102,7 +117,22 @@ func (rt *funcRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 			name = rt.node.Children[0].Token.Val
 		}
 
-		fc = &function{name, nil, nil, rt.node, vs}
+		declarationVS := vs
+
+		if len(rt.loopVars) > 0 {
+
+			// Store the current values of loop variables in a new scope
+
+			declarationVS = scope.NewScopeWithParent(scope.NameFromASTNode(rt.node), vs)
+
+			for _, loopVar := range rt.loopVars {
+				if val, ok, _ := vs.GetValue(loopVar); ok {
+					declarationVS.SetLocalValue(loopVar, val)
+				}
+			}
+		}
+
+		fc = &function{name, nil, nil, rt.node, declarationVS}
 
 		if name != "" {
 			vs.SetValue(name, fc)
@@ -117,7 +147,7 @@ function models a function in ECAL. It can have a context object attached - this
 */
 type function struct {
 	name          string
-	super         []interface{}   // Super function pointer
+	super         interface{}     // Super function pointer (list for init, map for methods)
 	this          interface{}     // Function context
 	declaration   *parser.ASTNode // Function declaration node
 	declarationVS parser.Scope    // Function declaration scope
@@ -182,7 +212,7 @@ func (f *function) Run(instanceID string, vs parser.Scope, is map[string]interfa
 
 		scope.SetParentOfScope(fvs, f.declarationVS)
 
-		res, err = body.Runtime.Eval(fvs, make(map[string]interface{}), tid)
+		res, err = body.Runtime.Eval(fvs, newInstanceState(is), tid)
 
 		// Check for return value (delivered as error object)
 
@@ -207,6 +237,14 @@ func (f *function) DocString() (string, error) {
 	return fmt.Sprintf("Declared function: %v (%v)", f.name, f.declaration.Token.PosString()), nil
 }
 
+/*
+FunctionName returns the name of this function. The name is empty for
+anonymous functions.
+*/
+func (f *function) FunctionName() string {
+	return f.name
+}
+
 /*
 String returns a string representation of this function.
 */
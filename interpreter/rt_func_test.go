@@ -11,6 +11,7 @@
 package interpreter
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/rhedin/Abe_common/stringutil"
@@ -19,7 +20,7 @@ import (
 
 func TestFunctions(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEvalAndAST(`
 foo := [ [ func (a, b, c=1) {
@@ -68,7 +69,7 @@ statements
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	res, err = UnitTestEval(`
 b := "a"
@@ -90,7 +91,7 @@ result1 := foo[0].a(3, 2)[1][0]
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	res, err = UnitTestEval(`
 b := "a"
@@ -112,7 +113,7 @@ result1 := foo.a[0](3, 2).x.y[0]
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	res, err = UnitTestEvalAndAST(`
 foo := {
@@ -181,7 +182,7 @@ statements
 }
 
 func TestEmptyReturn(t *testing.T) {
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEvalAndAST(`
 func myfunc() {
@@ -215,7 +216,7 @@ statements
 
 func TestFunctionScoping(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEval(`
 c := 1
@@ -235,7 +236,7 @@ result1 := foo(3, 2)
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	res, err = UnitTestEval(`
 func fib(n) {
@@ -259,7 +260,7 @@ result1 := fib(12)
 
 func TestObjectInstantiation(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	res, err := UnitTestEvalAndAST(`
 Super := {
@@ -380,4 +381,406 @@ result1 := new(Bar)
 		return
 	}
 
+	res, err = UnitTestEval(`
+C := {
+  "init" : func(x) {
+    this.c := x
+  }
+}
+
+B := {
+  "super" : [ C ]
+
+  "init" : func(x) {
+    this.b := x
+    super[0](x + 1)
+  }
+}
+
+A := {
+  "super" : [ B ]
+
+  "init" : func(x) {
+    this.a := x
+    super[0](x + 1)
+  }
+}
+
+a := new(A, 1)
+[a.a, a.b, a.c]
+`, nil)
+
+	if fmt.Sprint(res) != "[1 2 3]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+C := {
+  "init" : func(x) {
+    this.c := x
+  }
+}
+
+B := {
+  "super" : [ C ]
+
+  "getC" : func() {
+    return this.c
+  }
+}
+
+A := {
+  "super" : [ B ]
+
+  "init" : func(x) {
+    this.a := x
+    super[0](x + 1)
+  }
+}
+
+a := new(A, 1)
+[a.a, a.getC()]
+`, nil)
+
+	if fmt.Sprint(res) != "[1 2]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+C := {
+  "getName" : func() {
+    return "C"
+  }
+}
+
+B := {
+  "super" : [ C ]
+}
+
+A := {
+  "super" : [ B ]
+
+  "init" : func() {
+  }
+}
+
+X := {
+  "init" : func() {
+  }
+}
+
+Data := {
+  "name" : "foo"
+}
+
+a := new(A)
+b := new(B)
+c := new(C)
+
+[instanceof(a, A), instanceof(a, B), instanceof(a, C), instanceof(a, X),
+ instanceof(b, B), instanceof(b, C), instanceof(b, A),
+ instanceof(c, C), instanceof(c, B), instanceof(new(X), A), instanceof(new(Data), Data)]
+`, nil)
+
+	if fmt.Sprint(res) != "[true true true false true true false true false false false]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`instanceof({}, 1)`, nil)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 2 should be a map) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+C := {
+  "id" : 10
+
+  "getName" : func(prefix) {
+    return prefix + this.id
+  }
+}
+
+B := {
+  "super" : [ C ]
+
+  "getName" : func(prefix) {
+    return super.getName(prefix + 1)
+  }
+}
+
+A := {
+  "super" : [ B ]
+
+  "id" : 100
+
+  "getName" : func(prefix) {
+    return [super.getName(prefix), this.id]
+  }
+}
+
+a := new(A)
+a.getName(1)
+`, nil)
+
+	if fmt.Sprint(res) != "[102 100]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	if _, err := UnitTestEval(`
+func add(a, b, c) {
+  return a + b + c
+}
+l := [2, 3]
+`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := UnitTestEval(
+		`add(...[1, 2, 3])`, vs)
+
+	if err != nil || res != 6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`add(1, 2, 3)`, vs)
+
+	if err != nil || res != 6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`add(1, ...l)`, vs)
+
+	if err != nil || res != 6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`add(...[1], ...l)`, vs)
+
+	if err != nil || res != 6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`add(...[], 1, ...[2, 3], ...[])`, vs)
+
+	if err != nil || res != 6. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`len(...[[1, 2, 3]])`, vs)
+
+	if err != nil || res != 3. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`add(...1)`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a list (Cannot spread value: 1) (Line:1 Pos:5)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(`a := ...l`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Invalid construct (Spread operator can only be used in function call arguments or map values) (Line:1 Pos:6)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestReturnPropagation(t *testing.T) {
+
+	// Return from inside a nested for loop
+
+	res, err := UnitTestEval(`
+func f() {
+  for a in [1, 2, 3] {
+    for b in [4, 5] {
+      if a == 2 {
+        return [a, b]
+      }
+    }
+  }
+  return "end"
+}
+f()
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "[2 4]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Return from inside a nested if
+
+	res, err = UnitTestEval(`
+func f(a) {
+  if a > 0 {
+    if a > 1 {
+      return "big"
+    } else {
+      return "small"
+    }
+  }
+  return "none"
+}
+[f(2), f(1), f(0)]
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "[big small none]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Return from inside a try block
+
+	res, err = UnitTestEval(`
+func f() {
+  try {
+    return 1
+  } except {
+    return 2
+  } otherwise {
+    return 3
+  }
+  return 4
+}
+f()
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "1" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Return from inside a try block inside a loop
+
+	res, err = UnitTestEval(`
+func f() {
+  for a in [1, 2] {
+    try {
+      if a == 2 {
+        return a
+      }
+    } except e {
+      return e
+    }
+  }
+}
+f()
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "2" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Return from inside a function called from a sink
+
+	res, err = UnitTestEval(`
+func g() {
+  for a in range(1, 10) {
+    try {
+      return a * 10
+    } except {
+      return "error"
+    }
+  }
+}
+r := null
+sink s1
+  kindmatch [ "test" ],
+  {
+    r := g()
+  }
+addEventAndWait("e", "test", {})
+r
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "10" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestClosuresOverLoopVariables(t *testing.T) {
+
+	// Each closure captures its own loop variable value
+
+	res, err := UnitTestEval(`
+fs := []
+for i in range(0, 9) {
+  fs := add(fs, func() { return i })
+}
+r := []
+for f in fs {
+  r := add(r, f())
+}
+r
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Nested closures inside a loop with multiple loop variables
+
+	res, err = UnitTestEval(`
+fs := {}
+m := {"a" : 1, "b" : 2}
+for [k, v] in m {
+  if v > 0 {
+    fs[k] := func(x) {
+      return func() { return [k, v, x] }
+    }
+  }
+}
+fa := fs.a(1)
+fb := fs.b(2)
+[fa(), fb()]
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "[[a 1 1] [b 2 2]]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Other variables are still captured by reference
+
+	res, err = UnitTestEval(`
+fs := []
+c := 0
+for i in [1, 2] {
+  fs := add(fs, func() { return [i, c] })
+}
+c := 5
+[fs[0](), fs[1]()]
+`, scope.NewGlobalScope())
+
+	if err != nil || fmt.Sprint(res) != "[[1 5] [2 5]]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
 }
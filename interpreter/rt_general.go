@@ -11,7 +11,9 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_ecal/parser"
@@ -52,7 +54,29 @@ func (rt *baseRuntime) Validate() error {
 }
 
 /*
-Eval evaluate this runtime component.
+Inspect returns a short human-readable description of this runtime component
+consisting of its type, source, line and position.
+*/
+func (rt *baseRuntime) Inspect() string {
+	rtType := "baseRuntime"
+
+	if rt.node.Runtime != nil {
+		rtType = fmt.Sprintf("%T", rt.node.Runtime)
+		rtType = rtType[strings.LastIndex(rtType, ".")+1:]
+	}
+
+	if rt.node.Token == nil {
+		return fmt.Sprintf("%v@<synthetic %v>", rtType, rt.node.Name)
+	}
+
+	return fmt.Sprintf("%v@%v:%v:%v", rtType, rt.node.Token.Lsource,
+		rt.node.Token.Lline, rt.node.Token.Lpos)
+}
+
+/*
+Eval evaluate this runtime component. All runtime components must call this
+function before evaluating themselves - it is the single integration point
+for the debugger which is notified of every visited state of a thread.
 */
 func (rt *baseRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
 	var err error
@@ -63,6 +87,17 @@ func (rt *baseRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 		err = rt.erp.Debugger.VisitState(rt.node, vs, tid)
 		rt.erp.Debugger.SetLockingState(rt.erp.MutexeOwners, rt.erp.MutexLog)
 		rt.erp.Debugger.SetThreadPool(rt.erp.Processor.ThreadPool())
+		rt.erp.Debugger.SetLogger(rt.erp.Logger)
+	}
+
+	if err == nil {
+		err = rt.erp.useBudget(rt.node)
+	}
+
+	// Stop if the evaluation was cancelled (e.g. by a timeout)
+
+	if ctx, ok := is["context"].(context.Context); ok && err == nil && ctx.Err() != nil {
+		err = rt.erp.NewRuntimeError(util.ErrTimeout, "Evaluation was cancelled", rt.node)
 	}
 
 	return nil, err
@@ -76,6 +111,26 @@ func newBaseRuntime(erp *ECALRuntimeProvider, node *parser.ASTNode) *baseRuntime
 	return &baseRuntime{fmt.Sprint(instanceCounter), erp, node, false}
 }
 
+/*
+newInstanceState returns a new empty instance state. The monitor of a sink is
+carried over so all code in a sink body (including called functions) can add
+events to the event cascade.
+The cancellation context of a timeout is carried over as well.
+*/
+func newInstanceState(is map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{})
+
+	if m, ok := is["monitor"]; ok {
+		ret["monitor"] = m
+	}
+
+	if ctx, ok := is["context"]; ok {
+		ret["context"] = ctx
+	}
+
+	return ret
+}
+
 // Void Runtime
 // ============
 
@@ -152,9 +207,14 @@ func (rt *importRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid ui
 				var ast *parser.ASTNode
 
 				if ast, err = parser.ParseWithRuntime(fmt.Sprint(importPath), codeText, rt.erp); err == nil {
+
+					if rt.erp.Debugger != nil {
+						rt.erp.Debugger.NotifySourceLoaded(fmt.Sprint(importPath))
+					}
+
 					if err = ast.Runtime.Validate(); err == nil {
 
-						ivs := scope.NewScope(scope.GlobalScope)
+						ivs := scope.NewGlobalScope()
 						if _, err = ast.Runtime.Eval(ivs, make(map[string]interface{}), tid); err == nil {
 							irt := rt.node.Children[1].Runtime.(*identifierRuntime)
 							irt.Set(vs, is, tid, scope.ToObject(ivs))
@@ -297,7 +357,6 @@ numOp executes an operation on two number values.
 */
 func (rt *operatorRuntime) numOp(op func(float64, float64) interface{},
 	vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
-	var ok bool
 	var res1, res2 interface{}
 	var err error
 
@@ -306,28 +365,67 @@ func (rt *operatorRuntime) numOp(op func(float64, float64) interface{},
 
 	if res1, err = rt.node.Children[0].Runtime.Eval(vs, is, tid); err == nil {
 		if res2, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
-			var res1Num, res2Num float64
+			return rt.applyNumOp(op, res1, res2)
+		}
+	}
 
-			if res1Num, ok = res1.(float64); !ok {
-				err = rt.erp.NewRuntimeError(util.ErrNotANumber,
-					rt.errorDetailString(rt.node.Children[0].Token, res1), rt.node.Children[0])
+	return nil, err
+}
 
-			} else {
-				if res2Num, ok = res2.(float64); !ok {
-					err = rt.erp.NewRuntimeError(util.ErrNotANumber,
-						rt.errorDetailString(rt.node.Children[1].Token, res2), rt.node.Children[1])
+/*
+applyNumOp executes an operation on two already evaluated number values.
+*/
+func (rt *operatorRuntime) applyNumOp(op func(float64, float64) interface{},
+	res1 interface{}, res2 interface{}) (interface{}, error) {
+	var ok bool
+	var err error
+	var res1Num, res2Num float64
 
-				} else {
+	if res1Num, ok = res1.(float64); !ok {
+		err = rt.erp.NewRuntimeError(util.ErrNotANumber,
+			rt.errorDetailString(rt.node.Children[0].Token, res1), rt.node.Children[0])
 
-					return op(res1Num, res2Num), err
-				}
-			}
+	} else {
+		if res2Num, ok = res2.(float64); !ok {
+			err = rt.erp.NewRuntimeError(util.ErrNotANumber,
+				rt.errorDetailString(rt.node.Children[1].Token, res2), rt.node.Children[1])
+
+		} else {
+
+			return op(res1Num, res2Num), err
 		}
 	}
 
 	return nil, err
 }
 
+/*
+divOp executes a division operation on two number values. Returns an error
+if the divisor is zero. The divisor is truncated to an integer before the
+check if the operation is an integer operation.
+*/
+func (rt *operatorRuntime) divOp(op func(float64, float64) interface{}, intOp bool,
+	vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+
+	var divisorIsZero bool
+
+	res, err := rt.numOp(func(n1 float64, n2 float64) interface{} {
+		if n2 == 0 || intOp && int64(n2) == 0 {
+			divisorIsZero = true
+			return nil
+		}
+		return op(n1, n2)
+	}, vs, is, tid)
+
+	if err == nil && divisorIsZero {
+		res = nil
+		err = rt.erp.NewRuntimeError(util.ErrDivisionByZero,
+			rt.errorDetailString(rt.node.Children[1].Token, float64(0)), rt.node.Children[1])
+	}
+
+	return res, err
+}
+
 /*
 genOp executes an operation on two general values.
 */
@@ -409,33 +507,3 @@ func (rt *operatorRuntime) boolOp(op func(bool, bool) interface{},
 
 	return res, err
 }
-
-/*
-listOp executes an operation on a value and a list.
-*/
-func (rt *operatorRuntime) listOp(op func(interface{}, []interface{}) interface{},
-	vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
-
-	var res interface{}
-
-	errorutil.AssertTrue(len(rt.node.Children) == 2,
-		fmt.Sprint("Operation requires 2 operands", rt.node))
-
-	res1, err := rt.node.Children[0].Runtime.Eval(vs, is, tid)
-	if err == nil {
-		var res2 interface{}
-
-		if res2, err = rt.node.Children[1].Runtime.Eval(vs, is, tid); err == nil {
-
-			res2list, ok := res2.([]interface{})
-			if !ok {
-				err = rt.erp.NewRuntimeError(util.ErrNotAList,
-					rt.errorDetailString(rt.node.Children[1].Token, res2), rt.node.Children[0])
-			} else {
-				res = op(res1, res2list)
-			}
-		}
-	}
-
-	return res, err
-}
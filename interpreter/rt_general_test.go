@@ -11,8 +11,11 @@
 package interpreter
 
 import (
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_ecal/parser"
 	"github.com/rhedin/Abe_ecal/scope"
 	"github.com/rhedin/Abe_ecal/util"
@@ -20,7 +23,7 @@ import (
 
 func TestGeneralCases(t *testing.T) {
 
-	rt := NewECALRuntimeProvider("a", nil, nil)
+	rt := NewECALRuntimeProvider("a")
 	id1 := rt.NewThreadID()
 	id2 := rt.NewThreadID()
 
@@ -43,11 +46,11 @@ func TestGeneralCases(t *testing.T) {
 	}
 
 	n, _ = parser.Parse("a", "a")
-	inv = &invalidRuntime{newBaseRuntime(NewECALRuntimeProvider("a", nil, nil), n)}
+	inv = &invalidRuntime{newBaseRuntime(NewECALRuntimeProvider("a"), n)}
 	n.Runtime = inv
 
 	n2, _ := parser.Parse("a", "a")
-	inv2 := &invalidRuntime{newBaseRuntime(NewECALRuntimeProvider("a", nil, nil), n2)}
+	inv2 := &invalidRuntime{newBaseRuntime(NewECALRuntimeProvider("a"), n2)}
 	n2.Runtime = inv2
 	n.Children = append(n.Children, n2)
 
@@ -57,7 +60,7 @@ func TestGeneralCases(t *testing.T) {
 	}
 
 	n, _ = parser.Parse("a", "a")
-	void := &voidRuntime{newBaseRuntime(NewECALRuntimeProvider("a", nil, nil), n)}
+	void := &voidRuntime{newBaseRuntime(NewECALRuntimeProvider("a"), n)}
 	n.Runtime = void
 	void.Validate()
 
@@ -67,9 +70,48 @@ func TestGeneralCases(t *testing.T) {
 	}
 }
 
+func TestDebuggerIntegration(t *testing.T) {
+	erp := NewECALRuntimeProvider("a")
+	erp.Debugger = NewECALDebugger(nil)
+	erp.Debugger.SetBreakPoint("a", 1)
+
+	n, _ := parser.Parse("a", "a")
+	void := &voidRuntime{newBaseRuntime(erp, n)}
+	n.Runtime = void
+	void.Validate()
+
+	done := make(chan error)
+
+	go func() {
+		_, err := void.Eval(nil, nil, 5)
+		done <- err
+	}()
+
+	// The breakpoint should suspend the thread in baseRuntime.Eval
+
+	var threadState map[string]interface{}
+
+	for i := 0; i < 100 && threadState["threadRunning"] != false; i++ {
+		time.Sleep(time.Millisecond)
+		threadState = erp.Debugger.Status().(map[string]interface{})["threads"].(map[string]map[string]interface{})["5"]
+	}
+
+	if threadState["threadRunning"] != false {
+		t.Error("Unexpected result:", threadState)
+		return
+	}
+
+	erp.Debugger.Continue(5, util.Resume)
+
+	if err := <-done; err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestImporting(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 	il := &util.MemoryImportLocator{Files: make(map[string]string)}
 
 	il.Files["foo/bar"] = `
@@ -100,9 +142,9 @@ statements
 	}
 
 	n, _ := parser.Parse("a", "a")
-	imp := &importRuntime{newBaseRuntime(NewECALRuntimeProvider("a", nil, nil), n)}
+	imp := &importRuntime{newBaseRuntime(NewECALRuntimeProvider("a"), n)}
 	n.Runtime = imp
-	imp.erp = NewECALRuntimeProvider("ECALTestRuntime", nil, nil)
+	imp.erp = NewECALRuntimeProvider("ECALTestRuntime")
 	imp.erp.ImportLocator = nil
 	imp.Validate()
 
@@ -114,7 +156,7 @@ statements
 
 func TestLogging(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEvalAndAST(
 		`
@@ -151,7 +193,7 @@ error: bar` {
 func TestOperatorRuntimeErrors(t *testing.T) {
 
 	n, _ := parser.Parse("a", "a")
-	op := &operatorRuntime{newBaseRuntime(NewECALRuntimeProvider("a", nil, nil), n)}
+	op := &operatorRuntime{newBaseRuntime(NewECALRuntimeProvider("a"), n)}
 
 	if res := op.errorDetailString(n.Token, "foo"); res != "a=foo" {
 		t.Error("Unexpected result:", res)
@@ -208,8 +250,264 @@ func TestOperatorRuntimeErrors(t *testing.T) {
 	res, err = UnitTestEval(
 		`a := "foo"; x in a`, nil)
 
-	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a list (a=foo) (Line:1 Pos:13)" {
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a list nor a map (a=foo) (Line:1 Pos:13)" {
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
 }
+
+func TestRuntimeProviderOptions(t *testing.T) {
+
+	// Zero options use defaults
+
+	erp := NewECALRuntimeProvider("test")
+
+	if _, ok := erp.ImportLocator.(*util.FileImportLocator); !ok || erp.Processor == nil ||
+		erp.Cron == nil || erp.Debugger != nil || erp.Budget != 0 || erp.Sandbox != nil {
+		t.Error("Unexpected result:", erp)
+		return
+	}
+
+	if _, ok := erp.Logger.(*util.MemoryLogger); !ok {
+		t.Error("Unexpected result:", erp.Logger)
+		return
+	}
+
+	// All options are applied
+
+	il := &util.MemoryImportLocator{Files: make(map[string]string)}
+	logger := util.NewMemoryLogger(10)
+	proc := NewECALRuntimeProvider("other").Processor
+	debugger := NewECALDebugger(scope.NewGlobalScope())
+
+	erp = NewECALRuntimeProvider("test", WithImportLocator(il), WithLogger(logger),
+		WithProcessor(proc), WithDebugger(debugger), WithBudget(10), WithSandbox([]string{"math"}))
+
+	if erp.ImportLocator != il || erp.Logger != logger || erp.Processor != proc ||
+		erp.Debugger != debugger || erp.Budget != 10 || len(erp.Sandbox) != 1 {
+		t.Error("Unexpected result:", erp)
+		return
+	}
+
+	eval := func(erp *ECALRuntimeProvider, code string) (interface{}, error) {
+		ast, err := parser.ParseWithRuntime("test", code, erp)
+		if err == nil {
+			if err = ast.Runtime.Validate(); err == nil {
+				return ast.Runtime.Eval(scope.NewGlobalScope(), make(map[string]interface{}), erp.NewThreadID())
+			}
+		}
+		return nil, err
+	}
+
+	// Budget limits the number of evaluation steps
+
+	erp = NewECALRuntimeProvider("test", WithBudget(50))
+
+	if res, err := eval(erp, "a := 1 + 2; a"); err != nil || res != float64(3) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, err := eval(erp, `
+a := 0
+for i in range(1, 100) {
+  a := a + i
+}
+`); err == nil || err.(*util.RuntimeError).Type != util.ErrBudgetExceeded {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Sandbox restricts stdlib access
+
+	erp = NewECALRuntimeProvider("test", WithSandbox([]string{"math.Pi", "math.floor"}))
+
+	if res, err := eval(erp, "math.floor(math.Pi)"); err != nil || res != float64(3) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, err := eval(erp, "math.ceil(1.5)"); err == nil ||
+		err.Error() != "ECAL error in test (test): Unknown construct (Unknown function: ceil) (Line:1 Pos:6)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if res, err := eval(erp, "math.E"); err != nil || res != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	erp = NewECALRuntimeProvider("test", WithSandbox([]string{"math"}))
+
+	if res, err := eval(erp, "math.ceil(1.5)"); err != nil || res != float64(2) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestRuntimeProviderClone(t *testing.T) {
+	il := &util.MemoryImportLocator{Files: make(map[string]string)}
+	logger := util.NewMemoryLogger(100)
+
+	erp := NewECALRuntimeProvider("orig", WithImportLocator(il), WithLogger(logger),
+		WithBudget(1000), WithSandbox([]string{"math"}))
+	erp.Debugger = NewECALDebugger(scope.NewGlobalScope())
+
+	c1 := erp.Clone("clone1")
+	c2 := erp.Clone("clone2")
+
+	if c1.Name != "clone1" || c1.ImportLocator != il || c1.Logger != logger ||
+		c1.Budget != 1000 || len(c1.Sandbox) != 1 || c1.Debugger != nil ||
+		c1.Processor == erp.Processor || c1.Processor == c2.Processor {
+		t.Error("Unexpected result:", c1)
+		return
+	}
+
+	if c := NewECALRuntimeProvider("nosandbox").Clone("c"); c.Sandbox != nil {
+		t.Error("Unexpected result:", c.Sandbox)
+		return
+	}
+
+	il.Files["script.ecal"] = `
+b := 0
+for i in range(1, 10) {
+  b := b + i
+}
+if a == 0 {
+  raise("Failed", "Division by zero")
+}
+result := b / a
+`
+	load := func(erp *ECALRuntimeProvider) *parser.ASTNode {
+		code, _ := il.Resolve("script.ecal")
+
+		ast, err := parser.ParseWithRuntime("script.ecal", code, erp)
+		if err == nil {
+			err = ast.Runtime.Validate()
+		}
+		errorutil.AssertOk(err)
+
+		return ast
+	}
+
+	run := func(erp *ECALRuntimeProvider, ast *parser.ASTNode, a int) (parser.Scope, error) {
+		vs := scope.NewGlobalScope()
+		vs.SetValue("a", float64(a))
+
+		_, err := ast.Runtime.Eval(vs, make(map[string]interface{}), erp.NewThreadID())
+
+		return vs, err
+	}
+
+	// Run the same script concurrently on both clones - one of them fails
+
+	ast1 := load(c1)
+	ast2 := load(c2)
+
+	var vs1, vs2 parser.Scope
+	var err1, err2 error
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		vs1, err1 = run(c1, ast1, 0)
+		wg.Done()
+	}()
+	go func() {
+		vs2, err2 = run(c2, ast2, 5)
+		wg.Done()
+	}()
+
+	wg.Wait()
+
+	if err1 == nil || err1.(*util.RuntimeErrorWithDetail).Source != "clone1 (script.ecal)" {
+		t.Error("Unexpected result:", err1)
+		return
+	}
+
+	if res, _, _ := vs1.GetValue("result"); err2 != nil || res != nil {
+		t.Error("Unexpected result:", res, err2)
+		return
+	}
+
+	if res, _, _ := vs2.GetValue("result"); res != float64(11) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The failed clone can still be used
+
+	if vs, err := run(c1, ast1, 11); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	} else if res, _, _ := vs.GetValue("result"); res != float64(5) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestInspect(t *testing.T) {
+	erp := NewECALRuntimeProvider("test")
+
+	ast, err := parser.ParseWithRuntime("foo.ecal", `
+a := 1
+if a == 1 {
+  b := [1, "x"]
+}
+`, erp)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := ast.Runtime.Inspect(); res != "statementsRuntime@<synthetic statements>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Children[0].Runtime.Inspect(); res != "assignmentRuntime@foo.ecal:2:3" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Children[0].Children[0].Runtime.Inspect(); res != "identifierRuntime@foo.ecal:2:1" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Children[0].Children[1].Runtime.Inspect(); res != "numberValueRuntime@foo.ecal:2:6" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Children[1].Runtime.Inspect(); res != "ifRuntime@foo.ecal:3:1" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Children[1].Children[0].Children[0].Runtime.Inspect(); res != "equalOpRuntime@foo.ecal:3:6" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Synthetic nodes have no location
+
+	n := &parser.ASTNode{Name: parser.NodeSTATEMENTS}
+	n.Runtime = erp.Runtime(n)
+
+	if res := n.Runtime.Inspect(); res != "statementsRuntime@<synthetic statements>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ed := NewECALDebugger(scope.NewGlobalScope()).(*ecalDebugger)
+
+	if res := ed.prettyPrintCallStack([]*parser.ASTNode{ast.Children[0], n}); len(res) != 2 ||
+		res[0] != "a := 1 (foo.ecal:2)" || res[1] != " (statementsRuntime@<synthetic statements>)" {
+		t.Errorf("Unexpected result: %#v", res)
+		return
+	}
+}
@@ -43,6 +43,18 @@ func (rt *identifierRuntime) Eval(vs parser.Scope, is map[string]interface{}, ti
 	_, err := rt.baseRuntime.Eval(vs, is, tid)
 	if err == nil {
 		res, err = rt.resolveValue(vs, is, tid, rt.node)
+
+		if err != nil {
+			switch err.(type) {
+			case *util.RuntimeError, *util.RuntimeErrorWithDetail:
+			default:
+
+				// Errors of the variable scope (e.g. an invalid list index)
+				// are converted into runtime errors
+
+				err = rt.erp.NewRuntimeError(util.ErrVarAccess, err.Error(), rt.node)
+			}
+		}
 	}
 	return res, err
 }
@@ -88,7 +100,7 @@ func (rt *identifierRuntime) resolveValue(vs parser.Scope, is map[string]interfa
 
 		result, _, err = vs.GetValue(node.Token.Val)
 
-	} else if cval, ok := stdlib.GetStdlibConst(astring); ok {
+	} else if cval, ok := stdlib.GetStdlibConst(astring); ok && rt.erp.sandboxAllows(astring) {
 
 		result = cval
 
@@ -149,8 +161,20 @@ func (rt *identifierRuntime) resolveFunction(astring string, vs parser.Scope, is
 					var val interface{}
 
 					if err == nil {
-						val, err = c.Runtime.Eval(vs, make(map[string]interface{}), tid)
-						args = append(args, val)
+
+						// Spread arguments are expanded into individual parameters
+
+						if spread, ok := c.Runtime.(*spreadRuntime); ok {
+							var vals []interface{}
+
+							if vals, err = spread.spreadList(vs, newInstanceState(is), tid); err == nil {
+								args = append(args, vals...)
+							}
+
+						} else {
+							val, err = c.Runtime.Eval(vs, newInstanceState(is), tid)
+							args = append(args, val)
+						}
 					}
 				}
 
@@ -177,9 +201,16 @@ resolveFunctionObject will resolve a given string or object into a concrete ECAL
 func (rt *identifierRuntime) resolveFunctionObject(astring string, result interface{}) (util.ECALFunction, bool) {
 	var funcObj util.ECALFunction
 
-	ok := astring == "log" || astring == "error" || astring == "debug"
+	// The original logging functions cannot be overwritten - warn was added
+	// later and can be overwritten to not break existing code
+
+	inbuildFunc, isInbuild := lookupInbuildFunc(astring)
+	_, ok := inbuildFunc.(*logFunc)
+	ok = ok && astring != "warn"
 
-	if !ok {
+	if ok {
+		funcObj = inbuildFunc
+	} else {
 
 		funcObj, ok = result.(util.ECALFunction)
 
@@ -188,12 +219,13 @@ func (rt *identifierRuntime) resolveFunctionObject(astring string, result interf
 			// Check for stdlib function
 
 			funcObj, ok = stdlib.GetStdlibFunc(astring)
+			ok = ok && rt.erp.sandboxAllows(astring)
 
 			if !ok {
 
 				// Check for inbuild function
 
-				funcObj, ok = InbuildFuncMap[astring]
+				funcObj, ok = inbuildFunc, isInbuild
 			}
 		}
 	}
@@ -210,23 +242,11 @@ func (rt *identifierRuntime) executeFunction(astring string, funcObj util.ECALFu
 	var result interface{}
 	var err error
 
-	if stringutil.IndexOf(astring, []string{"log", "error", "debug"}) != -1 {
-
-		// Convert non-string structures
+	if _, ok := funcObj.(*logFunc); ok {
 
-		for i, a := range args {
-			if _, ok := a.(string); !ok {
-				args[i] = stringutil.ConvertToPrettyString(a)
-			}
-		}
+		// Logging functions are not visible to the debugger
 
-		if astring == "log" {
-			rt.erp.Logger.LogInfo(args...)
-		} else if astring == "error" {
-			rt.erp.Logger.LogError(args...)
-		} else if astring == "debug" {
-			rt.erp.Logger.LogDebug(args...)
-		}
+		result, err = funcObj.Run(rt.instanceID, vs, is, tid, args)
 
 	} else {
 
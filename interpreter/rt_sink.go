@@ -108,6 +108,15 @@ func (rt *sinkRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 					"state": e.State(),
 				})
 
+				if err == nil {
+					err = sinkVS.SetValue("sink", map[interface{}]interface{}{
+						"name":     rule.Name,
+						"priority": float64(rule.Priority),
+						"kind":     rt.toValueList(rule.KindMatch),
+						"scope":    rt.toValueList(rule.ScopeMatch),
+					})
+				}
+
 				if err == nil {
 					scope.SetParentOfScope(sinkVS, vs)
 
@@ -117,22 +126,17 @@ func (rt *sinkRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 							sre.Environment = sinkVS
 
 						} else {
-							var data interface{}
-							rerr := rt.erp.NewRuntimeError(util.ErrSink, err.Error(), rt.node).(*util.RuntimeError)
-
 							if e, ok := err.(*util.RuntimeError); ok {
-								rerr = e
+								err = &util.RuntimeErrorWithDetail{RuntimeError: e, Environment: sinkVS}
 							} else if r, ok := err.(*returnValue); ok {
-								rerr = r.RuntimeError
-								data = r.returnValue
-							}
+								err = &util.RuntimeErrorWithDetail{RuntimeError: r.RuntimeError,
+									Environment: sinkVS, Data: r.returnValue}
+							} else {
 
-							// Provide additional information for unexpected errors
+								// Provide additional information for unexpected errors
 
-							err = &util.RuntimeErrorWithDetail{
-								RuntimeError: rerr,
-								Environment:  sinkVS,
-								Data:         data,
+								err = rt.erp.NewRuntimeErrorBuilder(util.ErrSink, rt.node).
+									Detail(err.Error()).Environment(sinkVS).Build()
 							}
 						}
 					}
@@ -246,6 +250,19 @@ func (rt *sinkRuntime) makeStringList(child *parser.ASTNode, vs parser.Scope,
 	return ret, err
 }
 
+/*
+toValueList converts a list of strings into a list of ECAL values.
+*/
+func (rt *sinkRuntime) toValueList(list []string) []interface{} {
+	ret := make([]interface{}, 0, len(list))
+
+	for _, v := range list {
+		ret = append(ret, v)
+	}
+
+	return ret
+}
+
 // Sink child nodes
 // ================
 
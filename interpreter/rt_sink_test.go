@@ -19,7 +19,7 @@ import (
 
 func TestEventProcessing(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEvalAndAST(
 		`
@@ -317,9 +317,269 @@ rule2 - Handling request: test.event`[1:] {
 	}
 }
 
+func TestSinkSuppression(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.suppress" ],
+    suppresses [ "rule2" ],
+	{
+        log("rule1 - ", event.kind)
+	}
+
+sink rule2
+    kindmatch [ "test.*" ],
+	{
+        log("rule2 - ", event.kind)
+	}
+
+addEventAndWait("myevent", "test.suppress", {})
+addEventAndWait("myevent", "test.other", {})
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// rule2 should only run if rule1 is not triggered by the event
+
+	if testlogger.String() != `
+rule1 - test.suppress
+rule2 - test.other`[1:] {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+}
+
+func TestNestedStateMatch(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.event" ],
+    statematch { "user" : { "role" : "admin" } },
+	{
+        log("rule1 - ", event.state.user.name)
+	}
+
+sink rule2
+    kindmatch [ "test.event" ],
+    statematch { "user" : { "role" : null, "address" : { "city" : null } } },
+	{
+        log("rule2 - ", event.state.user.name)
+	}
+
+addEventAndWait("myevent", "test.event", {
+	"user" : { "name" : "foo", "role" : "admin" }
+})
+addEventAndWait("myevent", "test.event", {
+	"user" : { "name" : "bar", "role" : "guest" }
+})
+addEventAndWait("myevent", "test.event", {
+	"user" : { "name" : "baz", "role" : "guest", "address" : { "city" : "Berlin" } }
+})
+addEventAndWait("myevent", "test.event", {
+	"user" : "admin"
+})
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testlogger.String() != `
+rule1 - foo
+rule2 - baz`[1:] {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+}
+
+func TestScopeMatchWildcard(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.event" ],
+    scopematch [ "data.*" ],
+	{
+        log("rule1 - ", event.name)
+	}
+
+sink rule2
+    kindmatch [ "test.event" ],
+    scopematch [ "data.x.*" ],
+	{
+        log("rule2 - ", event.name)
+	}
+
+addEventAndWait("read", "test.event", {}, { "data.read" : true })
+addEventAndWait("write", "test.event", {}, { "data.write" : true })
+addEventAndWait("other", "test.event", {}, { "other.write" : true })
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testlogger.String() != `
+rule1 - read
+rule1 - write`[1:] {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+}
+
+func TestSinkPriorityExpression(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+a := 5
+sink rule1
+    kindmatch [ "test.event" ],
+    priority a + 1
+	{
+        log("rule1 - ", event.kind)
+	}
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if p := testprocessor.Rules()["rule1"].Priority; p != 6 {
+		t.Error("Unexpected result:", p)
+		return
+	}
+
+	_, err = UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.event" ],
+    priority b + 1
+	{
+        log("rule1 - ", event.kind)
+	}
+`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a number (b=NULL) (Line:4 Pos:14)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestSinkMetadata(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.event", "foo.*" ],
+    scopematch [ "data.read" ],
+    priority 3
+	{
+        log(sink.name, " ", sink.priority, " ", sink.kind[0], " ", sink.kind[1], " ", sink.scope[0])
+	}
+
+addEventAndWait("myevent", "test.event", {}, { "data.read" : true })
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testlogger.String() != `rule1 3 test.event foo.* data.read` {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+}
+
+func TestEventStateSchema(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	_, err := UnitTestEval(
+		`
+sink rule1
+    kindmatch [ "test.event" ],
+	{
+        log("rule1 - ", event.state.name, " ", event.state.count)
+	}
+
+addEventAndWait("myevent", "test.event", {
+	"name" : "foo",
+	"count" : 1,
+	"tags" : ["a"],
+	"data" : {},
+	"flag" : true,
+}, null, {
+	"name" : "string",
+	"count" : "number",
+	"tags" : "list",
+	"data" : "map",
+	"flag" : "bool",
+})
+`, vs)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if testlogger.String() != "rule1 - foo 1" {
+		t.Error("Unexpected result:", testlogger.String())
+		return
+	}
+
+	_, err = UnitTestEval(
+		`addEvent("myevent", "test.event", {"name" : "foo"}, null, {"name" : "string", "count" : "number"})`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error "+
+		"(Event state is missing field count) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(
+		`addEventAndWait("myevent", "test.event", {"name" : 1}, {"": true}, {"name" : "string"})`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error "+
+		"(Event state field name should be of type string) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(
+		`addEvent("myevent", "test.event", {"name" : 1}, null, {"name" : "int"})`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error "+
+		"(Unknown type int in event state schema - must be string, number, bool, list or map) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(
+		`addEvent("myevent", "test.event", {"name" : 1}, null, "foo")`, vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error "+
+		"(Parameter 5 should be a map) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestSinkErrorConditions(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEval(
 		`
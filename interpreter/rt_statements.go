@@ -155,13 +155,14 @@ loopRuntime is the runtime for the loop statement (for).
 type loopRuntime struct {
 	*baseRuntime
 	leftInVarName []string
+	isInfinite    bool // Flag if the loop has a literal true guard
 }
 
 /*
 loopRuntimeInst returns a new runtime component instance.
 */
 func loopRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
-	return &loopRuntime{newBaseRuntime(erp, node), nil}
+	return &loopRuntime{newBaseRuntime(erp, node), nil, false}
 }
 
 /*
@@ -173,6 +174,11 @@ func (rt *loopRuntime) Validate() error {
 
 	if err == nil {
 
+		// A literal true guard never needs to be re-evaluated
+
+		rt.isInfinite = rt.node.Children[0].Name == parser.NodeGUARD &&
+			rt.node.Children[0].Children[0].Name == parser.NodeTRUE
+
 		if rt.node.Children[0].Name == parser.NodeIN {
 
 			inVar := rt.node.Children[0].Children[0]
@@ -198,12 +204,32 @@ func (rt *loopRuntime) Validate() error {
 					rt.leftInVarName = append(rt.leftInVarName, child.Token.Val)
 				}
 			}
+
+			// Functions which are defined in the loop body capture the
+			// values of the loop variables
+
+			rt.registerLoopVars(rt.node.Children[1])
 		}
 	}
 
 	return err
 }
 
+/*
+registerLoopVars registers the loop variables with all functions which are
+defined in a given part of the loop body.
+*/
+func (rt *loopRuntime) registerLoopVars(node *parser.ASTNode) {
+	if frt, ok := node.Runtime.(*funcRuntime); ok {
+		frt.addLoopVars(rt.leftInVarName)
+		return
+	}
+
+	for _, child := range node.Children {
+		rt.registerLoopVars(child)
+	}
+}
+
 /*
 Eval evaluate this runtime component.
 */
@@ -213,6 +239,7 @@ func (rt *loopRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 
 	if err == nil {
 		var guardres interface{}
+		var executed bool
 
 		// Create a new variable scope
 
@@ -220,15 +247,16 @@ func (rt *loopRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 
 		// Create a new instance scope - elements in each loop iteration start from scratch
 
-		is = make(map[string]interface{})
+		is = newInstanceState(is)
 
 		if rt.node.Children[0].Name == parser.NodeGUARD {
 
 			// Evaluate guard
 
-			guardres, err = rt.node.Children[0].Runtime.Eval(vs, is, tid)
+			guardres, err = rt.evalGuard(vs, is, tid)
 
 			for err == nil && guardres.(bool) {
+				executed = true
 
 				// Execute block
 
@@ -248,13 +276,26 @@ func (rt *loopRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 
 					// Evaluate guard
 
-					guardres, err = rt.node.Children[0].Runtime.Eval(vs, is, tid)
+					guardres, err = rt.evalGuard(vs, is, tid)
 				}
 			}
 
+			// Check for break - other end of iteration errors (e.g. from
+			// a closed channel) are passed on
+
+			if isBreak(err) {
+				err = nil
+			}
+
 		} else if rt.node.Children[0].Name == parser.NodeIN {
 
-			err = rt.handleIterator(vs, is, tid)
+			executed, err = rt.handleIterator(vs, is, tid)
+		}
+
+		// Execute the else block if the loop body was never entered
+
+		if err == nil && !executed && len(rt.node.Children) > 2 {
+			_, err = rt.node.Children[2].Runtime.Eval(vs, is, tid)
 		}
 	}
 
@@ -262,10 +303,22 @@ func (rt *loopRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint
 }
 
 /*
-handleIterator handles iterator functions for loops.
+evalGuard evaluates the guard of a conditional loop.
+*/
+func (rt *loopRuntime) evalGuard(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	if rt.isInfinite {
+		return true, nil
+	}
+	return rt.node.Children[0].Runtime.Eval(vs, is, tid)
+}
+
+/*
+handleIterator handles iterator functions for loops. Returns if the loop body
+was executed at least once.
 */
-func (rt *loopRuntime) handleIterator(vs parser.Scope, is map[string]interface{}, tid uint64) error {
+func (rt *loopRuntime) handleIterator(vs parser.Scope, is map[string]interface{}, tid uint64) (bool, error) {
 	var res interface{}
+	var executed bool
 
 	iterator, err := rt.getIterator(vs, is, tid)
 
@@ -276,7 +329,9 @@ func (rt *loopRuntime) handleIterator(vs parser.Scope, is map[string]interface{}
 		if res, err = rt.getIteratorValue(iterator); err == nil {
 
 			if len(vars) == 1 {
-				err = vs.SetValue(vars[0], res)
+				if vars[0] != parser.WildcardName {
+					err = vs.SetValue(vars[0], res)
+				}
 
 			} else if resList, ok := res.([]interface{}); ok {
 
@@ -288,7 +343,7 @@ func (rt *loopRuntime) handleIterator(vs parser.Scope, is map[string]interface{}
 
 				if err == nil {
 					for i, v := range vars {
-						if err == nil {
+						if err == nil && v != parser.WildcardName {
 							err = vs.SetValue(v, resList[i])
 						}
 					}
@@ -300,35 +355,43 @@ func (rt *loopRuntime) handleIterator(vs parser.Scope, is map[string]interface{}
 			}
 
 			if err != nil {
-				return rt.erp.NewRuntimeError(util.ErrRuntimeError,
+				return executed, rt.erp.NewRuntimeError(util.ErrRuntimeError,
 					err.Error(), rt.node)
 			}
 
 			// Execute block
 
+			executed = true
 			_, err = rt.node.Children[1].Runtime.Eval(vs, is, tid)
-		}
 
-		// Check for continue
+			// Check for continue and break - other end of iteration errors
+			// (e.g. from a closed channel) are passed on
 
-		if err != nil {
-			if eoi, ok := err.(*util.RuntimeError); ok {
-				if eoi.Type == util.ErrContinueIteration {
-					err = nil
-				}
+			if eoi, ok := err.(*util.RuntimeError); ok && eoi.Type == util.ErrContinueIteration {
+				err = nil
+			} else if isBreak(err) {
+				return executed, nil
 			}
-		}
-	}
 
-	// Check for end of iteration error
+		} else if eoi, ok := err.(*util.RuntimeError); ok && eoi.Type == util.ErrEndOfIteration {
 
-	if eoi, ok := err.(*util.RuntimeError); ok {
-		if eoi.Type == util.ErrEndOfIteration {
-			err = nil
+			// The iterator has no more values
+
+			return executed, nil
 		}
 	}
 
-	return err
+	return executed, err
+}
+
+/*
+isBreak checks if a given error was produced by a break statement.
+*/
+func isBreak(err error) bool {
+	eoi, ok := err.(*util.RuntimeError)
+
+	return ok && eoi.Type == util.ErrEndOfIteration && eoi.Node != nil &&
+		eoi.Node.Name == parser.NodeBREAK
 }
 
 /*
@@ -388,26 +451,27 @@ func (rt *loopRuntime) getIterator(vs parser.Scope, is map[string]interface{}, t
 			}
 
 		} else if valMap, isMap := val.(map[interface{}]interface{}); isMap {
-			var keys []interface{}
 
-			index := -1
+			iterator = rt.mapIterator(valMap, true)
 
-			for k := range valMap {
-				keys = append(keys, k)
-			}
-			end := len(keys)
+		} else if valMap, isUnsorted := val.(*unsortedMap); isUnsorted {
 
-			// Try to sort according to string value
+			iterator = rt.mapIterator(valMap.m, false)
 
-			sortutil.InterfaceStrings(keys)
+		} else if valString, isString := val.(string); isString {
+
+			// Iterate over the unicode characters of a string
+
+			runes := []rune(valString)
+			index := -1
+			end := len(runes)
 
 			iterator = func() (interface{}, error) {
 				index++
 				if index >= end {
 					return nil, rt.erp.NewRuntimeError(util.ErrEndOfIteration, "", rt.node)
 				}
-				key := keys[index]
-				return []interface{}{key, valMap[key]}, nil
+				return string(runes[index]), nil
 			}
 
 		} else {
@@ -429,6 +493,37 @@ func (rt *loopRuntime) getIterator(vs parser.Scope, is map[string]interface{}, t
 	return iterator, err
 }
 
+/*
+mapIterator creates an iterator object which returns key / value pairs of a map.
+The keys are optionally sorted.
+*/
+func (rt *loopRuntime) mapIterator(valMap map[interface{}]interface{}, sorted bool) func() (interface{}, error) {
+	keys := make([]interface{}, 0, len(valMap))
+
+	index := -1
+
+	for k := range valMap {
+		keys = append(keys, k)
+	}
+	end := len(keys)
+
+	if sorted {
+
+		// Try to sort according to string value
+
+		sortutil.InterfaceStrings(keys)
+	}
+
+	return func() (interface{}, error) {
+		index++
+		if index >= end {
+			return nil, rt.erp.NewRuntimeError(util.ErrEndOfIteration, "", rt.node)
+		}
+		key := keys[index]
+		return []interface{}{key, valMap[key]}, nil
+	}
+}
+
 // Break statement
 // ===============
 
@@ -526,9 +621,15 @@ func (rt *tryRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint6
 
 		res, err = rt.node.Children[0].Runtime.Eval(tvs, is, tid)
 
-		// Evaluate except clauses
+		// Evaluate except clauses - a return is not an error and must be
+		// passed on to the enclosing function. An abort by the debugger
+		// cannot be caught.
+
+		_, isReturn := err.(*returnValue)
+		rtErr, isRuntimeErr := err.(*util.RuntimeError)
+		isAbort := isRuntimeErr && rtErr.Type == util.ErrAborted
 
-		if err != nil {
+		if err != nil && !isReturn && !isAbort {
 			errObj := map[interface{}]interface{}{
 				"type":  "UnexpectedError",
 				"error": err.Error(),
@@ -568,7 +669,7 @@ func (rt *tryRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint6
 				}
 			}
 
-		} else {
+		} else if err == nil {
 
 			// Evaluate otherwise clause
 
@@ -11,8 +11,13 @@
 package interpreter
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/rhedin/Abe_common/errorutil"
+	"github.com/rhedin/Abe_ecal/parser"
 	"github.com/rhedin/Abe_ecal/scope"
 )
 
@@ -20,7 +25,7 @@ func TestGuardStatements(t *testing.T) {
 
 	// Test normal if
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEvalAndAST(
 		`
@@ -69,7 +74,7 @@ GlobalScope {
 
 	// Test elif
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	_, err = UnitTestEvalAndAST(
 		`
@@ -124,7 +129,7 @@ GlobalScope {
 
 	// Test else
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 
 	_, err = UnitTestEvalAndAST(
 		`
@@ -188,7 +193,7 @@ GlobalScope {
 
 func TestLoopStatements(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 	buf := addLogFunction(vs)
 
 	_, err := UnitTestEvalAndAST(
@@ -232,7 +237,7 @@ statements
 	if vs.String() != `
 GlobalScope {
     a (float64) : 0
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:4 Pos:1) {
     }
 }`[1:] {
@@ -255,7 +260,7 @@ Info: -> 1`[1:] {
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -288,7 +293,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:5) {
         a (float64) : 10
     }
@@ -311,7 +316,7 @@ Info->10`[1:] {
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -345,7 +350,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:1) {
         a (float64) : 4
     }
@@ -367,7 +372,7 @@ func TestLoopStatements2(t *testing.T) {
 
 	// Test nested loops
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 	buf := addLogFunction(vs)
 
 	_, err := UnitTestEvalAndAST(
@@ -413,7 +418,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:1) {
         a (float64) : 4
         block: loop (Line:3 Pos:3) {
@@ -441,7 +446,7 @@ Info->4 3`[1:] {
 
 	// Break statement
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -483,7 +488,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:1) {
         a (float64) : 3
         block: if (Line:4 Pos:3) {
@@ -504,7 +509,7 @@ Info->3`[1:] {
 
 	// Continue statement
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -550,7 +555,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:1) {
         a (float64) : 10
         block: if (Line:3 Pos:3) {
@@ -580,7 +585,7 @@ func TestLoopStatements3(t *testing.T) {
 
 	// Loop over lists
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 	buf := addLogFunction(vs)
 
 	_, err := UnitTestEvalAndAST(
@@ -625,7 +630,7 @@ loop
 
 	if vs.String() != `
 GlobalScope {
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:2 Pos:1) {
         a (float64) : 2
         block: loop (Line:3 Pos:3) {
@@ -652,7 +657,7 @@ Info->2-4`[1:] {
 		return
 	}
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -730,7 +735,7 @@ statements
 	if vs.String() != `
 GlobalScope {
     l ([]interface {}) : [1,2,3,4]
-    testlog (*interpreter.TestLogger) : TestLogger
+    testlog (*ecaltest.TestLogger) : TestLogger
     block: loop (Line:3 Pos:1) {
         a (float64) : 3
     }
@@ -758,7 +763,7 @@ Infoxxx>4`[1:] {
 
 	// Loop over a map
 
-	vs = scope.NewScope(scope.GlobalScope)
+	vs = scope.NewGlobalScope()
 	buf = addLogFunction(vs)
 
 	_, err = UnitTestEvalAndAST(
@@ -826,7 +831,7 @@ for [1, b] in x {
 }
 
 func TestLoopStatements4(t *testing.T) {
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	// Test continue
 
@@ -901,11 +906,503 @@ for [a, b] in [[1,2],[3,4],[5,6,7]] {
 		t.Error("Unexpected result:", err)
 		return
 	}
+
+	// Test break in conditional loops
+
+	res, err := UnitTestEval(`
+a := 0
+for true {
+  a := a + 1
+  if a > 3 {
+    break
+  }
+}
+a
+	   `[1:], vs)
+
+	if res != 4. || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Only break ends a conditional loop - other end of iteration errors are
+	// passed on
+
+	_, err = UnitTestEval(`
+c := newChannel(1)
+c.close()
+for true {
+  c.recv()
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): End of iteration was reached (End of iteration was reached) (Line:4 Pos:5)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+c := newChannel(1)
+c.close()
+for i in [1, 2] {
+  c.recv()
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): End of iteration was reached (End of iteration was reached) (Line:4 Pos:5)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+r := []
+for i in range(1, 5) {
+  for j in [1, 2, 3] {
+    if j > i {
+      break
+    }
+    r := add(r, j)
+  }
+  if i > 2 {
+    break
+  }
+}
+r
+	   `[1:], vs)
+
+	if fmt.Sprint(res) != "[1 1 2 1 2 3]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Test character ranges
+
+	res, err = UnitTestEval(`
+r := []
+for c in range("hello") {
+  r := add(r, c)
+}
+for c in range("äö€😀") {
+  r := add(r, c)
+}
+for c in range("") {
+  r := add(r, "empty")
+}
+for a in range(1, 2) {
+  for c in range("ab") {
+    r := add(r, c)
+  }
+}
+r
+	   `[1:], vs)
+
+	if fmt.Sprint(res) != "[h e l l o ä ö € 😀 a b a b]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Test enumerate
+
+	res, err = UnitTestEval(`
+r := []
+for [i, v] in enumerate(["a", "b", "c"]) {
+  r := add(r, [i, v])
+}
+for [i, v] in enumerate([]) {
+  r := add(r, "empty")
+}
+r
+	   `[1:], vs)
+
+	if fmt.Sprint(res) != "[[0 a] [1 b] [2 c]]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+for [i, v] in enumerate("abc") {
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a list) (Line:1 Pos:15)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Test zipIter
+
+	res, err = UnitTestEval(`
+r := []
+for [a, b] in zipIter([1, 2, 3], ["a", "b"]) {
+  r := add(r, [a, b])
+}
+for [a, b, c] in zipIter([1, 2], ["a", "b"], [true, false]) {
+  r := add(r, [a, b, c])
+}
+for [a, b] in zipIter([], [1]) {
+  r := add(r, "empty")
+}
+r
+	   `[1:], vs)
+
+	if fmt.Sprint(res) != "[[1 a] [2 b] [1 a true] [2 b false]]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+for [a, b] in zipIter([1], "abc") {
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 2 should be a list) (Line:1 Pos:15)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+for [a] in zipIter([1]) {
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Need at least two lists as parameters) (Line:1 Pos:12)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Test mapKeys and mapValues
+
+	res, err = UnitTestEval(`
+m := {"b" : 2, "c" : 3, "a" : 1, 1 : 0}
+r := []
+for [k, v] in m {
+  r := add(r, k)
+}
+for k in mapKeys(m) {
+  r := add(r, k)
+}
+for v in mapValues(m) {
+  r := add(r, v)
+}
+e := {}
+for k in mapKeys(e) {
+  r := add(r, "empty")
+}
+for v in mapValues(e) {
+  r := add(r, "empty")
+}
+r
+	   `[1:], vs)
+
+	if fmt.Sprint(res) != "[1 a b c 1 a b c 0 1 2 3]" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+for k in mapKeys([1]) {
+}
+	   `[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a map) (Line:1 Pos:10)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestLoopElseStatements(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	// List with items - else is skipped
+
+	res, err := UnitTestEval(`
+r := []
+for a in [1, 2] {
+  r := add(r, a)
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[1 2]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Empty list - else runs
+
+	res, err = UnitTestEval(`
+r := []
+for a in [] {
+  r := add(r, a)
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[else]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Empty map - else runs
+
+	res, err = UnitTestEval(`
+r := []
+e := {}
+for [k, v] in e {
+  r := add(r, k)
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[else]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Guard which starts false - else runs
+
+	res, err = UnitTestEval(`
+r := []
+i := 5
+for i < 5 {
+  r := add(r, i)
+  i := i + 1
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[else]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Guard which starts true - else is skipped
+
+	res, err = UnitTestEval(`
+r := []
+i := 0
+for i < 2 {
+  r := add(r, i)
+  i := i + 1
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[0 1]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Break after the body was entered - else is skipped
+
+	res, err = UnitTestEval(`
+r := []
+for a in [1, 2] {
+  break
+} else {
+  r := add(r, "else")
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestLoopWildcardStatements(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEval(`
+m := {"b" : 2, "a" : 1}
+keys := []
+values := []
+for [k, _] in m {
+  keys := add(keys, k)
+}
+for [_, v] in m {
+  values := add(values, v)
+}
+count := 0
+for _ in [1, 2, 3] {
+  count := count + 1
+}
+[keys, values, count]
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[[a b] [1 2] 3]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if vsRes := vs.String(); strings.Contains(vsRes, " _ ") || !strings.Contains(vsRes, "block: loop") {
+		t.Error("Unexpected result:", vsRes)
+		return
+	}
+}
+
+func TestLoopUnsortedStatements(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEval(`
+m := {"d" : 4, "b" : 2, "c" : 3, "a" : 1, "e" : 5}
+sorted := []
+for [k, v] in m {
+  sorted := add(sorted, k)
+}
+unsortedKeys := {}
+count := 0
+for [k, v] in unsorted(m) {
+  unsortedKeys[k] := v
+  count := count + 1
+}
+[sorted, unsortedKeys, count]
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[[a b c d e] map[a:1 b:2 c:3 d:4 e:5] 5]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`
+for [k, v] in unsorted([1, 2]) {
+}
+`[1:], vs)
+
+	if err == nil || err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Runtime error (Parameter 1 should be a map) (Line:1 Pos:15)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestLoopStringStatements(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEval(`
+r := []
+for c in "hello" {
+  r := add(r, c)
+}
+r
+`, vs)
+
+	if err != nil || !reflect.DeepEqual(res, []interface{}{"h", "e", "l", "l", "o"}) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+r := []
+for c in "h\u00e9\u4e16\U0001F600" {
+  r := add(r, c)
+}
+r
+`, vs)
+
+	if err != nil || !reflect.DeepEqual(res, []interface{}{"h", "\u00e9", "\u4e16", "\U0001F600"}) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(`
+r := []
+for c in "" {
+  r := add(r, c)
+} else {
+  r := add(r, "empty")
+}
+r
+`, vs)
+
+	if err != nil || !reflect.DeepEqual(res, []interface{}{"empty"}) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestInfiniteLoopStatements(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEval(`
+r := []
+i := 0
+for true {
+  i := i + 1
+  if i == 2 {
+    continue
+  }
+  r := add(r, i)
+  if i >= 4 {
+    break
+  }
+}
+r
+`, vs)
+
+	if err != nil || fmt.Sprint(res) != "[1 3 4]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Only a literal true guard is detected as an infinite loop
+
+	ast, err := parser.ParseWithRuntime("", `for true { break }`, NewECALRuntimeProvider(""))
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	if rt := ast.Runtime.(*loopRuntime); !rt.isInfinite {
+		t.Error("Unexpected result:", rt.isInfinite)
+		return
+	}
+
+	if _, err := ast.Runtime.Eval(scope.NewScope(""), make(map[string]interface{}), 0); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, err = parser.ParseWithRuntime("", `for 1 == 1 { break }`, NewECALRuntimeProvider(""))
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	if rt := ast.Runtime.(*loopRuntime); rt.isInfinite {
+		t.Error("Unexpected result:", rt.isInfinite)
+		return
+	}
+
+	if _, err := ast.Runtime.Eval(scope.NewScope(""), make(map[string]interface{}), 0); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, err = parser.ParseWithRuntime("", `for a in [1] { break }`, NewECALRuntimeProvider(""))
+	errorutil.AssertOk(err)
+	errorutil.AssertOk(ast.Runtime.Validate())
+
+	if rt := ast.Runtime.(*loopRuntime); rt.isInfinite {
+		t.Error("Unexpected result:", rt.isInfinite)
+		return
+	}
+
+	if _, err := ast.Runtime.Eval(scope.NewScope(""), make(map[string]interface{}), 0); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
 }
 
 func TestTryStatements(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEvalAndAST(
 		`
@@ -1037,7 +1534,7 @@ Cleanup`[1:] {
 	_, err = UnitTestEval(
 		`
 try {
-	x := 1 + "a"
+	x := 1 * "a"
 } except {
 	error("This did not work")
 }
@@ -1058,7 +1555,7 @@ error: This did not work`[1:] {
 		`
 try {
 	try {
-		x := 1 + "a"
+		x := 1 * "a"
 	} except e {
 		raise("usererror", "This did not work", e)
 	}
@@ -1122,7 +1619,7 @@ all good`[1:] {
 
 func TestMutexStatements(t *testing.T) {
 
-	vs := scope.NewScope(scope.GlobalScope)
+	vs := scope.NewGlobalScope()
 
 	_, err := UnitTestEvalAndAST(
 		`
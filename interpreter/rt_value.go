@@ -14,9 +14,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_ecal/parser"
 	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 /*
@@ -120,17 +123,83 @@ func (rt *stringValueRuntime) Eval(vs parser.Scope, is map[string]interface{}, t
 	return ret, err
 }
 
+/*
+GetInfix returns the contents between the first start delimiter and its
+matching end delimiter. Nested start and end delimiters are counted so
+the returned contents can contain balanced pairs of delimiters. Delimiters
+inside quoted string literals are ignored.
+*/
 func (rt *stringValueRuntime) GetInfix(str string, start string, end string) (string, bool) {
-	res := str
+	s := strings.Index(str, start)
+
+	if s >= 0 {
+		depth := 0
+
+		for i := s; i < len(str); {
+
+			if depth > 0 {
+				if j := skipQuoted(str, i); j > i {
+					i = j
+					continue
+				}
+			}
+
+			if strings.HasPrefix(str[i:], start) {
+				depth++
+				i += len(start)
+				continue
+			}
+
+			if strings.HasPrefix(str[i:], end) {
+				if depth--; depth == 0 {
+					return str[s+len(start) : i], true
+				}
+				i += len(end)
+				continue
+			}
 
-	if s := strings.Index(str, start); s >= 0 {
-		s += len(start)
-		if e := strings.Index(str, end); e >= 0 {
-			res = str[s:e]
+			i++
 		}
 	}
 
-	return res, res != str
+	return str, false
+}
+
+/*
+skipQuoted returns the index after the quoted string literal which starts at
+the given index. The given index is returned if no complete string literal
+starts at the index (e.g. a quote which follows an identifier).
+*/
+func skipQuoted(str string, i int) int {
+	isIdentChar := func(j int) bool {
+		return j >= 0 && (unicode.IsLetter(rune(str[j])) || unicode.IsDigit(rune(str[j])))
+	}
+
+	start := i
+	escapes := true
+
+	if isIdentChar(i - 1) {
+		return start
+	} else if str[i] == 'r' && i+1 < len(str) && (str[i+1] == '\'' || str[i+1] == '"') {
+		escapes = false
+		i++
+	} else if str[i] == '`' {
+		escapes = false
+	} else if str[i] != '\'' && str[i] != '"' {
+		return start
+	}
+
+	quote := str[i]
+
+	for i++; i < len(str); i++ {
+		if escapes && str[i] == '\\' {
+			i++
+		} else if str[i] == quote {
+			return i + 1
+		}
+	}
+
+	return start
 }
 
 /*
@@ -159,9 +228,34 @@ func (rt *mapValueRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid
 		for _, kvp := range rt.node.Children {
 			var key, val interface{}
 
-			if err == nil {
-				if key, err = kvp.Children[0].Runtime.Eval(vs, is, tid); err == nil {
-					if val, err = kvp.Children[1].Runtime.Eval(vs, is, tid); err == nil {
+			if spread, ok := kvp.Runtime.(*spreadRuntime); ok && err == nil {
+				var sm map[interface{}]interface{}
+
+				// Copy all entries of a spread map - later keys overwrite earlier ones
+
+				if sm, err = spread.spreadMap(vs, is, tid); err == nil {
+					for k, v := range sm {
+						m[k] = v
+					}
+				}
+
+			} else if err == nil {
+				keyNode := kvp.Children[0]
+
+				// A key of the form [expr] is a computed key - the single
+				// expression inside the brackets is evaluated to form the key
+
+				if keyNode.Name == parser.NodeLIST && len(keyNode.Children) == 1 {
+					keyNode = keyNode.Children[0]
+				}
+
+				if key, err = keyNode.Runtime.Eval(vs, is, tid); err == nil {
+					if !isHashableKey(key) {
+						err = rt.erp.NewRuntimeError(util.ErrInvalidState,
+							fmt.Sprintf("Map key must not be a list or map: %v",
+								stringutil.ConvertToString(key)), keyNode)
+
+					} else if val, err = kvp.Children[1].Runtime.Eval(vs, is, tid); err == nil {
 						m[key] = val
 					}
 				}
@@ -172,6 +266,101 @@ func (rt *mapValueRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid
 	return m, err
 }
 
+/*
+isHashableKey checks if a given value can be used as a map key.
+*/
+func isHashableKey(key interface{}) bool {
+	switch key.(type) {
+	case []interface{}, map[interface{}]interface{}:
+		return false
+	}
+	return true
+}
+
+/*
+spreadRuntime is the runtime component for the spread operator.
+*/
+type spreadRuntime struct {
+	*baseRuntime
+}
+
+/*
+spreadRuntimeInst returns a new runtime component instance.
+*/
+func spreadRuntimeInst(erp *ECALRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &spreadRuntime{newBaseRuntime(erp, node)}
+}
+
+/*
+Eval evaluate this runtime component. A spread can only be evaluated as part
+of an enclosing construct which expands its values.
+*/
+func (rt *spreadRuntime) Eval(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	_, err := rt.baseRuntime.Eval(vs, is, tid)
+
+	if err == nil {
+		err = rt.erp.NewRuntimeError(util.ErrInvalidConstruct,
+			"Spread operator can only be used in function call arguments or map values", rt.node)
+	}
+
+	return nil, err
+}
+
+/*
+spreadValue evaluates the operand of the spread operator.
+*/
+func (rt *spreadRuntime) spreadValue(vs parser.Scope, is map[string]interface{}, tid uint64) (interface{}, error) {
+	var val interface{}
+
+	_, err := rt.baseRuntime.Eval(vs, is, tid)
+
+	if err == nil {
+		val, err = rt.node.Children[0].Runtime.Eval(vs, is, tid)
+	}
+
+	return val, err
+}
+
+/*
+spreadList evaluates the operand of the spread operator and returns its items.
+*/
+func (rt *spreadRuntime) spreadList(vs parser.Scope, is map[string]interface{}, tid uint64) ([]interface{}, error) {
+	var res []interface{}
+
+	val, err := rt.spreadValue(vs, is, tid)
+
+	if err == nil {
+		var ok bool
+
+		if res, ok = val.([]interface{}); !ok {
+			err = rt.erp.NewRuntimeError(util.ErrNotAList,
+				fmt.Sprintf("Cannot spread value: %v", stringutil.ConvertToString(val)), rt.node)
+		}
+	}
+
+	return res, err
+}
+
+/*
+spreadMap evaluates the operand of the spread operator and returns its entries.
+*/
+func (rt *spreadRuntime) spreadMap(vs parser.Scope, is map[string]interface{}, tid uint64) (map[interface{}]interface{}, error) {
+	var res map[interface{}]interface{}
+
+	val, err := rt.spreadValue(vs, is, tid)
+
+	if err == nil {
+		var ok bool
+
+		if res, ok = val.(map[interface{}]interface{}); !ok {
+			err = rt.erp.NewRuntimeError(util.ErrNotAMap,
+				fmt.Sprintf("Cannot spread value: %v", stringutil.ConvertToString(val)), rt.node)
+		}
+	}
+
+	return res, err
+}
+
 /*
 listValueRuntime is the runtime component for list values.
 */
@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 func TestSimpleValues(t *testing.T) {
@@ -83,6 +84,36 @@ string: '{{foo'}}test{{'foo'}}test'
 		return
 	}
 
+	res, err = UnitTestEval(
+		"`Foo\\nbar {{1+2}}`", nil)
+
+	if err != nil || res != "Foo\\nbar {{1+2}}" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	vs := scope.NewGlobalScope()
+
+	_, err = UnitTestEval(`
+a := 1
+b := <<END
+a is {{a}}
+  \n
+END
+c := <<'END'
+a is {{a}}
+END`, vs)
+
+	if b, _, _ := vs.GetValue("b"); err != nil || b != "a is 1\n  \\n" {
+		t.Error("Unexpected result: ", b, err)
+		return
+	}
+
+	if c, _, _ := vs.GetValue("c"); c != "a is {{a}}" {
+		t.Error("Unexpected result: ", c)
+		return
+	}
+
 	res, err = UnitTestEval(
 		`b:=1;"test{{a:=1;concat([1,2,3], [4,5], [a,b])}}test"`, nil)
 
@@ -106,6 +137,70 @@ string: '{{foo'}}test{{'foo'}}test'
 		t.Error("Unexpected result: ", res, err)
 		return
 	}
+
+	res, err = UnitTestEval(
+		`"x{{'y{{1+1}}z'}}w"`, nil)
+
+	if err != nil || res != "xy2zw" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"x{{'a}b{c'}}y"`, nil)
+
+	if err != nil || res != "xa}b{cy" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a{{'}}'}}b"`, nil)
+
+	if err != nil || res != "a}}b" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a{{\"x\\\"}}\" + '{{'}}b"`, nil)
+
+	if err != nil || res != "ax\"}}{{b" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a{{r'}}\\' + \"c\"}}b"`, nil)
+
+	if err != nil || res != "a}}\\cb" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a}}b{{1}}c"`, nil)
+
+	if err != nil || res != "a}}b1c" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a{{1"`, nil)
+
+	if err != nil || res != "a{{1" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`"a{{}}b"`, nil)
+
+	if err != nil || res != "a#Parse error in String interpolation: : Unexpected endb" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
 }
 
 func TestCompositionValues(t *testing.T) {
@@ -185,3 +280,225 @@ map
 	}
 
 }
+
+func TestCompositionAccessKeys(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	if _, err := UnitTestEval(`
+l := ["a", "b", "c"]
+m := {"x": 1, 2: "two"}
+`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Number key on list
+
+	res, err := UnitTestEval(
+		`l[2]`, vs)
+
+	if err != nil || res != "c" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// String number key on list
+
+	res, err = UnitTestEval(
+		`l["2"]`, vs)
+
+	if err != nil || res != "c" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Negative string number key on list
+
+	res, err = UnitTestEval(
+		`l["-1"]`, vs)
+
+	if err != nil || res != "c" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// String key on map
+
+	res, err = UnitTestEval(
+		`m["x"]`, vs)
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Field access on map
+
+	res, err = UnitTestEval(
+		`m.x`, vs)
+
+	if err != nil || res != 1. {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// Number key on map
+
+	res, err = UnitTestEval(
+		`m[2]`, vs)
+
+	if err != nil || res != "two" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// String number key on map
+
+	res, err = UnitTestEval(
+		`m["2"]`, vs)
+
+	if err != nil || res != "two" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	_, err = UnitTestEval(`l["abc"]`, vs)
+
+	if rerr, ok := err.(*util.RuntimeError); !ok || rerr.Type != util.ErrVarAccess ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Cannot access variable (List l needs a number index not: abc) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	_, err = UnitTestEval(`l["abc"] := 1`, vs)
+
+	if rerr, ok := err.(*util.RuntimeError); !ok || rerr.Type != util.ErrVarAccess {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := UnitTestEval(`l["1"] := "x"`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("l"); scope.EvalToString(res) != `["a","x","c"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestComputedMapKeys(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	res, err := UnitTestEval(`
+varName := "x"
+a := { [varName]: 42, [1 + 1]: "two", "y": 1 }
+`, vs)
+
+	if err != nil || res != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("a"); scope.EvalToString(res) != `{"2":"two","x":42,"y":1}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("a"); res.(map[interface{}]interface{})["x"] != 42. {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	_, err = UnitTestEval(`a := { [1, 2]: 42 }`, vs)
+
+	if rerr, ok := err.(*util.RuntimeError); !ok || rerr.Type != util.ErrInvalidState ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Invalid state (Map key must not be a list or map: [1,2]) (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestMapSpread(t *testing.T) {
+	vs := scope.NewGlobalScope()
+
+	if _, err := UnitTestEval(`
+a := {"x": 1, "y": 2}
+b := {"y": 3, "z": 4}
+`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := UnitTestEval(
+		`{"x": 1}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"x":1}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`{...a, "extra": 1}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"extra":1,"x":1,"y":2}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`{...a, ...b}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"x":1,"y":3,"z":4}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`{...b, ...a}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"x":1,"y":2,"z":4}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`{...a, "x": 5}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"x":5,"y":2}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = UnitTestEval(
+		`{"x": 5, ...a}`, vs)
+
+	if err != nil || scope.EvalToString(res) != `{"x":1,"y":2}` {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// The spread is a shallow copy
+
+	if _, err := UnitTestEval(`
+c := {...a}
+c.x := 10
+`, vs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, _, _ := vs.GetValue("a"); scope.EvalToString(res) != `{"x":1,"y":2}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	_, err = UnitTestEval(`{...[1, 2]}`, vs)
+
+	if rerr, ok := err.(*util.RuntimeError); !ok || rerr.Type != util.ErrNotAMap ||
+		err.Error() != "ECAL error in ECALTestRuntime (ECALEvalTest): Operand is not a map (Cannot spread value: [1,2]) (Line:1 Pos:2)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rhedin/Abe_ecal/parser"
+	"github.com/rhedin/Abe_ecal/scope"
+	"github.com/rhedin/Abe_ecal/util"
+)
+
+/*
+UnitTestEvalAndASTAndImport evaluates a given ECAL input with a new runtime
+provider which uses the given import locator. If an expected AST is given then
+the parsed AST must match it. If no variable scope is given then a new global
+scope is used. This function is intended for packages which embed the
+interpreter and want to evaluate ECAL code in their own tests.
+*/
+func UnitTestEvalAndASTAndImport(input string, vs parser.Scope, expectedAST string,
+	il util.ECALImportLocator) (interface{}, error) {
+
+	erp := NewECALRuntimeProvider("ECALTestRuntime", WithImportLocator(il))
+
+	return evalTestInput(erp, input, vs, expectedAST, nil)
+}
+
+/*
+evalTestInput parses, validates and evaluates a given ECAL input with a given
+runtime provider. An optional inspect function is called with the parsed AST.
+*/
+func evalTestInput(erp *ECALRuntimeProvider, input string, vs parser.Scope,
+	expectedAST string, inspect func(ast *parser.ASTNode)) (interface{}, error) {
+
+	ast, err := parser.ParseWithRuntime("ECALEvalTest", input, erp)
+	if err != nil {
+		return nil, err
+	}
+
+	if inspect != nil {
+		inspect(ast)
+	}
+
+	if expectedAST != "" && ast.String() != expectedAST {
+		return nil, fmt.Errorf("Unexpected AST result:\n%v", ast.String())
+	}
+
+	// Validate input
+
+	if err := ast.Runtime.Validate(); err != nil {
+		return nil, err
+	}
+
+	if vs == nil {
+		vs = scope.NewGlobalScope()
+	}
+
+	return ast.Runtime.Eval(vs, make(map[string]interface{}), erp.NewThreadID())
+}
+
+/*
+NodeCoverageTracker records which AST node types have been encountered. It can
+be used to check that tests exercise all node types of the interpreter.
+*/
+type NodeCoverageTracker struct {
+	covered map[string]bool
+	lock    *sync.Mutex
+}
+
+/*
+NewNodeCoverageTracker returns a new NodeCoverageTracker.
+*/
+func NewNodeCoverageTracker() *NodeCoverageTracker {
+	return &NodeCoverageTracker{make(map[string]bool), &sync.Mutex{}}
+}
+
+/*
+Track records all node types of a given AST.
+*/
+func (nct *NodeCoverageTracker) Track(ast *parser.ASTNode) {
+	var traverseAST func(n *parser.ASTNode)
+
+	traverseAST = func(n *parser.ASTNode) {
+		nct.covered[n.Name] = true
+		for _, cn := range n.Children {
+			traverseAST(cn)
+		}
+	}
+
+	if ast != nil {
+		nct.lock.Lock()
+		defer nct.lock.Unlock()
+
+		traverseAST(ast)
+	}
+}
+
+/*
+Covered returns all node types which have been recorded so far.
+*/
+func (nct *NodeCoverageTracker) Covered() map[string]bool {
+	nct.lock.Lock()
+	defer nct.lock.Unlock()
+
+	res := make(map[string]bool, len(nct.covered))
+	for n := range nct.covered {
+		res[n] = true
+	}
+
+	return res
+}
+
+/*
+Uncovered returns a sorted list of all node types known to the interpreter
+which have not been recorded so far. End-of-file nodes are never part of an
+AST and are therefore not included.
+*/
+func (nct *NodeCoverageTracker) Uncovered() []string {
+	nct.lock.Lock()
+	defer nct.lock.Unlock()
+
+	var res []string
+
+	for n := range providerMap {
+		if _, ok := nct.covered[n]; !ok && n != parser.NodeEOF {
+			res = append(res, n)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,99 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/rhedin/Abe_ecal/parser"
+)
+
+func TestNodeCoverageTracker(t *testing.T) {
+	nct := NewNodeCoverageTracker()
+
+	if res := nct.Uncovered(); len(res) != len(providerMap)-1 {
+		t.Error("Unexpected result:", len(res), len(providerMap))
+		return
+	}
+
+	nct.Track(nil)
+
+	for _, n := range []string{parser.NodeLOOP, parser.NodeIN,
+		parser.NodeBREAK, parser.NodeCONTINUE} {
+		if nct.Covered()[n] {
+			t.Error("Node should not be covered yet:", n)
+			return
+		}
+	}
+
+	ast, err := parser.Parse("test", `
+for a in range(1, 10) {
+  if a == 3 {
+    continue
+  } elif a == 5 {
+    break
+  }
+}
+`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	nct.Track(ast)
+
+	covered := nct.Covered()
+
+	for _, n := range []string{parser.NodeLOOP, parser.NodeIN,
+		parser.NodeBREAK, parser.NodeCONTINUE} {
+		if !covered[n] {
+			t.Error("Node should be covered:", n)
+			return
+		}
+	}
+
+	for _, n := range nct.Uncovered() {
+		if covered[n] {
+			t.Error("Covered node should not be uncovered:", n)
+			return
+		}
+	}
+
+	if res := nct.Uncovered(); len(res)+len(covered) != len(providerMap)-1 {
+		t.Error("Unexpected result:", res, covered)
+		return
+	}
+
+	// The tracker of the package tests has seen the same nodes after a
+	// loop has been evaluated
+
+	if _, err := UnitTestEval(`
+for a in range(1, 2) {
+  if a == 1 {
+    continue
+  }
+  break
+}
+`, nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	covered = testNodeCoverage.Covered()
+
+	for _, n := range []string{parser.NodeLOOP, parser.NodeIN,
+		parser.NodeBREAK, parser.NodeCONTINUE} {
+		if !covered[n] {
+			t.Error("Node should be covered:", n)
+			return
+		}
+	}
+}
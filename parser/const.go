@@ -191,6 +191,24 @@ const (
 
 	TokenMUTEX
 
+	// Token IDs are part of the JSON AST format - new symbols are appended
+	// here so the IDs of existing tokens do not change
+
+	TOKENodeAPPENDEDSYMBOLS // Used to separate appended symbols from keywords in this list
+
+	// Conditional operators
+
+	TokenNULLCOALESCE
+	TokenTERNARY
+
+	// Spread operator
+
+	TokenSPREAD
+
+	// Assignment statement
+
+	TokenNULLCOALESCEASSIGN
+
 	TokenENDLIST
 )
 
@@ -244,10 +262,20 @@ const (
 	NodeMODINT = "modint"
 	NodeDIVINT = "divint"
 
+	// Conditional operators
+
+	NodeNULLCOALESCE = "??"
+	NodeTERNARY      = "ternary"
+
+	// Spread operator
+
+	NodeSPREAD = "spread"
+
 	// Assignment statement
 
-	NodeASSIGN = ":="
-	NodeLET    = "let"
+	NodeASSIGN             = ":="
+	NodeNULLCOALESCEASSIGN = "??="
+	NodeLET                = "let"
 
 	// Import statement
 
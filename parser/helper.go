@@ -164,6 +164,104 @@ func (n *ASTNode) equalsPath(path string, other *ASTNode, ignoreTokenPosition bo
 	return res, msg
 }
 
+/*
+ASTDiff describes the first found difference between two ASTs.
+*/
+type ASTDiff struct {
+	Path  string      // Path to the differing value e.g. children[2].token.val
+	Left  interface{} // Value in the first AST
+	Right interface{} // Value in the second AST
+}
+
+/*
+String returns a string representation of this difference.
+*/
+func (d *ASTDiff) String() string {
+	return fmt.Sprintf("%v: '%v' != '%v'", d.Path, d.Left, d.Right)
+}
+
+/*
+Diff compares this AST with another AST and returns the first found difference.
+Returns nil if both ASTs are equal.
+*/
+func (n *ASTNode) Diff(other *ASTNode, ignoreTokenPosition bool) *ASTDiff {
+	return n.diffPath("", other, ignoreTokenPosition)
+}
+
+/*
+diffPath compares this AST with another AST while preserving the search path.
+*/
+func (n *ASTNode) diffPath(path string, other *ASTNode, ignoreTokenPosition bool) *ASTDiff {
+	field := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return fmt.Sprintf("%v.%v", path, name)
+	}
+
+	if n == nil || other == nil {
+		if n != other {
+			return &ASTDiff{field("node"), n, other}
+		}
+		return nil
+	}
+
+	if n.Name != other.Name {
+		return &ASTDiff{field("name"), n.Name, other.Name}
+	}
+
+	if (n.Token == nil) != (other.Token == nil) {
+		return &ASTDiff{field("token"), n.Token, other.Token}
+	}
+
+	if n.Token != nil {
+		t1, t2 := n.Token, other.Token
+
+		for _, c := range []struct {
+			name  string
+			left  interface{}
+			right interface{}
+			isPos bool
+		}{
+			{"id", t1.ID, t2.ID, false},
+			{"pos", t1.Pos, t2.Pos, true},
+			{"val", t1.Val, t2.Val, false},
+			{"identifier", t1.Identifier, t2.Identifier, false},
+			{"lline", t1.Lline, t2.Lline, true},
+			{"lpos", t1.Lpos, t2.Lpos, true},
+		} {
+			if (!c.isPos || !ignoreTokenPosition) && c.left != c.right {
+				return &ASTDiff{field("token." + c.name), c.left, c.right}
+			}
+		}
+	}
+
+	if len(n.Meta) != len(other.Meta) {
+		return &ASTDiff{field("meta.length"), len(n.Meta), len(other.Meta)}
+	}
+
+	for i, meta := range n.Meta {
+		if meta.Type() != other.Meta[i].Type() {
+			return &ASTDiff{field(fmt.Sprintf("meta[%v].type", i)), meta.Type(), other.Meta[i].Type()}
+		} else if meta.Value() != other.Meta[i].Value() {
+			return &ASTDiff{field(fmt.Sprintf("meta[%v].value", i)), meta.Value(), other.Meta[i].Value()}
+		}
+	}
+
+	if len(n.Children) != len(other.Children) {
+		return &ASTDiff{field("children.length"), len(n.Children), len(other.Children)}
+	}
+
+	for i, child := range n.Children {
+		if diff := child.diffPath(field(fmt.Sprintf("children[%v]", i)),
+			other.Children[i], ignoreTokenPosition); diff != nil {
+			return diff
+		}
+	}
+
+	return nil
+}
+
 /*
 String returns a string representation of this token.
 */
@@ -222,6 +320,20 @@ func (n *ASTNode) levelString(indent int, buf *bytes.Buffer, printChildren int)
 ToJSONObject returns this ASTNode and all its children as a JSON object.
 */
 func (n *ASTNode) ToJSONObject() map[string]interface{} {
+	return ASTNodeToMap(n)
+}
+
+/*
+ASTNodeToMap returns a given ASTNode and all its children as a JSON object.
+The result can be converted back into an AST with ASTFromJSONObject. Token
+information is only included for nodes which have a token. A nil node
+results in a nil map.
+*/
+func ASTNodeToMap(n *ASTNode) map[string]interface{} {
+	if n == nil {
+		return nil
+	}
+
 	ret := make(map[string]interface{})
 
 	ret["name"] = n.Name
@@ -245,7 +357,7 @@ func (n *ASTNode) ToJSONObject() map[string]interface{} {
 	if lenChildren > 0 {
 		children := make([]map[string]interface{}, lenChildren)
 		for i, child := range n.Children {
-			children[i] = child.ToJSONObject()
+			children[i] = ASTNodeToMap(child)
 		}
 
 		ret["children"] = children
@@ -11,6 +11,8 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -300,6 +302,178 @@ func TestASTNode4(t *testing.T) {
 	}
 }
 
+func TestASTTokenIDs(t *testing.T) {
+
+	// Token IDs are part of the JSON AST format and must not change
+
+	if TokenCOLON != 31 || TokenASSIGN != 39 || TokenLET != 40 || TokenIF != 63 || TokenMUTEX != 73 {
+		t.Error("Unexpected token IDs:", TokenCOLON, TokenASSIGN, TokenLET, TokenIF, TokenMUTEX)
+		return
+	}
+
+	// Load an AST which was written with the previous token IDs
+
+	ast, err := ASTFromJSONObject(map[string]interface{}{
+		"name":  NodeASSIGN,
+		"id":    39,
+		"value": ":=",
+		"children": []map[string]interface{}{
+			{"name": NodeIDENTIFIER, "id": 7, "value": "a"},
+			{"name": NodeNUMBER, "id": 6, "value": "1"},
+		},
+	})
+
+	if err != nil || ast.Token.ID != TokenASSIGN || ast.Token.String() != ":=" {
+		t.Error("Unexpected result: ", ast, err)
+		return
+	}
+
+	if tok := (LexToken{ID: TokenNULLCOALESCEASSIGN, Val: "??="}); tok.String() != "??=" {
+		t.Error("Unexpected result: ", tok.String())
+		return
+	}
+}
+
+func TestASTDiff(t *testing.T) {
+	n, _ := Parse("test1", "a := [1, foo]")
+	n2, _ := Parse("test1", "a := [1, bar]")
+
+	if diff := n.Diff(n, false); diff != nil {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	if diff := n.Diff(n2, false); diff == nil ||
+		diff.String() != "children[1].children[1].token.val: 'foo' != 'bar'" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	n2, _ = Parse("test1", "a :=  [1, foo]")
+
+	if diff := n.Diff(n2, false); diff == nil || diff.String() != "children[1].token.pos: '5' != '6'" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	if diff := n.Diff(n2, true); diff != nil {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	n2, _ = Parse("test1", "a := [1, foo, 2]")
+
+	if diff := n.Diff(n2, false); diff == nil || diff.String() != "children[1].children.length: '2' != '3'" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	n2, _ = Parse("test1", "a := [1, /* x */ foo]")
+
+	if diff := n.Diff(n2, true); diff == nil || diff.String() != "children[1].children[1].meta.length: '0' != '1'" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	n2, _ = Parse("test1", "b ??= 1")
+
+	if diff := n.Diff(n2, false); diff == nil || diff.String() != "name: ':=' != '??='" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	if diff := n.Diff(&ASTNode{Name: ":="}, false); diff == nil || diff.Path != "token" {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+}
+
+func TestASTNodeToMap(t *testing.T) {
+	ast, _ := Parse("mytest", "a := [1, b]")
+
+	// Add a synthetic node without a token
+
+	ast.Children[1].Children = append(ast.Children[1].Children, &ASTNode{Name: "foo"})
+
+	res, err := json.MarshalIndent(ASTNodeToMap(ast), "", "  ")
+
+	if err != nil || string(res) != `{
+  "allowescapes": false,
+  "children": [
+    {
+      "allowescapes": false,
+      "id": 7,
+      "identifier": true,
+      "line": 1,
+      "linepos": 1,
+      "name": "identifier",
+      "pos": 0,
+      "source": "mytest",
+      "value": "a"
+    },
+    {
+      "allowescapes": false,
+      "children": [
+        {
+          "allowescapes": false,
+          "id": 6,
+          "identifier": false,
+          "line": 1,
+          "linepos": 7,
+          "name": "number",
+          "pos": 6,
+          "source": "mytest",
+          "value": "1"
+        },
+        {
+          "allowescapes": false,
+          "id": 7,
+          "identifier": true,
+          "line": 1,
+          "linepos": 10,
+          "name": "identifier",
+          "pos": 9,
+          "source": "mytest",
+          "value": "b"
+        },
+        {
+          "name": "foo"
+        }
+      ],
+      "id": 24,
+      "identifier": false,
+      "line": 1,
+      "linepos": 6,
+      "name": "list",
+      "pos": 5,
+      "source": "mytest",
+      "value": "["
+    }
+  ],
+  "id": 39,
+  "identifier": false,
+  "line": 1,
+  "linepos": 3,
+  "name": ":=",
+  "pos": 2,
+  "source": "mytest",
+  "value": ":="
+}` {
+		t.Error("Unexpected result:", string(res), err)
+		return
+	}
+
+	if fmt.Sprint(ast.ToJSONObject()) != fmt.Sprint(ASTNodeToMap(ast)) {
+		t.Error("Unexpected result:", ast.ToJSONObject())
+		return
+	}
+
+	if res := ASTNodeToMap(nil); res != nil {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func TestLABuffer(t *testing.T) {
 
 	buf := NewLABuffer(Lex("test", "1 2 3 4 5 6 7 8 9"), 3)
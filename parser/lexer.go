@@ -26,11 +26,27 @@ NamePattern is the pattern for valid names.
 */
 var NamePattern = regexp.MustCompile("^[A-Za-z][A-Za-z0-9]*$")
 
+/*
+ParameterPattern is the pattern for positional parameter names (e.g. $1).
+*/
+var ParameterPattern = regexp.MustCompile("^\\$[1-9][0-9]*$")
+
+/*
+WildcardName is the name of the wildcard variable which discards values
+assigned to it in destructuring assignments and loops.
+*/
+const WildcardName = "_"
+
 /*
 numberPattern is a hint pattern for numbers.
 */
 var numberPattern = regexp.MustCompile("^[0-9].*$")
 
+/*
+heredocPattern is the pattern for the start of a heredoc string value.
+*/
+var heredocPattern = regexp.MustCompile("^<<('?)([A-Z][A-Z0-9_]*)('?)")
+
 /*
 LexToken represents a token which is returned by the lexer.
 */
@@ -146,7 +162,7 @@ func (t LexToken) String() string {
 	case t.ID == TokenPOSTCOMMENT:
 		return fmt.Sprintf("# %s", t.Val)
 
-	case t.ID > TOKENodeSYMBOLS && t.ID < TOKENodeKEYWORDS:
+	case t.ID > TOKENodeSYMBOLS && t.ID < TOKENodeKEYWORDS, t.ID > TOKENodeAPPENDEDSYMBOLS:
 		return fmt.Sprintf("%s", strings.ToUpper(t.Val))
 
 	case t.ID > TOKENodeKEYWORDS:
@@ -260,7 +276,7 @@ var KeywordMap = map[string]LexTokenID{
 
 /*
 SymbolMap is a map of special symbols which will always be unique - these will separate unquoted strings
-Symbols can be maximal 2 characters long.
+Symbols can be maximal 3 characters long.
 */
 var SymbolMap = map[string]LexTokenID{
 
@@ -302,9 +318,19 @@ var SymbolMap = map[string]LexTokenID{
 	"//": TokenDIVINT,
 	"%":  TokenMODINT,
 
+	// Conditional operators
+
+	"??": TokenNULLCOALESCE,
+	"?":  TokenTERNARY,
+
+	// Spread operator
+
+	"...": TokenSPREAD,
+
 	// Assignment statement
 
-	":=": TokenASSIGN,
+	":=":  TokenASSIGN,
+	"??=": TokenNULLCOALESCEASSIGN,
 }
 
 // Lexer
@@ -393,6 +419,10 @@ func (l *lexer) next(peek int) rune {
 	pos := l.pos
 	if peek > 0 {
 		pos += peek - 1
+
+		if pos >= len(l.input) {
+			return RuneEOF
+		}
 	}
 
 	r, w := utf8.DecodeRuneInString(l.input[pos:])
@@ -488,7 +518,7 @@ func skipWhiteSpace(l *lexer) bool {
 
 /*
 lexTextBlock lexes a block of text without whitespaces. Interprets
-optionally all one or two letter tokens.
+optionally all one, two or three letter tokens.
 */
 func lexTextBlock(l *lexer, interpretToken bool) {
 
@@ -499,6 +529,12 @@ func lexTextBlock(l *lexer, interpretToken bool) {
 		// Check if we start with a known symbol
 
 		nr := l.next(1)
+		if _, ok := SymbolMap[strings.ToLower(string(r)+string(nr)+string(l.next(2)))]; ok {
+			l.next(0)
+			l.next(0)
+			return
+		}
+
 		if _, ok := SymbolMap[strings.ToLower(string(r)+string(nr))]; ok {
 			l.next(0)
 			return
@@ -587,10 +623,16 @@ func lexToken(l *lexer) lexFunc {
 
 	// Parse strings
 
-	if (n1 == '"' || n1 == '\'') || (n1 == 'r' && (n2 == '"' || n2 == '\'')) {
+	if (n1 == '"' || n1 == '\'' || n1 == '`') || (n1 == 'r' && (n2 == '"' || n2 == '\'')) {
 		return lexValue
 	}
 
+	// Parse heredoc strings
+
+	if n3 := l.next(3); n1 == '<' && n2 == '<' && (n3 == '\'' || unicode.IsUpper(n3)) {
+		return lexHeredoc
+	}
+
 	// Lex a block of text and emit any found tokens
 
 	l.startNew()
@@ -623,7 +665,16 @@ func lexToken(l *lexer) lexFunc {
 
 	token, ok := KeywordMap[keywordCandidate]
 
-	if !ok {
+	if ok && ((token == TokenSINK && l.next(1) == '.') ||
+		(l.start > 0 && l.input[l.start-1] == '.' && !strings.HasSuffix(l.input[:l.start], "..."))) {
+
+		// The sink keyword followed by a dot is the sink variable in a sink
+		// body and keywords after a dot are field names (e.g. sink.priority)
+		// unless the dot is part of a spread operator
+
+		ok = false
+
+	} else if !ok {
 
 		// Check for symbol
 
@@ -638,7 +689,8 @@ func lexToken(l *lexer) lexFunc {
 
 	} else {
 
-		if !NamePattern.MatchString(keywordCandidate) {
+		if !NamePattern.MatchString(keywordCandidate) && !ParameterPattern.MatchString(keywordCandidate) &&
+			keywordCandidate != WildcardName {
 			l.emitError(fmt.Sprintf("Cannot parse identifier '%v'. Identifies may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character", keywordCandidate))
 			return nil
 		}
@@ -661,6 +713,9 @@ Characters are parsed between quotes (escape sequences are interpreted)
 
 r' ... ' or r" ... "
 Characters are parsed plain between quote
+
+` ... `
+Characters are parsed plain between backticks
 */
 func lexValue(l *lexer) lexFunc {
 	var endToken rune
@@ -668,13 +723,17 @@ func lexValue(l *lexer) lexFunc {
 	l.startNew()
 
 	allowEscapes := false
+	rawPrefix := 1
 
 	r := l.next(0)
 
 	// Check if we have a raw quoted string
 
-	if q := l.next(1); r == 'r' && (q == '"' || q == '\'') {
+	if r == '`' {
+		endToken = r
+	} else if q := l.next(1); r == 'r' && (q == '"' || q == '\'') {
 		endToken = q
+		rawPrefix = 2
 		l.next(0)
 	} else {
 		allowEscapes = true
@@ -724,7 +783,7 @@ func lexValue(l *lexer) lexFunc {
 
 	} else {
 
-		l.emitTokenAndValue(TokenSTRING, l.input[l.start+2:l.pos-1], false, false)
+		l.emitTokenAndValue(TokenSTRING, l.input[l.start+rawPrefix:l.pos-1], false, false)
 	}
 
 	//  Set newline
@@ -735,6 +794,88 @@ func lexValue(l *lexer) lexFunc {
 	return lexToken
 }
 
+/*
+lexHeredoc lexes a multiline string value.
+
+Values can be declared in different ways:
+
+<<END
+...
+END
+Characters are parsed plain between the delimiters (inline expressions are interpreted)
+
+<<'END'
+...
+END
+Characters are parsed plain between the delimiters
+
+The delimiter can be any upper case name. The closing delimiter must be on
+its own line. The newline after the opening delimiter and the newline before
+the closing delimiter are not part of the value.
+*/
+func lexHeredoc(l *lexer) lexFunc {
+
+	l.startNew()
+
+	m := heredocPattern.FindStringSubmatch(l.input[l.pos:])
+	if m == nil || m[1] != m[3] {
+		l.emitError("Invalid heredoc delimiter")
+		return nil
+	}
+
+	allowEscapes := m[1] == ""
+	delimiter := m[2]
+	rest := l.input[l.pos+len(m[0]):]
+
+	// Strip the newline after the opening delimiter
+
+	valStart := 0
+	if strings.HasPrefix(rest, "\r\n") {
+		valStart = 2
+	} else if strings.HasPrefix(rest, "\n") {
+		valStart = 1
+	}
+
+	// Search for the closing delimiter at the start of a line
+
+	for nl := strings.Index(rest, "\n"); nl != -1; {
+		e := nl + 1 + len(rest[nl+1:]) - len(strings.TrimLeft(rest[nl+1:], " \t"))
+
+		if strings.HasPrefix(rest[e:], delimiter) {
+			end := e + len(delimiter)
+
+			if end == len(rest) || !(unicode.IsLetter(rune(rest[end])) ||
+				unicode.IsDigit(rune(rest[end])) || rest[end] == '_') {
+
+				val := ""
+				if nl >= valStart {
+					val = strings.TrimSuffix(rest[valStart:nl], "\r")
+				}
+
+				l.emitTokenAndValue(TokenSTRING, val, false, allowEscapes)
+
+				// Set newline
+
+				consumed := l.input[l.pos : l.pos+len(m[0])+end]
+				l.line += strings.Count(consumed, "\n")
+				l.lastnl = l.pos + strings.LastIndex(consumed, "\n") + 1
+				l.pos += len(consumed)
+
+				return lexToken
+			}
+		}
+
+		if next := strings.Index(rest[nl+1:], "\n"); next != -1 {
+			nl += next + 1
+		} else {
+			nl = -1
+		}
+	}
+
+	l.emitError(fmt.Sprintf("Unexpected end while reading heredoc value (missing delimiter %v)", delimiter))
+	return nil
+}
+
 /*
 lexComment lexes comments.
 */
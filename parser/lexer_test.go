@@ -151,6 +151,45 @@ func TestBasicTokenLexing(t *testing.T) {
 		t.Error("Unexpected lexer result:\n  ", res)
 		return
 	}
+
+	input = `$1 + $12`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`["$1" + "$12" EOF]` {
+		t.Error("Unexpected lexer result:\n  ", res)
+		return
+	}
+
+	// Test wildcard variable
+
+	input = `[_, a] := [1, 2]`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`[[ "_" , "a" ] := [ v:"1" , v:"2" ] EOF]` {
+		t.Error("Unexpected lexer result:\n  ", res)
+		return
+	}
+
+	input = `_a`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`[Error: Cannot parse identifier '_a'. Identifies may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character (Line 1, Pos 1) EOF]` {
+		t.Error("Unexpected lexer result:\n  ", res)
+		return
+	}
+
+	// Test spread operator
+
+	input = `add(...a, ...[1], ...true, b.c)`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`["add" ( ... "a" , ... [ v:"1" ] , ... <TRUE> , "b" . "c" ) EOF]` {
+		t.Error("Unexpected lexer result:\n  ", res)
+		return
+	}
+
+	input = `$0`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`[Error: Cannot parse identifier '$0'. Identifies may only contain [a-zA-Z] and [a-zA-Z0-9] from the second character (Line 1, Pos 1) EOF]` {
+		t.Error("Unexpected lexer result:\n  ", res)
+		return
+	}
 }
 
 func TestAssignmentLexing(t *testing.T) {
@@ -254,6 +293,24 @@ func TestStringLexing(t *testing.T) {
 		return
 	}
 
+	input = "name `te\\n{{1}}\n\tst`  'bla'"
+	res = LexToList("mytest", input)
+	if fmt.Sprint(res) != `["name" v:"te\\n{{1}}\n\tst" v:"bla" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	if res[1].AllowEscapes {
+		t.Error("String value should not allow escapes")
+		return
+	}
+
+	input = "name `test"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `["name" Error: Unexpected end while reading string value (unclosed quotes) (Line 1, Pos 6) EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
 	// Parsing with escape sequences
 
 	input = `"test\n\ttest"  '\nfoo\u0028bar' "test{foo}.5w3f"`
@@ -269,6 +326,81 @@ func TestStringLexing(t *testing.T) {
 	}
 }
 
+func TestHeredocLexing(t *testing.T) {
+
+	input := `name := <<END
+foo
+  bar {{1}}
+END
+x := 1`
+	res := LexToList("mytest", input)
+	if fmt.Sprint(res) != `["name" := v:"foo\n  bar {{1}}" "x" := v:"1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	if !res[2].AllowEscapes {
+		t.Error("String value should allow escapes")
+		return
+	}
+
+	if res[3].Lline != 5 || res[3].Lpos != 1 {
+		t.Error("Unexpected token position:", res[3].PosString())
+		return
+	}
+
+	input = `<<'SQL'
+SELECT *
+  FROM x
+  SQL`
+	res = LexToList("mytest", input)
+	if fmt.Sprint(res) != `[v:"SELECT *\n  FROM x" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	if res[0].AllowEscapes {
+		t.Error("String value should not allow escapes")
+		return
+	}
+
+	input = `<<END
+ENDX
+END`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[v:"ENDX" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `<<END
+END`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[v:"" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `<<END
+foo`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[Error: Unexpected end while reading heredoc value (missing delimiter END) (Line 1, Pos 1)]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `<<'END
+foo
+END`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[Error: Invalid heredoc delimiter (Line 1, Pos 1)]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `a<<`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `["a" < < EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+}
+
 func TestCommentLexing(t *testing.T) {
 
 	input := `name /* foo
@@ -346,4 +478,13 @@ suppresses [ "myothersink" ]
 		t.Error("Unexpected lexer result:", res)
 		return
 	}
+
+	// The sink keyword followed by a dot and keywords after a dot are identifiers
+
+	input = `log(sink.name, sink.priority, a.sink)`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `["log" ( "sink" . "name" , "sink" . "priority" , `+
+		`"a" . "sink" ) EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
 }
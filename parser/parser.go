@@ -66,7 +66,7 @@ func init() {
 
 		// Grouping
 
-		TokenCOLON: {NodeKVP, nil, nil, nil, nil, 60, nil, ldInfix},
+		TokenCOLON: {NodeKVP, nil, nil, nil, nil, 60, nil, ldKVP},
 		TokenEQUAL: {NodePRESET, nil, nil, nil, nil, 60, nil, ldInfix},
 
 		// Arithmetic operators
@@ -78,10 +78,20 @@ func init() {
 		TokenDIVINT: {NodeDIVINT, nil, nil, nil, nil, 120, nil, ldInfix},
 		TokenMODINT: {NodeMODINT, nil, nil, nil, nil, 120, nil, ldInfix},
 
+		// Conditional operators
+
+		TokenNULLCOALESCE: {NodeNULLCOALESCE, nil, nil, nil, nil, 25, nil, ldInfix},
+		TokenTERNARY:      {NodeTERNARY, nil, nil, nil, nil, 20, nil, ldTernary},
+
+		// Spread operator
+
+		TokenSPREAD: {NodeSPREAD, nil, nil, nil, nil, 0, ndPrefix, nil},
+
 		// Assignment statement
 
-		TokenASSIGN: {NodeASSIGN, nil, nil, nil, nil, 10, nil, ldInfix},
-		TokenLET:    {NodeLET, nil, nil, nil, nil, 0, ndPrefix, nil},
+		TokenASSIGN:             {NodeASSIGN, nil, nil, nil, nil, 10, nil, ldInfix},
+		TokenNULLCOALESCEASSIGN: {NodeNULLCOALESCEASSIGN, nil, nil, nil, nil, 10, nil, ldInfix},
+		TokenLET:                {NodeLET, nil, nil, nil, nil, 0, ndPrefix, nil},
 
 		// Import statement
 
@@ -94,7 +104,7 @@ func init() {
 		TokenKINDMATCH:  {NodeKINDMATCH, nil, nil, nil, nil, 150, ndPrefix, nil},
 		TokenSCOPEMATCH: {NodeSCOPEMATCH, nil, nil, nil, nil, 150, ndPrefix, nil},
 		TokenSTATEMATCH: {NodeSTATEMATCH, nil, nil, nil, nil, 150, ndPrefix, nil},
-		TokenPRIORITY:   {NodePRIORITY, nil, nil, nil, nil, 150, ndPrefix, nil},
+		TokenPRIORITY:   {NodePRIORITY, nil, nil, nil, nil, 150, ndPriority, nil},
 		TokenSUPPRESSES: {NodeSUPPRESSES, nil, nil, nil, nil, 150, ndPrefix, nil},
 
 		// Function definition
@@ -112,8 +122,8 @@ func init() {
 
 		TokenLIKE:      {NodeLIKE, nil, nil, nil, nil, 60, nil, ldInfix},
 		TokenIN:        {NodeIN, nil, nil, nil, nil, 60, nil, ldInfix},
-		TokenHASPREFIX: {NodeHASPREFIX, nil, nil, nil, nil, 60, nil, ldInfix},
-		TokenHASSUFFIX: {NodeHASSUFFIX, nil, nil, nil, nil, 60, nil, ldInfix},
+		TokenHASPREFIX: {NodeHASPREFIX, nil, nil, nil, nil, 60, ndKeywordIdentifier, ldInfix},
+		TokenHASSUFFIX: {NodeHASSUFFIX, nil, nil, nil, nil, 60, ndKeywordIdentifier, ldInfix},
 		TokenNOTIN:     {NodeNOTIN, nil, nil, nil, nil, 60, nil, ldInfix},
 
 		// Constant terminals
@@ -154,10 +164,71 @@ func init() {
 Parser data structure
 */
 type parser struct {
-	name   string          // Name to identify the input
-	node   *ASTNode        // Current ast node
-	tokens *LABuffer       // Buffer which is connected to the channel which contains lex tokens
-	rp     RuntimeProvider // Runtime provider which creates runtime components
+	name    string          // Name to identify the input
+	node    *ASTNode        // Current ast node
+	tokens  *LABuffer       // Buffer which is connected to the channel which contains lex tokens
+	rp      RuntimeProvider // Runtime provider which creates runtime components
+	opts    *parseOptions   // Parser options
+	depth   int             // Current recursion depth of the parser
+	ternary bool            // Flag if a colon ends the current ternary branch
+}
+
+/*
+parseOptions holds the configuration of a parser.
+*/
+type parseOptions struct {
+	maxRecursionDepth int  // Maximum recursion depth (0 means unlimited)
+	captureComments   bool // Flag if comments should be attached to AST nodes
+	strictMode        bool // Flag if reserved words should be rejected
+}
+
+/*
+ParseOption is an option which configures the parser.
+*/
+type ParseOption func(*parseOptions)
+
+/*
+WithMaxRecursionDepth limits the recursion depth of the parser. Deeply nested
+code which exceeds the limit produces a parser error instead of exhausting the
+stack. A value of 0 means unlimited (default).
+*/
+func WithMaxRecursionDepth(n int) ParseOption {
+	return func(o *parseOptions) {
+		o.maxRecursionDepth = n
+	}
+}
+
+/*
+WithCommentCapture controls if comments are attached as meta data to AST
+nodes (default is true).
+*/
+func WithCommentCapture(capture bool) ParseOption {
+	return func(o *parseOptions) {
+		o.captureComments = capture
+	}
+}
+
+/*
+WithStrictMode controls if the parser should reject words which are reserved
+for future use (default is false). Currently no additional words are reserved.
+*/
+func WithStrictMode(strict bool) ParseOption {
+	return func(o *parseOptions) {
+		o.strictMode = strict
+	}
+}
+
+/*
+newParseOptions creates the parser configuration from a given list of options.
+*/
+func newParseOptions(opts []ParseOption) *parseOptions {
+	res := &parseOptions{0, true, false}
+
+	for _, o := range opts {
+		o(res)
+	}
+
+	return res
 }
 
 /*
@@ -172,10 +243,18 @@ ParseWithRuntime parses a given input string and returns an AST decorated with
 runtime components.
 */
 func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode, error) {
+	return ParseWithRuntimeAndOptions(name, input, rp)
+}
+
+/*
+ParseWithRuntimeAndOptions parses a given input string and returns an AST decorated
+with runtime components. The parser can be configured with a list of options.
+*/
+func ParseWithRuntimeAndOptions(name string, input string, rp RuntimeProvider, opts ...ParseOption) (*ASTNode, error) {
 
 	// Create a new parser with a look-ahead buffer of 3
 
-	p := &parser{name, nil, NewLABuffer(Lex(name, input), 3), rp}
+	p := &parser{name, nil, NewLABuffer(Lex(name, input), 3), rp, newParseOptions(opts), 0, false}
 
 	// Read and set initial AST node
 
@@ -226,6 +305,16 @@ func (p *parser) run(rightBinding int) (*ASTNode, error) {
 
 	n := p.node
 
+	p.depth++
+	defer func() {
+		p.depth--
+	}()
+
+	if p.opts.maxRecursionDepth > 0 && p.depth > p.opts.maxRecursionDepth {
+		return nil, p.newParserError(ErrMaxRecursionDepth,
+			fmt.Sprintf("depth exceeds %v", p.opts.maxRecursionDepth), *n.Token)
+	}
+
 	p.node, err = p.next()
 	if err != nil {
 		return nil, err
@@ -246,7 +335,7 @@ func (p *parser) run(rightBinding int) (*ASTNode, error) {
 	// Collect left denotations as long as the left binding power is greater
 	// than the initial right one
 
-	for rightBinding < p.node.binding {
+	for rightBinding < p.leftBinding(p.node) {
 		var nleft *ASTNode
 
 		n = p.node
@@ -285,6 +374,18 @@ func (p *parser) run(rightBinding int) (*ASTNode, error) {
 	return left, nil
 }
 
+/*
+leftBinding returns the left binding power of a given AST node. A colon does
+not bind while the first branch of a ternary expression is parsed.
+*/
+func (p *parser) leftBinding(n *ASTNode) int {
+	if p.ternary && n.Token != nil && n.Token.ID == TokenCOLON {
+		return 0
+	}
+
+	return n.binding
+}
+
 /*
 next retrieves the next lexer token.
 */
@@ -331,9 +432,11 @@ func (p *parser) next() (*ASTNode, error) {
 
 		ret := node.instance(p, &token)
 
-		ret.Meta = append(ret.Meta, preComments...) // Attach pre comments to the next AST node
-		if len(postComments) > 0 && p.node != nil {
-			p.node.Meta = append(p.node.Meta, postComments...) // Attach post comments to the previous AST node
+		if p.opts.captureComments {
+			ret.Meta = append(ret.Meta, preComments...) // Attach pre comments to the next AST node
+			if len(postComments) > 0 && p.node != nil {
+				p.node.Meta = append(p.node.Meta, postComments...) // Attach post comments to the previous AST node
+			}
 		}
 
 		return ret, nil
@@ -451,6 +554,30 @@ func ndSkink(p *parser, self *ASTNode) (*ASTNode, error) {
 	return ret, err
 }
 
+/*
+ndPriority is used to parse the priority of a sink. The priority can be an
+arithmetic expression.
+*/
+func ndPriority(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// The brace starts the sink body while parsing the priority expression
+
+	nodeMapEntryBak := astNodeMap[TokenLBRACE]
+	astNodeMap[TokenLBRACE] = &ASTNode{"", nil, nil, nil, nil, 0, parseInnerStatements, nil}
+
+	val, err := p.run(100)
+
+	astNodeMap[TokenLBRACE] = nodeMapEntryBak
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.Children = append(self.Children, val)
+
+	return self, nil
+}
+
 /*
 ndFunc is used to parse function definitions.
 */
@@ -616,6 +743,22 @@ func ndIdentifier(p *parser, self *ASTNode) (*ASTNode, error) {
 	return self, parseMore(self)
 }
 
+/*
+ndKeywordIdentifier is used for operator keywords which can also be used as
+identifiers if they start an expression (e.g. the function hasPrefix(a, b)
+and the operator a hasPrefix b).
+*/
+func ndKeywordIdentifier(p *parser, self *ASTNode) (*ASTNode, error) {
+	token := *self.Token
+	token.ID = TokenIDENTIFIER
+	token.Identifier = true
+
+	identifier := astNodeMap[TokenIDENTIFIER].instance(p, &token)
+	identifier.Meta = self.Meta
+
+	return ndIdentifier(p, identifier)
+}
+
 /*
 ndList is used to collect elements of a list.
 */
@@ -662,6 +805,12 @@ func ndMap(p *parser, self *ASTNode) (*ASTNode, error) {
 
 	st := astNodeMap[TokenMAP].instance(p, self.Token)
 
+	// Colons inside a map are always key-value separators (even if the map
+	// is part of the first branch of a ternary expression)
+
+	ternaryBak := p.ternary
+	p.ternary = false
+
 	// Get the inner expression
 
 	for err == nil && IsNotEndAndNotTokens(p, []LexTokenID{TokenRBRACE}) {
@@ -677,6 +826,8 @@ func ndMap(p *parser, self *ASTNode) (*ASTNode, error) {
 		}
 	}
 
+	p.ternary = ternaryBak
+
 	if err == nil {
 		err = skipToken(p, TokenRBRACE)
 	}
@@ -767,6 +918,15 @@ func ndLoop(p *parser, self *ASTNode) (*ASTNode, error) {
 		self.Children = append(self.Children, g)
 
 		_, err = parseInnerStatements(p, self)
+
+		if err == nil && p.node.Token.ID == TokenELSE {
+
+			// Parse else which is executed if the loop body was never entered
+
+			if err = skipToken(p, TokenELSE); err == nil {
+				_, err = parseInnerStatements(p, self)
+			}
+		}
 	}
 
 	return self, err
@@ -877,6 +1037,54 @@ func ldInfix(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
 	return self, nil
 }
 
+/*
+ldKVP is used for key-value pairs. The value binds everything down to a
+ternary expression so e.g. {"a": x ? 1 : 2} produces a key-value pair with
+a ternary value.
+*/
+func ldKVP(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
+
+	right, err := p.run(astNodeMap[TokenTERNARY].binding - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	self.Children = append(self.Children, left)
+	self.Children = append(self.Children, right)
+
+	return self, nil
+}
+
+/*
+ldTernary is used for the ternary conditional expression cond ? a : b.
+*/
+func ldTernary(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
+
+	// The expression of the first branch ends at the colon - the colon
+	// must not bind as key-value pair while parsing the first branch
+
+	ternaryBak := p.ternary
+	p.ternary = true
+
+	trueBranch, err := p.run(0)
+
+	p.ternary = ternaryBak
+
+	if err == nil {
+		if err = skipToken(p, TokenCOLON); err == nil {
+			var falseBranch *ASTNode
+
+			// Nested ternary expressions in the second branch are right associative
+
+			if falseBranch, err = p.run(self.binding - 1); err == nil {
+				self.Children = append(self.Children, left, trueBranch, falseBranch)
+			}
+		}
+	}
+
+	return self, err
+}
+
 // Helper functions
 // ================
 
@@ -12,6 +12,7 @@ package parser
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -232,6 +233,219 @@ or
 	}
 }
 
+func TestConditionalOperatorParsing(t *testing.T) {
+	input := "a ?? b or c ?? 1 + 2"
+	expectedOutput := `
+??
+  ??
+    identifier: a
+    or
+      identifier: b
+      identifier: c
+  plus
+    number: 1
+    number: 2
+`[1:]
+
+	res, err := UnitTestParseWithPPResult("mytest", input, "a ?? b or c ?? 1 + 2")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = "(a ?? b) + 1"
+	expectedOutput = `
+plus
+  ??
+    identifier: a
+    identifier: b
+  number: 1
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "(a ?? b) + 1")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = "a??=b??1"
+	expectedOutput = `
+??=
+  identifier: a
+  ??
+    identifier: b
+    number: 1
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "a ??= b ?? 1")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = "x := a > 1 or b ? c ?? 1 : d?e:f"
+	expectedOutput = `
+:=
+  identifier: x
+  ternary
+    or
+      >
+        identifier: a
+        number: 1
+      identifier: b
+    ??
+      identifier: c
+      number: 1
+    ternary
+      identifier: d
+      identifier: e
+      identifier: f
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "x := a > 1 or b ? c ?? 1 : d ? e : f")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `{"a" : (b == 1 ? {"c" : 1} : [2]), "d" : (e ? 1 : 2) + 1}`
+	expectedOutput = `
+map
+  kvp
+    string: 'a'
+    ternary
+      ==
+        identifier: b
+        number: 1
+      map
+        kvp
+          string: 'c'
+          number: 1
+      list
+        number: 2
+  kvp
+    string: 'd'
+    plus
+      ternary
+        identifier: e
+        number: 1
+        number: 2
+      number: 1
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `a ? {"b" : 1 + 2, c : d} : {e : [f]}`
+	expectedOutput = `
+ternary
+  identifier: a
+  map
+    kvp
+      string: 'b'
+      plus
+        number: 1
+        number: 2
+    kvp
+      identifier: c
+      identifier: d
+  map
+    kvp
+      identifier: e
+      list
+        identifier: f
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `{"a" : b == 1 ? 1 : 2, "c" : d ?? e}`
+	expectedOutput = `
+map
+  kvp
+    string: 'a'
+    ternary
+      ==
+        identifier: b
+        number: 1
+      number: 1
+      number: 2
+  kvp
+    string: 'c'
+    ??
+      identifier: d
+      identifier: e
+`[1:]
+
+	res, err = UnitTestParseWithPPResult("mytest", input, "")
+
+	if err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = "a ? b"
+	if _, err := UnitTestParse("mytest", input); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected end (Line:1 Pos:5)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestConcurrentTernaryParsing(t *testing.T) {
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 20)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if res, err := Parse("mytest", "a ? b : c"); err != nil ||
+					res.Name != NodeTERNARY || len(res.Children) != 3 {
+					errs <- fmt.Errorf("Unexpected ternary result: %v %v", res, err)
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if res, err := Parse("mytest", "{a : b}"); err != nil ||
+					res.Name != NodeMAP || res.Children[0].Name != NodeKVP {
+					errs <- fmt.Errorf("Unexpected map result: %v %v", res, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+		return
+	}
+}
+
 func TestCompositionStructureParsing(t *testing.T) {
 
 	// Assignment of map
@@ -343,4 +557,24 @@ statements
 		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
 		return
 	}
+
+	input = `x := {...a, "b" : 1, ...c.d}`
+	expectedOutput = `
+:=
+  identifier: x
+  map
+    spread
+      identifier: a
+    kvp
+      string: 'b'
+      number: 1
+    spread
+      identifier: c
+        identifier: d
+`[1:]
+
+	if res, err := UnitTestParseWithPPResult("mytest", input, ""); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
 }
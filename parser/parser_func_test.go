@@ -116,6 +116,33 @@ sink
 		return
 	}
 
+	input = `
+	sink mySink
+    kindmatch [ "foo" ],
+	priority a * 2 + 1
+	{
+	}
+`
+	expectedOutput = `
+sink
+  identifier: mySink
+  kindmatch
+    list
+      string: 'foo'
+  priority
+    plus
+      times
+        identifier: a
+        number: 2
+      number: 1
+  statements
+`[1:]
+
+	if res, err := UnitTestParse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
 	input = `
 	sink fooBar
     ==
@@ -315,4 +342,46 @@ statements
 		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
 		return
 	}
+
+	input = `hasPrefix("abc", "a") and b hasSuffix "c" or x(hasSuffix)`
+	expectedOutput = `
+or
+  and
+    identifier: hasPrefix
+      funccall
+        string: 'abc'
+        string: 'a'
+    hassuffix
+      identifier: b
+      string: 'c'
+  identifier: x
+    funccall
+      identifier: hasSuffix
+`[1:]
+
+	if res, err := UnitTestParseWithPPResult("mytest", input,
+		`hasPrefix("abc", "a") and b hassuffix "c" or x(hasSuffix)`); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `add(...[1, 2], ...b, 3, ...true)`
+	expectedOutput = `
+identifier: add
+  funccall
+    spread
+      list
+        number: 1
+        number: 2
+    spread
+      identifier: b
+    number: 3
+    spread
+      true
+`[1:]
+
+	if res, err := UnitTestParseWithPPResult("mytest", input, ""); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
 }
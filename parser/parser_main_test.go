@@ -138,6 +138,43 @@ number: 1 #  foo   foo bar
 	}
 }
 
+func TestParseOptions(t *testing.T) {
+
+	// Comments can be excluded from the AST
+
+	input := `/* This is  a comment */ a := 1 + 1 # foo bar`
+	expectedOutput := `
+:=
+  identifier: a
+  plus
+    number: 1
+    number: 1
+`[1:]
+
+	if res, err := ParseWithRuntimeAndOptions("mytest", input, nil,
+		WithCommentCapture(false)); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Limit the recursion depth
+
+	input = `a := [[[[[1]]]]]`
+
+	if _, err := ParseWithRuntimeAndOptions("mytest", input, nil,
+		WithMaxRecursionDepth(10), WithStrictMode(true)); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ParseWithRuntimeAndOptions("mytest", input, nil,
+		WithMaxRecursionDepth(4)); err == nil || err.Error() !=
+		"Parse error in mytest: Maximum recursion depth exceeded (depth exceeds 4) (Line:1 Pos:9)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestErrorConditions(t *testing.T) {
 
 	input := ``
@@ -168,7 +205,7 @@ func TestErrorConditions(t *testing.T) {
 
 	input = `a := 1 + a`
 
-	p := &parser{"test", nil, NewLABuffer(Lex("test", input), 3), nil}
+	p := &parser{"test", nil, NewLABuffer(Lex("test", input), 3), nil, newParseOptions(nil), 0, false}
 	node, _ := p.next()
 	p.node = node
 
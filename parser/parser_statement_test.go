@@ -294,6 +294,32 @@ for a in == {
 		t.Error(err)
 		return
 	}
+
+	input = `
+for a in b {
+    print(a)
+} else {
+    print(1)
+}`[1:]
+	expectedOutput = `
+loop
+  in
+    identifier: a
+    identifier: b
+  statements
+    identifier: print
+      funccall
+        identifier: a
+  statements
+    identifier: print
+      funccall
+        number: 1
+`[1:]
+
+	if res, err := UnitTestParseWithPPResult("mytest", input, ""); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
 }
 
 func TestConditionalParsing(t *testing.T) {
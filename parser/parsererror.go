@@ -62,4 +62,5 @@ var (
 	ErrImpossibleNullDenotation = errors.New("Term cannot start an expression")
 	ErrImpossibleLeftDenotation = errors.New("Term can only start an expression")
 	ErrUnexpectedToken          = errors.New("Unexpected term")
+	ErrMaxRecursionDepth        = errors.New("Maximum recursion depth exceeded")
 )
@@ -80,10 +80,20 @@ func init() {
 		NodeMODINT + "_2": template.Must(template.New(NodeMODINT).Parse("{{.c1}} % {{.c2}}")),
 		NodeDIVINT + "_2": template.Must(template.New(NodeDIVINT).Parse("{{.c1}} // {{.c2}}")),
 
+		// Conditional operators
+
+		NodeNULLCOALESCE + "_2": template.Must(template.New(NodeNULLCOALESCE).Parse("{{.c1}} ?? {{.c2}}")),
+		NodeTERNARY + "_3":      template.Must(template.New(NodeTERNARY).Parse("{{.c1}} ? {{.c2}} : {{.c3}}")),
+
+		// Spread operator
+
+		NodeSPREAD + "_1": template.Must(template.New(NodeSPREAD).Parse("...{{.c1}}")),
+
 		// Assignment statement
 
-		NodeASSIGN + "_2": template.Must(template.New(NodeASSIGN).Parse("{{.c1}} := {{.c2}}")),
-		NodeLET + "_1":    template.Must(template.New(NodeASSIGN).Parse("let {{.c1}}")),
+		NodeASSIGN + "_2":             template.Must(template.New(NodeASSIGN).Parse("{{.c1}} := {{.c2}}")),
+		NodeNULLCOALESCEASSIGN + "_2": template.Must(template.New(NodeNULLCOALESCEASSIGN).Parse("{{.c1}} ??= {{.c2}}")),
+		NodeLET + "_1":                template.Must(template.New(NodeASSIGN).Parse("let {{.c1}}")),
 
 		// Import statement
 
@@ -135,6 +145,7 @@ func init() {
 		// Loop statement
 
 		NodeLOOP + "_2": template.Must(template.New(NodeLOOP).Parse("for {{.c1}} {\n{{.c2}}}")),
+		NodeLOOP + "_3": template.Must(template.New(NodeLOOP).Parse("for {{.c1}} {\n{{.c2}}} else {\n{{.c3}}}")),
 		NodeBREAK:       template.Must(template.New(NodeBREAK).Parse("break")),
 		NodeCONTINUE:    template.Must(template.New(NodeCONTINUE).Parse("continue")),
 
@@ -155,6 +166,9 @@ func init() {
 		NodeMINUS: true,
 		NodeAND:   true,
 		NodeOR:    true,
+
+		NodeNULLCOALESCE: true,
+		NodeTERNARY:      true,
 	}
 }
 
@@ -269,6 +283,10 @@ func ppPostProcessing(ast *ASTNode, path []*ASTNode, ppString string) string {
 				NodeRETURN,
 				NodeIN,
 				NodeASSIGN,
+				NodeNULLCOALESCEASSIGN,
+				NodeNULLCOALESCE,
+				NodeTERNARY,
+				NodeSPREAD,
 				NodePRESET,
 				NodeKVP,
 				NodeLIST,
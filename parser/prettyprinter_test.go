@@ -647,3 +647,97 @@ a := 2
 		return
 	}
 }
+
+func TestPrettyPrintRoundTrip(t *testing.T) {
+
+	input := `
+import "foo/bar.ecal" as foobar
+
+/*
+Calculate the sum of a list.
+*/
+func sum(l, start=0) {
+  let res := start
+  for i in l {
+    if i > 10 {
+      continue
+    } elif i < 0 {
+      break
+    } else {
+      res := res + i
+    }
+  }
+  return res
+}
+
+sink mysink
+  kindmatch [ "foo.bar.*" ],
+  statematch { "a" : { "b" : 1 } },
+  priority 2 + 3,
+  suppresses [ "othersink" ],
+{
+  try {
+    m := {"a" : [1, 2, {"x" : sum([1, 2, 3])}], "b" : not true and false}
+    log(m.a[2].x, -m.a[1] % 3)
+  } except "MyError" as e {
+    raise("Failed", e)
+  } finally {
+    mutex foo {
+      a := x % 2 == 0 ?? [1]
+    }
+  }
+}
+
+x := func(a) {
+  return a * 2
+}
+for x(1) < 10 {
+  x := 11
+}
+`
+
+	// Comments are normalized by the pretty printer so they are excluded
+	// from the structural comparison
+
+	astres, err := ParseWithRuntimeAndOptions("mytest", input, &DummyRuntimeProvider{},
+		WithCommentCapture(false))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	astresWithComments, err := ParseWithRuntime("mytest", input, &DummyRuntimeProvider{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	markASTNodesAsPrettyPrinted(astresWithComments)
+
+	ppres, err := PrettyPrint(astresWithComments)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	astres2, err := ParseWithRuntimeAndOptions("mytest", ppres, &DummyRuntimeProvider{},
+		WithCommentCapture(false))
+	if err != nil {
+		t.Error("Could not parse pretty printed code:", err, "\n", ppres)
+		return
+	}
+
+	if ok, msg := astres.Equals(astres2, true); !ok {
+		t.Error("Pretty printed code produces a different AST:", msg, "\n", ppres)
+		return
+	}
+
+	// Pretty printing the pretty printed code should not change it anymore
+
+	astres2, _ = ParseWithRuntime("mytest", ppres, &DummyRuntimeProvider{})
+
+	if ppres2, err := PrettyPrint(astres2); err != nil || ppres2 != ppres {
+		t.Error("Unexpected result:", ppres2, err)
+		return
+	}
+}
@@ -42,6 +42,12 @@ type Runtime interface {
 		The thread ID can be used to identify a running process.
 	*/
 	Eval(Scope, map[string]interface{}, uint64) (interface{}, error)
+
+	/*
+	   Inspect returns a short human-readable description of this runtime
+	   component (e.g. assignmentRuntime@foo.ecal:10:5).
+	*/
+	Inspect() string
 }
 
 /*
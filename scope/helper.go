@@ -14,7 +14,12 @@ Package scope contains the block scope implementation for the event condition la
 package scope
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_ecal/parser"
@@ -29,19 +34,117 @@ const (
 )
 
 /*
-NameFromASTNode returns a scope name from a given ASTNode.
+syntheticNodeCounter is used to give scopes of nodes without a token unique names.
+*/
+var syntheticNodeCounter uint64
+
+/*
+NameFromASTNode returns a scope name from a given ASTNode. Synthetic nodes
+without a token get a unique name which is derived from the node name.
 */
 func NameFromASTNode(node *parser.ASTNode) string {
+	if node.Token == nil {
+		return fmt.Sprintf("block: %v (synthetic %d)", node.Name,
+			atomic.AddUint64(&syntheticNodeCounter, 1))
+	}
 	return fmt.Sprintf("block: %v (Line:%d Pos:%d)", node.Name, node.Token.Lline, node.Token.Lpos)
 }
 
+/*
+ecalFunction is the method set of an ECAL function (see util.ECALFunction).
+*/
+type ecalFunction interface {
+	Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error)
+	DocString() (string, error)
+}
+
+/*
+namedFunction is a function which was declared in ECAL code.
+*/
+type namedFunction interface {
+	FunctionName() string
+}
+
 /*
 EvalToString should be used if a value should be converted into a string.
+Numbers are written without a trailing fraction if they are integer-valued,
+strings are quoted and lists and maps are written as JSON with sorted map keys.
+Functions are written as <function> or <userfunction:name> if they were declared
+in ECAL code.
 */
 func EvalToString(v interface{}) string {
+
+	switch val := v.(type) {
+	case nil:
+		return "null"
+
+	case bool:
+		return strconv.FormatBool(val)
+
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+
+	case string:
+		res, _ := json.Marshal(val)
+		return string(res)
+
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, i := range val {
+			items = append(items, EvalToString(i))
+		}
+		return fmt.Sprintf("[%v]", strings.Join(items, ","))
+
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(val))
+		values := make([]string, 0, len(val))
+		for k, mv := range val {
+			keys = append(keys, mapKeyToString(k))
+			values = append(values, EvalToString(mv))
+		}
+
+		// Sort by key and by value if different keys have the same string
+		// representation (e.g. 1 and "1")
+
+		idx := make([]int, len(keys))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.Slice(idx, func(i, j int) bool {
+			if keys[idx[i]] != keys[idx[j]] {
+				return keys[idx[i]] < keys[idx[j]]
+			}
+			return values[idx[i]] < values[idx[j]]
+		})
+
+		items := make([]string, 0, len(keys))
+		for _, i := range idx {
+			items = append(items, fmt.Sprintf("%v:%v", EvalToString(keys[i]), values[i]))
+		}
+
+		return fmt.Sprintf("{%v}", strings.Join(items, ","))
+
+	case namedFunction:
+		return fmt.Sprintf("<userfunction:%v>", val.FunctionName())
+
+	case ecalFunction:
+		return "<function>"
+	}
+
 	return stringutil.ConvertToString(v)
 }
 
+/*
+mapKeyToString converts a map key into the string which is used as key in
+the string representation of a map.
+*/
+func mapKeyToString(k interface{}) string {
+	if ks, ok := k.(string); ok {
+		return ks
+	}
+	return EvalToString(k)
+}
+
 /*
 ToObject converts a Scope into an object.
 */
@@ -54,13 +157,19 @@ func ToObject(vs parser.Scope) map[interface{}]interface{} {
 }
 
 /*
-ToScope converts a given object into a Scope.
+ToScope converts a given object into a Scope. Values are stored as they are
+so ToObject(ToScope(name, o)) is equal to o if all keys of o are strings.
 */
 func ToScope(name string, o map[interface{}]interface{}) parser.Scope {
 	vs := NewScope(name)
+
+	// Keys are stored directly so dotted keys do not address containers
+
+	storage := vs.(*varsScope).storage
 	for k, v := range o {
-		vs.SetValue(fmt.Sprint(k), v)
+		storage[fmt.Sprint(k)] = v
 	}
+
 	return vs
 }
 
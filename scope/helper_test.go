@@ -11,7 +11,10 @@
 package scope
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/rhedin/Abe_ecal/parser"
@@ -24,6 +27,164 @@ func TestNameFromASTNode(t *testing.T) {
 		t.Error("Unexpected result:", res)
 		return
 	}
+
+	n = &parser.ASTNode{Name: "foo"}
+
+	res1 := NameFromASTNode(n)
+	res2 := NameFromASTNode(n)
+
+	if !strings.HasPrefix(res1, "block: foo (synthetic ") || res1 == res2 {
+		t.Error("Unexpected result:", res1, res2)
+		return
+	}
+}
+
+type testFunction struct {
+}
+
+func (f *testFunction) Run(instanceID string, vs parser.Scope, is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (f *testFunction) DocString() (string, error) {
+	return "", nil
+}
+
+type testUserFunction struct {
+	testFunction
+	name string
+}
+
+func (f *testUserFunction) FunctionName() string {
+	return f.name
+}
+
+func TestEvalToString(t *testing.T) {
+
+	if res := EvalToString(nil); res != "null" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(true); res != "true" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(false); res != "false" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(float64(3)); res != "3" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(float64(-12)); res != "-12" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(3.125); res != "3.125" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(float64(1000000)); res != "1000000" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString("foo"); res != `"foo"` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString("a \"b\""); res != `"a \"b\""` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString([]interface{}{}); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString([]interface{}{float64(1), "a", nil}); res != `[1,"a",null]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(map[interface{}]interface{}{}); res != "{}" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(map[interface{}]interface{}{"b": float64(2), "a": true, float64(1): "x"}); res != `{"1":"x","a":true,"b":2}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(&testFunction{}); res != "<function>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(&testUserFunction{name: "foo"}); res != "<userfunction:foo>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EvalToString(5); res != "5" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Lists and maps must be valid JSON
+
+	var obj interface{}
+
+	res := EvalToString([]interface{}{float64(1), map[interface{}]interface{}{"a": []interface{}{float64(2), float64(3)}}, true, nil})
+
+	if res != `[1,{"a":[2,3]},true,null]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := json.Unmarshal([]byte(res), &obj); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	res = EvalToString(map[interface{}]interface{}{nil: "x", true: nil, "a b": float64(1), "a": float64(2)})
+
+	if res != `{"a":2,"a b":1,"null":"x","true":null}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := json.Unmarshal([]byte(res), &obj); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	res = EvalToString(map[interface{}]interface{}{"k\"ey": []interface{}{map[interface{}]interface{}{}}})
+
+	if res != `{"k\"ey":[{}]}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := json.Unmarshal([]byte(res), &obj); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if res := EvalToString(map[interface{}]interface{}{"1": "b", float64(1): "a"}); res != `{"1":"a","1":"b"}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
 }
 
 func TestScopeConversion(t *testing.T) {
@@ -39,6 +200,87 @@ func TestScopeConversion(t *testing.T) {
 		t.Error("Unexpected result:", vs.String(), vs2.String())
 		return
 	}
+
+	// Round trips keep all values and their types
+
+	obj := map[interface{}]interface{}{
+		"int":     1,
+		"int64":   int64(2),
+		"float32": float32(1.5),
+		"float64": float64(3),
+		"bool":    true,
+		"string":  "foo",
+		"nil":     nil,
+		"a.b":     "dotted",
+		"map": map[interface{}]interface{}{
+			"x": map[interface{}]interface{}{"y": []interface{}{1, nil}},
+		},
+		"list": []interface{}{
+			map[interface{}]interface{}{"a": float64(1)},
+			[]interface{}{"b", false},
+		},
+	}
+
+	if res := ToObject(ToScope("foo", obj)); !reflect.DeepEqual(res, obj) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ToObject(ToScope("foo", ToObject(vs))); !reflect.DeepEqual(res, ToObject(vs)) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, ok, _ := ToScope("foo", obj).GetValue("int"); !ok || res != 1 {
+		t.Error("Unexpected result:", res, ok)
+		return
+	}
+
+	if res := ToObject(ToScope("foo", map[interface{}]interface{}{1: "a"})); res["1"] != "a" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestScopeConstructors(t *testing.T) {
+	vs := NewScope("foo")
+
+	vs.SetValue("a", 1.0)
+	vs.SetValue("b", "test")
+	vs.SetValue("c", map[interface{}]interface{}{
+		"d": []interface{}{true, nil},
+	})
+
+	vs2 := NewScopeWithValues("foo", map[string]interface{}{
+		"a": 1.0,
+		"b": "test",
+		"c": map[interface{}]interface{}{
+			"d": []interface{}{true, nil},
+		},
+	})
+
+	if vs.String() != vs2.String() {
+		t.Error("Unexpected result:", vs.String(), vs2.String())
+		return
+	}
+
+	vs3, err := NewScopeFromJSON("foo", `{"a": 1, "b": "test", "c": {"d": [true, null]}}`)
+
+	if err != nil || vs.String() != vs3.String() {
+		t.Error("Unexpected result:", vs3, err)
+		return
+	}
+
+	if c, _, _ := vs3.GetValue("c"); fmt.Sprintf("%T", c) != "map[interface {}]interface {}" {
+		t.Error("Unexpected result:", c)
+		return
+	}
+
+	if _, err := NewScopeFromJSON("foo", `[1, 2]`); err == nil ||
+		err.Error() != "Could not parse JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}" {
+		t.Error("Unexpected result:", err)
+		return
+	}
 }
 
 func TestConvertJSONToECALObject(t *testing.T) {
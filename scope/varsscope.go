@@ -19,6 +19,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/rhedin/Abe_common/errorutil"
 	"github.com/rhedin/Abe_common/stringutil"
 	"github.com/rhedin/Abe_ecal/parser"
 )
@@ -41,11 +42,51 @@ func NewScope(name string) parser.Scope {
 	return NewScopeWithParent(name, nil)
 }
 
+/*
+NewGlobalScope creates a new global variable scope. A global scope is always
+the root of a scope structure.
+*/
+func NewGlobalScope() parser.Scope {
+	return NewScope(GlobalScope)
+}
+
+/*
+NewScopeWithValues creates a new variable scope which contains a given set of values.
+*/
+func NewScopeWithValues(name string, values map[string]interface{}) parser.Scope {
+	vs := NewScope(name)
+	for k, v := range values {
+		vs.SetValue(k, v)
+	}
+	return vs
+}
+
+/*
+NewScopeFromJSON creates a new variable scope from a given JSON object. JSON
+objects are converted into ECAL maps.
+*/
+func NewScopeFromJSON(name string, jsonStr string) (parser.Scope, error) {
+	var values map[string]interface{}
+
+	if err := json.Unmarshal([]byte(jsonStr), &values); err != nil {
+		return nil, fmt.Errorf("Could not parse JSON object: %v", err)
+	}
+
+	for k, v := range values {
+		values[k] = ConvertJSONToECALObject(v)
+	}
+
+	return NewScopeWithValues(name, values), nil
+}
+
 /*
 NewScopeWithParent creates a new variable scope with a parent. This can be
 used to create scope structures without children links.
 */
 func NewScopeWithParent(name string, parent parser.Scope) parser.Scope {
+	errorutil.AssertTrue(parent == nil || name != GlobalScope,
+		"A global scope cannot have a parent scope")
+
 	res := &varsScope{name, nil, nil, make(map[string]interface{}), &sync.RWMutex{}}
 	SetParentOfScope(res, parent)
 	return res
@@ -76,6 +117,8 @@ by the parent scope. This means it should not be used for global scopes with
 many children.
 */
 func (s *varsScope) NewChild(name string) parser.Scope {
+	errorutil.AssertTrue(name != GlobalScope, "A global scope cannot be a child scope")
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -172,15 +215,8 @@ func (s *varsScope) setValue(varName string, varValue interface{}) error {
 
 					if index, err = strconv.Atoi(fieldIndex); err == nil {
 
-						if index < 0 {
-
-							// Handle negative numbers
-
-							index = len(listContainer) + index
-						}
-
-						if index < len(listContainer) {
-							listContainer[index] = varValue
+						if listIndex, ok := listContainerIndex(listContainer, index); ok {
+							listContainer[listIndex] = varValue
 						} else {
 							err = fmt.Errorf("Out of bounds access to list %v with index: %v",
 								strings.Join(cFields[:len(cFields)-1], "."), index)
@@ -215,6 +251,22 @@ func (s *varsScope) setValue(varName string, varValue interface{}) error {
 	return err
 }
 
+/*
+listContainerIndex returns the position in a given list for a given index.
+Negative indices count from the end of the list. Returns false if the index
+is out of bounds.
+*/
+func listContainerIndex(listContainer []interface{}, index int) (int, bool) {
+	if index < 0 {
+
+		// Handle negative numbers
+
+		index = len(listContainer) + index
+	}
+
+	return index, index >= 0 && index < len(listContainer)
+}
+
 /*
 containerAccess recursively accesses a field in a container structure.
 */
@@ -235,15 +287,8 @@ func (s *varsScope) containerAccess(container interface{}, cFields []string, fie
 
 		if index, err = strconv.Atoi(fmt.Sprint(fields[0])); err == nil {
 
-			if index < 0 {
-
-				// Handle negative numbers
-
-				index = len(listContainer) + index
-			}
-
-			if index < len(listContainer) {
-				container = listContainer[index]
+			if listIndex, ok := listContainerIndex(listContainer, index); ok {
+				container = listContainer[listIndex]
 			} else {
 				err = fmt.Errorf("Out of bounds access to list %v with index: %v",
 					strings.Join(cFields[:len(cFields)-len(fields)], "."), index)
@@ -338,15 +383,8 @@ func (s *varsScope) getValue(varName string) (interface{}, bool, error) {
 
 				if index, err = strconv.Atoi(fmt.Sprint(fields[0])); err == nil {
 
-					if index < 0 {
-
-						// Handle negative numbers
-
-						index = len(listContainer) + index
-					}
-
-					if index < len(listContainer) {
-						retContainer = listContainer[index]
+					if listIndex, ok := listContainerIndex(listContainer, index); ok {
+						retContainer = listContainer[listIndex]
 					} else {
 						err = fmt.Errorf("Out of bounds access to list %v with index: %v",
 							strings.Join(cFields[:len(cFields)-len(fields)], "."), index)
@@ -471,7 +509,7 @@ func (s *varsScope) scopeString(childrenString string) string {
 
 	for _, v := range varList {
 		buf.WriteString(fmt.Sprintf("    %s (%T) : %v\n", v, s.storage[v],
-			EvalToString(s.storage[v])))
+			stringutil.ConvertToString(s.storage[v])))
 	}
 
 	if childrenString != "" {
@@ -184,6 +184,28 @@ func testVarScopeSetMapErrors(t *testing.T, parentVS parser.Scope) {
 		t.Error("Unexpected result:", parentVS.String(), err)
 		return
 	}
+
+	// Negative indices beyond the list length are out of bounds
+
+	err = parentVS.SetValue("xx.-9", 1)
+
+	if err.Error() != "Out of bounds access to list xx with index: -9" {
+		t.Error("Unexpected result:", parentVS.String(), err)
+		return
+	}
+
+	err = parentVS.SetValue("xx.-9.1", 1)
+
+	if err.Error() != "Out of bounds access to list xx with index: -9" {
+		t.Error("Unexpected result:", parentVS.String(), err)
+		return
+	}
+
+	if res, ok, err := parentVS.GetValue("xx.-9"); res != nil || ok ||
+		err.Error() != "Out of bounds access to list xx with index: -9" {
+		t.Error("Unexpected result:", res, ok, err)
+		return
+	}
 }
 
 func TestVarScopeGet(t *testing.T) {
@@ -272,6 +294,40 @@ func TestVarScopeGet(t *testing.T) {
 	}
 }
 
+func TestGlobalScope(t *testing.T) {
+	vs := NewGlobalScope()
+	vs.SetValue("a", 1)
+
+	if res := vs.String(); res != `GlobalScope {
+    a (int) : 1
+}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := vs.NewChild("foo").Parent(); res != vs {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	assertPanic := func(f func(), expected string) {
+		defer func() {
+			if r := recover(); r == nil || fmt.Sprint(r) != expected {
+				t.Error("Unexpected result:", r)
+			}
+		}()
+		f()
+	}
+
+	assertPanic(func() {
+		vs.NewChild(GlobalScope)
+	}, "A global scope cannot be a child scope")
+
+	assertPanic(func() {
+		NewScopeWithParent(GlobalScope, vs)
+	}, "A global scope cannot have a parent scope")
+}
+
 func TestVarScopeDump(t *testing.T) {
 
 	// Build a small tree of VS
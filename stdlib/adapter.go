@@ -56,29 +56,12 @@ func (ea *ECALFunctionAdapter) Run(instanceID string, vs parser.Scope,
 				len(args), funcType.NumIn())
 		}
 
-		expectedType := funcType.In(i)
-
-		// Try to convert into correct number types
-
-		if float64Arg, ok := arg.(float64); ok {
-			arg = ea.convertNumber(arg, float64Arg, expectedType)
+		fval, err := ea.convertArg(i, arg, funcType.In(i))
+		if err != nil {
+			return nil, err
 		}
 
-		givenType := reflect.TypeOf(arg)
-
-		// Check that the right types were given
-
-		if givenType != expectedType &&
-			!(expectedType.Kind() == reflect.Interface &&
-				givenType.Kind() == reflect.Interface &&
-				givenType.Implements(expectedType)) &&
-			expectedType != reflect.TypeOf([]interface{}{}) {
-
-			return nil, fmt.Errorf("Parameter %v should be of type %v but is of type %v",
-				i+1, expectedType, givenType)
-		}
-
-		fargs = append(fargs, reflect.ValueOf(arg))
+		fargs = append(fargs, fval)
 	}
 
 	// Call the function
@@ -107,6 +90,18 @@ func (ea *ECALFunctionAdapter) Run(instanceID string, vs parser.Scope,
 			}
 		}
 
+		// Dereference pointers to primitive types
+
+		if v.Kind() == reflect.Ptr && isPrimitiveKind(v.Type().Elem().Kind()) {
+			if v.IsNil() {
+				results = append(results, nil)
+				continue
+			}
+
+			v = v.Elem()
+			res = v.Interface()
+		}
+
 		// Convert result if it is a primitive type
 
 		results = append(results, ea.convertResultNumber(res, v))
@@ -114,15 +109,83 @@ func (ea *ECALFunctionAdapter) Run(instanceID string, vs parser.Scope,
 
 	ret = results
 
-	// Return a single value if results contains only a single item
+	// Return a single value if results contains only a single item and
+	// nothing if the function has no results
 
 	if len(results) == 1 {
 		ret = results[0]
+	} else if len(results) == 0 {
+		ret = nil
 	}
 
 	return ret, err
 }
 
+/*
+convertArg converts a given argument into a value of the expected type.
+*/
+func (ea *ECALFunctionAdapter) convertArg(i int, arg interface{}, expectedType reflect.Type) (reflect.Value, error) {
+	targetType := expectedType
+
+	// Pointers to primitive types are created automatically
+
+	isPtr := expectedType.Kind() == reflect.Ptr && isPrimitiveKind(expectedType.Elem().Kind())
+
+	if isPtr {
+		if arg == nil {
+			return reflect.Zero(expectedType), nil
+		}
+		targetType = expectedType.Elem()
+	}
+
+	// Try to convert into correct number types
+
+	if float64Arg, ok := arg.(float64); ok {
+		arg = ea.convertNumber(arg, float64Arg, targetType)
+
+	} else if argVal := reflect.ValueOf(arg); arg != nil &&
+		isNumberKind(argVal.Kind()) && isNumberKind(targetType.Kind()) {
+
+		arg = argVal.Convert(targetType).Interface()
+	}
+
+	givenType := reflect.TypeOf(arg)
+
+	if isPtr && givenType == targetType {
+		ptr := reflect.New(targetType)
+		ptr.Elem().Set(reflect.ValueOf(arg))
+		return ptr, nil
+	}
+
+	// Check that the right types were given
+
+	if givenType != expectedType &&
+		!(expectedType.Kind() == reflect.Interface &&
+			givenType.Kind() == reflect.Interface &&
+			givenType.Implements(expectedType)) &&
+		expectedType != reflect.TypeOf([]interface{}{}) {
+
+		return reflect.Value{}, fmt.Errorf("Parameter %v should be of type %v but is of type %v",
+			i+1, expectedType, givenType)
+	}
+
+	return reflect.ValueOf(arg), nil
+}
+
+/*
+isNumberKind checks if a given kind is a number kind.
+*/
+func isNumberKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Uintptr) || k == reflect.Float32 || k == reflect.Float64
+}
+
+/*
+isPrimitiveKind checks if a given kind is a number, string or boolean kind.
+*/
+func isPrimitiveKind(k reflect.Kind) bool {
+	return isNumberKind(k) || k == reflect.String || k == reflect.Bool
+}
+
 /*
 convertNumber converts number arguments into the right type.
 */
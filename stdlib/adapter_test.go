@@ -202,6 +202,187 @@ func TestECALFunctionAdapterSimple2(t *testing.T) {
 	}
 }
 
+func TestECALFunctionAdapterReturnPatterns(t *testing.T) {
+	testErr := fmt.Errorf("testerror")
+
+	res, err := runAdapterTest(
+		reflect.ValueOf(func(a int) (int, error) { return a + 1, nil }),
+		[]interface{}{float64(1)},
+	)
+
+	if res != float64(2) || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int) (int, error) { return 0, testErr }),
+		[]interface{}{float64(1)},
+	)
+
+	if fmt.Sprint(res) != "0" || err != testErr {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int) (string, int64, error) { return "a", 1, nil }),
+		[]interface{}{float64(1)},
+	)
+
+	if fmt.Sprint(res) != "[a 1]" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a uint64) uint { return uint(a) * 2 }),
+		[]interface{}{float64(2)},
+	)
+
+	if fmt.Sprint(res) != "4" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int64) error { return nil }),
+		[]interface{}{float64(1)},
+	)
+
+	if res != nil || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int64) error { return testErr }),
+		[]interface{}{float64(1)},
+	)
+
+	if res != nil || err != testErr {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func() {}),
+		[]interface{}{},
+	)
+
+	if res != nil || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int64) int64 { return a }),
+		[]interface{}{int(3)},
+	)
+
+	if fmt.Sprint(res) != "3" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a uint) float64 { return float64(a) }),
+		[]interface{}{int64(3)},
+	)
+
+	if fmt.Sprint(res) != "3" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	// No results should give nil instead of an empty list
+
+	if res, err = runAdapterTest(reflect.ValueOf(func() {}), nil); res != nil || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
+func TestECALFunctionAdapterPointers(t *testing.T) {
+	s := "foo"
+
+	res, err := runAdapterTest(
+		reflect.ValueOf(func(a *string) string { return *a + "bar" }),
+		[]interface{}{"foo"},
+	)
+
+	if res != "foobar" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a *int) int { return *a + 1 }),
+		[]interface{}{float64(1)},
+	)
+
+	if res != float64(2) || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a *float64) float64 { return *a * 2 }),
+		[]interface{}{float64(1.5)},
+	)
+
+	if res != float64(3) || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a *int) bool { return a == nil }),
+		[]interface{}{nil},
+	)
+
+	if res != true || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func() *string { return &s }),
+		nil,
+	)
+
+	if res != "foo" || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func() *string { return nil }),
+		nil,
+	)
+
+	if res != nil || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = runAdapterTest(
+		reflect.ValueOf(func(a int) *int { return &a }),
+		[]interface{}{float64(5)},
+	)
+
+	if res != float64(5) || err != nil {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if res, err = runAdapterTest(reflect.ValueOf(func(a *int) int { return *a }),
+		[]interface{}{"foo"}); err == nil || err.Error() != "Parameter 1 should be of type *int but is of type string" {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+}
+
 func TestECALFunctionAdapterErrors(t *testing.T) {
 
 	// Test Error cases
@@ -15,6 +15,7 @@ import (
 	"plugin"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/rhedin/Abe_ecal/util"
 )
@@ -29,6 +30,18 @@ internalStdlibDocMap holds the docstrings for all registered functions
 */
 var internalStdlibDocMap = make(map[string]string)
 
+/*
+genStdlibLock protects genStdlib and the internal stdlib maps from concurrent
+modifications.
+*/
+var genStdlibLock = &sync.RWMutex{}
+
+/*
+registeredPackages holds the names of all packages which were added with
+RegisterPackage - only these can be removed again.
+*/
+var registeredPackages = make(map[string]bool)
+
 /*
 pluginLookup is an interface for required function of the plugin object - only used for unit testing.
 */
@@ -46,10 +59,10 @@ AddStdlibPkg adds a package to stdlib. A package needs to be added before functi
 can be added.
 */
 func AddStdlibPkg(pkg string, docstring string) error {
-	_, ok1 := GetPkgDocString(pkg)
-	_, ok2 := internalStdlibDocMap[pkg]
+	genStdlibLock.Lock()
+	defer genStdlibLock.Unlock()
 
-	if ok1 || ok2 {
+	if pkgExists(pkg) {
 		return fmt.Errorf("Package %v already exists", pkg)
 	}
 
@@ -62,10 +75,10 @@ func AddStdlibPkg(pkg string, docstring string) error {
 AddStdlibFunc adds a function to stdlib.
 */
 func AddStdlibFunc(pkg string, name string, funcObj util.ECALFunction) error {
-	_, ok1 := GetPkgDocString(pkg)
-	_, ok2 := internalStdlibDocMap[pkg]
+	genStdlibLock.Lock()
+	defer genStdlibLock.Unlock()
 
-	if !ok1 && !ok2 {
+	if !pkgExists(pkg) {
 		return fmt.Errorf("Package %v does not exist", pkg)
 	}
 
@@ -74,6 +87,74 @@ func AddStdlibFunc(pkg string, name string, funcObj util.ECALFunction) error {
 	return nil
 }
 
+/*
+pkgExists checks if a given package exists in stdlib. The caller must hold
+genStdlibLock.
+*/
+func pkgExists(pkg string) bool {
+	_, ok1 := genStdlib[fmt.Sprintf("%v-synopsis", pkg)]
+	_, ok2 := internalStdlibDocMap[pkg]
+
+	return ok1 || ok2
+}
+
+/*
+RegisterPackage registers a complete package with functions and constants in
+stdlib. The package name must not conflict with an existing package.
+*/
+func RegisterPackage(name string, synopsis string, funcs map[string]util.ECALFunction,
+	consts map[string]interface{}) error {
+
+	if name == "" || strings.Contains(name, ".") {
+		return fmt.Errorf("Invalid package name: %v", name)
+	}
+
+	genStdlibLock.Lock()
+	defer genStdlibLock.Unlock()
+
+	if pkgExists(name) {
+		return fmt.Errorf("Package %v already exists", name)
+	}
+
+	funcMap := make(map[interface{}]interface{})
+	for k, v := range funcs {
+		funcMap[k] = v
+	}
+
+	constMap := make(map[interface{}]interface{})
+	for k, v := range consts {
+		constMap[k] = v
+	}
+
+	genStdlib[fmt.Sprintf("%v-synopsis", name)] = synopsis
+	genStdlib[fmt.Sprintf("%v-func", name)] = funcMap
+	genStdlib[fmt.Sprintf("%v-const", name)] = constMap
+
+	registeredPackages[name] = true
+
+	return nil
+}
+
+/*
+UnregisterPackage removes a package with all its functions and constants from
+stdlib. Only packages which were added with RegisterPackage can be removed.
+*/
+func UnregisterPackage(name string) error {
+	genStdlibLock.Lock()
+	defer genStdlibLock.Unlock()
+
+	if !registeredPackages[name] {
+		return fmt.Errorf("Package %v was not registered", name)
+	}
+
+	delete(registeredPackages, name)
+	delete(genStdlib, fmt.Sprintf("%v-synopsis", name))
+	delete(genStdlib, fmt.Sprintf("%v-func", name))
+	delete(genStdlib, fmt.Sprintf("%v-const", name))
+
+	return nil
+}
+
 /*
 LoadStdlibPlugins attempts to load stdlib functions from a given list of definitions.
 */
@@ -168,6 +249,9 @@ func GetStdlibSymbols() ([]string, []string, []string) {
 		return ret
 	}
 
+	genStdlibLock.RLock()
+	defer genStdlibLock.RUnlock()
+
 	for k, v := range genStdlib {
 		sym := fmt.Sprint(k)
 
@@ -200,6 +284,9 @@ func GetStdlibConst(name string) (interface{}, bool) {
 	var res interface{}
 	var resok bool
 
+	genStdlibLock.RLock()
+	defer genStdlibLock.RUnlock()
+
 	if m, n := splitModuleAndName(name); n != "" {
 		if cmap, ok := genStdlib[fmt.Sprintf("%v-const", m)]; ok {
 			res, resok = cmap.(map[interface{}]interface{})[n]
@@ -216,6 +303,9 @@ func GetStdlibFunc(name string) (util.ECALFunction, bool) {
 	var res util.ECALFunction
 	var resok bool
 
+	genStdlibLock.RLock()
+	defer genStdlibLock.RUnlock()
+
 	if m, n := splitModuleAndName(name); n != "" {
 		if fmap, ok := genStdlib[fmt.Sprintf("%v-func", m)]; ok {
 			if fn, ok := fmap.(map[interface{}]interface{})[n]; ok {
@@ -237,6 +327,10 @@ GetPkgDocString returns the docstring of a stdlib package.
 */
 func GetPkgDocString(name string) (string, bool) {
 	var res string
+
+	genStdlibLock.RLock()
+	defer genStdlibLock.RUnlock()
+
 	s, ok := genStdlib[fmt.Sprintf("%v-synopsis", name)]
 	if ok {
 		res = fmt.Sprint(s)
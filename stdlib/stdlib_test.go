@@ -16,7 +16,11 @@ import (
 	"plugin"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/rhedin/Abe_common/stringutil"
+	"github.com/rhedin/Abe_ecal/util"
 )
 
 func TestGetPkgDocString(t *testing.T) {
@@ -130,6 +134,136 @@ func TestAddStdLibFunc(t *testing.T) {
 		t.Error("Unexpected error:", err)
 		return
 	}
+
+	// Add and lookup packages and functions concurrently
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("concurrentAddPkg%v", i)
+			AddStdlibPkg(name, "")
+			AddStdlibFunc(name, "myfunc", dummyFunc)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("concurrentRegPkg%v", i)
+			RegisterPackage(name, "", nil, nil)
+			GetPkgDocString(fmt.Sprintf("concurrentAddPkg%v", i))
+			GetStdlibFunc(fmt.Sprintf("concurrentAddPkg%v.myfunc", i))
+			UnregisterPackage(name)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if f, _ := GetStdlibFunc("concurrentAddPkg3.myfunc"); f != dummyFunc {
+		t.Error("Unexpected result:", f)
+		return
+	}
+}
+
+func TestRegisterPackage(t *testing.T) {
+	dummyFunc := &ECALFunctionAdapter{}
+
+	if err := RegisterPackage("customPkg", "Custom package",
+		map[string]util.ECALFunction{"myfunc": dummyFunc},
+		map[string]interface{}{"myconst": 42}); err != nil {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	packageNames, constSymbols, funcSymbols := GetStdlibSymbols()
+
+	if stringutil.IndexOf("customPkg", packageNames) == -1 ||
+		stringutil.IndexOf("customPkg.myconst", constSymbols) == -1 ||
+		stringutil.IndexOf("customPkg.myfunc", funcSymbols) == -1 {
+		t.Error("Unexpected result:", packageNames, constSymbols, funcSymbols)
+		return
+	}
+
+	if d, _ := GetPkgDocString("customPkg"); d != "Custom package" {
+		t.Error("Unexpected result:", d)
+		return
+	}
+
+	if f, _ := GetStdlibFunc("customPkg.myfunc"); f != dummyFunc {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if c, _ := GetStdlibConst("customPkg.myconst"); c != 42 {
+		t.Error("Unexpected result:", c)
+		return
+	}
+
+	if err := RegisterPackage("customPkg", "", nil, nil); err == nil || err.Error() != "Package customPkg already exists" {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if err := RegisterPackage("math", "", nil, nil); err == nil || err.Error() != "Package math already exists" {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if err := RegisterPackage("a.b", "", nil, nil); err == nil || err.Error() != "Invalid package name: a.b" {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if err := UnregisterPackage("math"); err == nil || err.Error() != "Package math was not registered" {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if _, ok := GetPkgDocString("math"); !ok {
+		t.Error("Package math should not have been removed")
+		return
+	}
+
+	if err := UnregisterPackage("customPkg"); err != nil {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if err := UnregisterPackage("customPkg"); err == nil || err.Error() != "Package customPkg was not registered" {
+		t.Error("Unexpected error:", err)
+		return
+	}
+
+	if _, ok := GetStdlibFunc("customPkg.myfunc"); ok {
+		t.Error("Package should have been removed")
+		return
+	}
+
+	if packageNames, _, _ := GetStdlibSymbols(); stringutil.IndexOf("customPkg", packageNames) != -1 {
+		t.Error("Unexpected result:", packageNames)
+		return
+	}
+
+	// Register and lookup packages concurrently
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("concurrentPkg%v", i)
+			RegisterPackage(name, "", map[string]util.ECALFunction{"myfunc": dummyFunc}, nil)
+			UnregisterPackage(name)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			GetStdlibFunc(fmt.Sprintf("concurrentPkg%v.myfunc", i))
+			GetStdlibSymbols()
+		}(i)
+	}
+
+	wg.Wait()
 }
 
 func TestAddPluginStdLibFunc(t *testing.T) {
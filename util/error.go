@@ -71,6 +71,12 @@ var (
 	ErrNotAMap          = errors.New("Operand is not a map")
 	ErrNotAListOrMap    = errors.New("Operand is not a list nor a map")
 	ErrSink             = errors.New("Error in sink")
+	ErrTimeout          = errors.New("Timeout")
+	ErrBudgetExceeded   = errors.New("Evaluation budget exceeded")
+	ErrDivisionByZero   = errors.New("Division by zero")
+
+	// ErrAborted is raised when the debugger aborts a thread - it cannot be caught
+	ErrAborted = errors.New("Aborted")
 
 	// ErrReturn is not an error. It is used to return when executing a function
 	ErrReturn = errors.New("*** return ***")
@@ -91,6 +97,94 @@ func NewRuntimeError(source string, t error, d string, node *parser.ASTNode) err
 	return &RuntimeError{source, t, d, node, 0, 0, nil}
 }
 
+/*
+RuntimeErrorBuilder constructs runtime errors step by step.
+*/
+type RuntimeErrorBuilder struct {
+	source     string
+	t          error
+	detail     string
+	node       *parser.ASTNode
+	trace      []*parser.ASTNode
+	env        parser.Scope
+	data       interface{}
+	withDetail bool
+}
+
+/*
+NewRuntimeErrorBuilder creates a new builder for a runtime error of a given type.
+*/
+func NewRuntimeErrorBuilder(source string, t error) *RuntimeErrorBuilder {
+	return &RuntimeErrorBuilder{source: source, t: t}
+}
+
+/*
+Detail sets the details of the error.
+*/
+func (b *RuntimeErrorBuilder) Detail(d string) *RuntimeErrorBuilder {
+	b.detail = d
+	return b
+}
+
+/*
+Node sets the AST node where the error occurred.
+*/
+func (b *RuntimeErrorBuilder) Node(n *parser.ASTNode) *RuntimeErrorBuilder {
+	b.node = n
+	return b
+}
+
+/*
+Trace adds trace steps to the error.
+*/
+func (b *RuntimeErrorBuilder) Trace(nodes ...*parser.ASTNode) *RuntimeErrorBuilder {
+	b.trace = append(b.trace, nodes...)
+	return b
+}
+
+/*
+Environment sets the variable scope of the error. The built error will be a
+RuntimeErrorWithDetail.
+*/
+func (b *RuntimeErrorBuilder) Environment(vs parser.Scope) *RuntimeErrorBuilder {
+	b.env = vs
+	b.withDetail = true
+	return b
+}
+
+/*
+Data sets additional data of the error. The built error will be a
+RuntimeErrorWithDetail.
+*/
+func (b *RuntimeErrorBuilder) Data(v interface{}) *RuntimeErrorBuilder {
+	b.data = v
+	b.withDetail = true
+	return b
+}
+
+/*
+Build creates the runtime error. Returns a *RuntimeErrorWithDetail if an
+environment or data was given otherwise a *RuntimeError.
+*/
+func (b *RuntimeErrorBuilder) Build() error {
+	re := &RuntimeError{b.source, b.t, b.detail, b.node, 0, 0, nil}
+
+	if b.node != nil && b.node.Token != nil {
+		re.Line = b.node.Token.Lline
+		re.Pos = b.node.Token.Lpos
+	}
+
+	if len(b.trace) > 0 {
+		re.Trace = append([]*parser.ASTNode{}, b.trace...)
+	}
+
+	if b.withDetail {
+		return &RuntimeErrorWithDetail{re, b.env, b.data}
+	}
+
+	return re
+}
+
 /*
 Error returns a human-readable string representation of this error.
 */
@@ -141,12 +235,19 @@ func (re *RuntimeError) ToJSONObject() map[string]interface{} {
 	if re.Type != nil {
 		t = re.Type.Error()
 	}
+
+	trace := make([]interface{}, 0, len(re.Trace))
+	for _, n := range re.Trace {
+		trace = append(trace, parser.ASTNodeToMap(n))
+	}
+
 	return map[string]interface{}{
-		"Source": re.Source,
-		"Type":   t,
-		"Detail": re.Detail,
-		"Node":   re.Node,
-		"Trace":  re.Trace,
+		"source": re.Source,
+		"type":   t,
+		"detail": re.Detail,
+		"line":   re.Line,
+		"pos":    re.Pos,
+		"trace":  trace,
 	}
 }
 
@@ -157,6 +258,64 @@ func (re *RuntimeError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(re.ToJSONObject())
 }
 
+/*
+UnmarshalJSON deserializes a RuntimeError from a JSON string. Known error
+types are restored so they can be used in equal checks. The AST node of the
+error is not part of the JSON representation.
+*/
+func (re *RuntimeError) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		Source string
+		Type   string
+		Detail string
+		Line   int
+		Pos    int
+		Trace  []map[string]interface{}
+	}
+
+	err := json.Unmarshal(data, &obj)
+
+	if err == nil {
+		re.Source = obj.Source
+		re.Type = errorType(obj.Type)
+		re.Detail = obj.Detail
+		re.Node = nil
+		re.Line = obj.Line
+		re.Pos = obj.Pos
+		re.Trace = nil
+
+		for _, t := range obj.Trace {
+			var n *parser.ASTNode
+
+			if n, err = parser.ASTFromJSONObject(t); err != nil {
+				break
+			}
+
+			re.Trace = append(re.Trace, n)
+		}
+	}
+
+	return err
+}
+
+/*
+errorType returns a known error type from its string representation or a new
+error if the type is not known.
+*/
+func errorType(t string) error {
+	for _, e := range []error{ErrRuntimeError, ErrUnknownConstruct, ErrInvalidConstruct,
+		ErrInvalidState, ErrVarAccess, ErrNotANumber, ErrNotABoolean, ErrNotAList,
+		ErrNotAMap, ErrNotAListOrMap, ErrSink, ErrTimeout, ErrBudgetExceeded, ErrDivisionByZero, ErrAborted, ErrReturn, ErrIsIterator,
+		ErrEndOfIteration, ErrContinueIteration} {
+
+		if e.Error() == t {
+			return e
+		}
+	}
+
+	return errors.New(t)
+}
+
 /*
 RuntimeErrorWithDetail is a runtime error with additional environment information.
 */
@@ -175,8 +334,8 @@ func (re *RuntimeErrorWithDetail) ToJSONObject() map[string]interface{} {
 	if re.Environment != nil {
 		e = re.Environment.ToJSONObject()
 	}
-	res["Environment"] = e
-	res["Data"] = re.Data
+	res["environment"] = e
+	res["data"] = re.Data
 	return res
 }
 
@@ -186,3 +345,26 @@ MarshalJSON serializes this RuntimeErrorWithDetail into a JSON string.
 func (re *RuntimeErrorWithDetail) MarshalJSON() ([]byte, error) {
 	return json.Marshal(re.ToJSONObject())
 }
+
+/*
+UnmarshalJSON deserializes a RuntimeErrorWithDetail from a JSON string. The
+environment is not restored since it is a live variable scope.
+*/
+func (re *RuntimeErrorWithDetail) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		Data interface{}
+	}
+
+	re.RuntimeError = &RuntimeError{}
+
+	err := json.Unmarshal(data, re.RuntimeError)
+
+	if err == nil {
+		if err = json.Unmarshal(data, &obj); err == nil {
+			re.Environment = nil
+			re.Data = obj.Data
+		}
+	}
+
+	return err
+}
@@ -64,413 +64,350 @@ raise(c) (bar2:1)
 
 	res, _ := json.MarshalIndent(err4.RuntimeError, "", "  ")
 	if string(res) != `{
-  "Detail": "bar",
-  "Node": {
-    "Name": ":=",
-    "Token": {
-      "ID": 39,
-      "Pos": 1,
-      "Val": ":=",
-      "Identifier": false,
-      "AllowEscapes": false,
-      "PrefixNewlines": 0,
-      "Lsource": "foo",
-      "Lline": 1,
-      "Lpos": 2
-    },
-    "Meta": null,
-    "Children": [
-      {
-        "Name": "identifier",
-        "Token": {
-          "ID": 7,
-          "Pos": 0,
-          "Val": "a",
-          "Identifier": true,
-          "AllowEscapes": false,
-          "PrefixNewlines": 0,
-          "Lsource": "foo",
-          "Lline": 1,
-          "Lpos": 1
-        },
-        "Meta": null,
-        "Children": [],
-        "Runtime": null
-      },
-      {
-        "Name": "number",
-        "Token": {
-          "ID": 6,
-          "Pos": 3,
-          "Val": "1",
-          "Identifier": false,
-          "AllowEscapes": false,
-          "PrefixNewlines": 0,
-          "Lsource": "foo",
-          "Lline": 1,
-          "Lpos": 4
-        },
-        "Meta": null,
-        "Children": [],
-        "Runtime": null
-      }
-    ],
-    "Runtime": null
-  },
-  "Source": "foo",
-  "Trace": [
+  "detail": "bar",
+  "line": 1,
+  "pos": 2,
+  "source": "foo",
+  "trace": [
     {
-      "Name": "identifier",
-      "Token": {
-        "ID": 7,
-        "Pos": 0,
-        "Val": "print",
-        "Identifier": true,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar1",
-        "Lline": 1,
-        "Lpos": 1
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "funccall",
-          "Token": null,
-          "Meta": null,
-          "Children": [
+          "children": [
             {
-              "Name": "identifier",
-              "Token": {
-                "ID": 7,
-                "Pos": 6,
-                "Val": "b",
-                "Identifier": true,
-                "AllowEscapes": false,
-                "PrefixNewlines": 0,
-                "Lsource": "bar1",
-                "Lline": 1,
-                "Lpos": 7
-              },
-              "Meta": null,
-              "Children": [],
-              "Runtime": null
+              "allowescapes": false,
+              "id": 7,
+              "identifier": true,
+              "line": 1,
+              "linepos": 7,
+              "name": "identifier",
+              "pos": 6,
+              "source": "bar1",
+              "value": "b"
             }
           ],
-          "Runtime": null
+          "name": "funccall"
         }
       ],
-      "Runtime": null
+      "id": 7,
+      "identifier": true,
+      "line": 1,
+      "linepos": 1,
+      "name": "identifier",
+      "pos": 0,
+      "source": "bar1",
+      "value": "print"
     },
     {
-      "Name": "identifier",
-      "Token": {
-        "ID": 7,
-        "Pos": 0,
-        "Val": "raise",
-        "Identifier": true,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar2",
-        "Lline": 1,
-        "Lpos": 1
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "funccall",
-          "Token": null,
-          "Meta": null,
-          "Children": [
+          "children": [
             {
-              "Name": "identifier",
-              "Token": {
-                "ID": 7,
-                "Pos": 6,
-                "Val": "c",
-                "Identifier": true,
-                "AllowEscapes": false,
-                "PrefixNewlines": 0,
-                "Lsource": "bar2",
-                "Lline": 1,
-                "Lpos": 7
-              },
-              "Meta": null,
-              "Children": [],
-              "Runtime": null
+              "allowescapes": false,
+              "id": 7,
+              "identifier": true,
+              "line": 1,
+              "linepos": 7,
+              "name": "identifier",
+              "pos": 6,
+              "source": "bar2",
+              "value": "c"
             }
           ],
-          "Runtime": null
+          "name": "funccall"
         }
       ],
-      "Runtime": null
+      "id": 7,
+      "identifier": true,
+      "line": 1,
+      "linepos": 1,
+      "name": "identifier",
+      "pos": 0,
+      "source": "bar2",
+      "value": "raise"
     },
     {
-      "Name": "plus",
-      "Token": {
-        "ID": 33,
-        "Pos": 2,
-        "Val": "+",
-        "Identifier": false,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar3",
-        "Lline": 1,
-        "Lpos": 3
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "number",
-          "Token": {
-            "ID": 6,
-            "Pos": 0,
-            "Val": "1",
-            "Identifier": false,
-            "AllowEscapes": false,
-            "PrefixNewlines": 0,
-            "Lsource": "bar3",
-            "Lline": 1,
-            "Lpos": 1
-          },
-          "Meta": null,
-          "Children": [],
-          "Runtime": null
+          "allowescapes": false,
+          "id": 6,
+          "identifier": false,
+          "line": 1,
+          "linepos": 1,
+          "name": "number",
+          "pos": 0,
+          "source": "bar3",
+          "value": "1"
         },
         {
-          "Name": "identifier",
-          "Token": {
-            "ID": 7,
-            "Pos": 4,
-            "Val": "d",
-            "Identifier": true,
-            "AllowEscapes": false,
-            "PrefixNewlines": 0,
-            "Lsource": "bar3",
-            "Lline": 1,
-            "Lpos": 5
-          },
-          "Meta": null,
-          "Children": [],
-          "Runtime": null
+          "allowescapes": false,
+          "id": 7,
+          "identifier": true,
+          "line": 1,
+          "linepos": 5,
+          "name": "identifier",
+          "pos": 4,
+          "source": "bar3",
+          "value": "d"
         }
       ],
-      "Runtime": null
+      "id": 33,
+      "identifier": false,
+      "line": 1,
+      "linepos": 3,
+      "name": "plus",
+      "pos": 2,
+      "source": "bar3",
+      "value": "+"
     }
   ],
-  "Type": "foo"
+  "type": "foo"
+}` {
+		t.Error("Unexpected result:", string(res))
+		return
+	}
+
+	res, _ = json.MarshalIndent(&RuntimeError{"foo", ErrNotANumber, "bar", nil, 1, 2, nil}, "", "  ")
+	if string(res) != `{
+  "detail": "bar",
+  "line": 1,
+  "pos": 2,
+  "source": "foo",
+  "trace": [],
+  "type": "Operand is not a number"
 }` {
 		t.Error("Unexpected result:", string(res))
 		return
 	}
 
+	// Test round trip
+
+	res, _ = json.Marshal(err4.RuntimeError)
+
+	var err5 RuntimeError
+
+	if err := json.Unmarshal(res, &err5); err != nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err5.Error() != err3.Error() || strings.Join(err5.GetTraceString(), "\n") != trace {
+		t.Error("Unexpected result:", err5.Error(), err5.GetTraceString())
+		return
+	}
+
+	// Known error types are restored
+
+	res, _ = json.Marshal(&RuntimeError{"foo", ErrNotANumber, "bar", nil, 1, 2, nil})
+	json.Unmarshal(res, &err5)
+
+	if err5.Type != ErrNotANumber || err5.Error() != "ECAL error in foo: Operand is not a number (bar) (Line:1 Pos:2)" {
+		t.Error("Unexpected result:", err5.Error())
+		return
+	}
+
+	if err := json.Unmarshal([]byte(`{"trace":[{"value":"a"}]}`), &err5); err == nil ||
+		err.Error() != "Found json ast node without a name: map[value:a]" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
 	s := scope.NewScope("aa")
 	s.SetValue("xx", 123)
 	err4 = &RuntimeErrorWithDetail{err3.(*RuntimeError), s, sync.Mutex{}}
 
 	res, _ = json.MarshalIndent(err4, "", "  ")
 	if string(res) != `{
-  "Data": {},
-  "Detail": "bar",
-  "Environment": {
+  "data": {},
+  "detail": "bar",
+  "environment": {
     "xx": 123
   },
-  "Node": {
-    "Name": ":=",
-    "Token": {
-      "ID": 39,
-      "Pos": 1,
-      "Val": ":=",
-      "Identifier": false,
-      "AllowEscapes": false,
-      "PrefixNewlines": 0,
-      "Lsource": "foo",
-      "Lline": 1,
-      "Lpos": 2
-    },
-    "Meta": null,
-    "Children": [
-      {
-        "Name": "identifier",
-        "Token": {
-          "ID": 7,
-          "Pos": 0,
-          "Val": "a",
-          "Identifier": true,
-          "AllowEscapes": false,
-          "PrefixNewlines": 0,
-          "Lsource": "foo",
-          "Lline": 1,
-          "Lpos": 1
-        },
-        "Meta": null,
-        "Children": [],
-        "Runtime": null
-      },
-      {
-        "Name": "number",
-        "Token": {
-          "ID": 6,
-          "Pos": 3,
-          "Val": "1",
-          "Identifier": false,
-          "AllowEscapes": false,
-          "PrefixNewlines": 0,
-          "Lsource": "foo",
-          "Lline": 1,
-          "Lpos": 4
-        },
-        "Meta": null,
-        "Children": [],
-        "Runtime": null
-      }
-    ],
-    "Runtime": null
-  },
-  "Source": "foo",
-  "Trace": [
+  "line": 1,
+  "pos": 2,
+  "source": "foo",
+  "trace": [
     {
-      "Name": "identifier",
-      "Token": {
-        "ID": 7,
-        "Pos": 0,
-        "Val": "print",
-        "Identifier": true,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar1",
-        "Lline": 1,
-        "Lpos": 1
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "funccall",
-          "Token": null,
-          "Meta": null,
-          "Children": [
+          "children": [
             {
-              "Name": "identifier",
-              "Token": {
-                "ID": 7,
-                "Pos": 6,
-                "Val": "b",
-                "Identifier": true,
-                "AllowEscapes": false,
-                "PrefixNewlines": 0,
-                "Lsource": "bar1",
-                "Lline": 1,
-                "Lpos": 7
-              },
-              "Meta": null,
-              "Children": [],
-              "Runtime": null
+              "allowescapes": false,
+              "id": 7,
+              "identifier": true,
+              "line": 1,
+              "linepos": 7,
+              "name": "identifier",
+              "pos": 6,
+              "source": "bar1",
+              "value": "b"
             }
           ],
-          "Runtime": null
+          "name": "funccall"
         }
       ],
-      "Runtime": null
+      "id": 7,
+      "identifier": true,
+      "line": 1,
+      "linepos": 1,
+      "name": "identifier",
+      "pos": 0,
+      "source": "bar1",
+      "value": "print"
     },
     {
-      "Name": "identifier",
-      "Token": {
-        "ID": 7,
-        "Pos": 0,
-        "Val": "raise",
-        "Identifier": true,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar2",
-        "Lline": 1,
-        "Lpos": 1
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "funccall",
-          "Token": null,
-          "Meta": null,
-          "Children": [
+          "children": [
             {
-              "Name": "identifier",
-              "Token": {
-                "ID": 7,
-                "Pos": 6,
-                "Val": "c",
-                "Identifier": true,
-                "AllowEscapes": false,
-                "PrefixNewlines": 0,
-                "Lsource": "bar2",
-                "Lline": 1,
-                "Lpos": 7
-              },
-              "Meta": null,
-              "Children": [],
-              "Runtime": null
+              "allowescapes": false,
+              "id": 7,
+              "identifier": true,
+              "line": 1,
+              "linepos": 7,
+              "name": "identifier",
+              "pos": 6,
+              "source": "bar2",
+              "value": "c"
             }
           ],
-          "Runtime": null
+          "name": "funccall"
         }
       ],
-      "Runtime": null
+      "id": 7,
+      "identifier": true,
+      "line": 1,
+      "linepos": 1,
+      "name": "identifier",
+      "pos": 0,
+      "source": "bar2",
+      "value": "raise"
     },
     {
-      "Name": "plus",
-      "Token": {
-        "ID": 33,
-        "Pos": 2,
-        "Val": "+",
-        "Identifier": false,
-        "AllowEscapes": false,
-        "PrefixNewlines": 0,
-        "Lsource": "bar3",
-        "Lline": 1,
-        "Lpos": 3
-      },
-      "Meta": null,
-      "Children": [
+      "allowescapes": false,
+      "children": [
         {
-          "Name": "number",
-          "Token": {
-            "ID": 6,
-            "Pos": 0,
-            "Val": "1",
-            "Identifier": false,
-            "AllowEscapes": false,
-            "PrefixNewlines": 0,
-            "Lsource": "bar3",
-            "Lline": 1,
-            "Lpos": 1
-          },
-          "Meta": null,
-          "Children": [],
-          "Runtime": null
+          "allowescapes": false,
+          "id": 6,
+          "identifier": false,
+          "line": 1,
+          "linepos": 1,
+          "name": "number",
+          "pos": 0,
+          "source": "bar3",
+          "value": "1"
         },
         {
-          "Name": "identifier",
-          "Token": {
-            "ID": 7,
-            "Pos": 4,
-            "Val": "d",
-            "Identifier": true,
-            "AllowEscapes": false,
-            "PrefixNewlines": 0,
-            "Lsource": "bar3",
-            "Lline": 1,
-            "Lpos": 5
-          },
-          "Meta": null,
-          "Children": [],
-          "Runtime": null
+          "allowescapes": false,
+          "id": 7,
+          "identifier": true,
+          "line": 1,
+          "linepos": 5,
+          "name": "identifier",
+          "pos": 4,
+          "source": "bar3",
+          "value": "d"
         }
       ],
-      "Runtime": null
+      "id": 33,
+      "identifier": false,
+      "line": 1,
+      "linepos": 3,
+      "name": "plus",
+      "pos": 2,
+      "source": "bar3",
+      "value": "+"
     }
   ],
-  "Type": "foo"
+  "type": "foo"
+}` {
+		t.Error("Unexpected result:", string(res))
+		return
+	}
+
+	err4 = &RuntimeErrorWithDetail{&RuntimeError{"foo", fmt.Errorf("foo"), "bar", nil, 1, 2, nil},
+		s, map[string]interface{}{"a": 1}}
+
+	res, _ = json.MarshalIndent(err4, "", "  ")
+	if string(res) != `{
+  "data": {
+    "a": 1
+  },
+  "detail": "bar",
+  "environment": {
+    "xx": 123
+  },
+  "line": 1,
+  "pos": 2,
+  "source": "foo",
+  "trace": [],
+  "type": "foo"
 }` {
 		t.Error("Unexpected result:", string(res))
 		return
 	}
+
+	var err6 RuntimeErrorWithDetail
+
+	if err := json.Unmarshal(res, &err6); err != nil || err6.Error() != err4.Error() ||
+		fmt.Sprint(err6.Data) != "map[a:1]" || err6.Environment != nil {
+		t.Error("Unexpected result:", err6, err)
+		return
+	}
+
+	if err := json.Unmarshal([]byte(`{"data": 1, "trace": 1}`), &err6); err == nil {
+		t.Error("Unexpected result:", err6)
+		return
+	}
+}
+
+func TestRuntimeErrorBuilder(t *testing.T) {
+	ast, _ := parser.Parse("foo", "a:=1")
+	trace1, _ := parser.Parse("bar1", "print(b)")
+	trace2, _ := parser.Parse("bar2", "1 + d")
+
+	err := NewRuntimeErrorBuilder("foo", ErrNotANumber).Build()
+
+	if err.Error() != "ECAL error in foo: Operand is not a number ()" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	err = NewRuntimeErrorBuilder("foo", ErrNotANumber).Detail("bar").Node(ast).
+		Trace(trace1).Trace(trace2).Build()
+
+	re, ok := err.(*RuntimeError)
+
+	if !ok || re.Source != "foo" || re.Type != ErrNotANumber || re.Detail != "bar" ||
+		re.Node != ast || re.Line != 1 || re.Pos != 2 || len(re.Trace) != 2 ||
+		re.Trace[0] != trace1 || re.Trace[1] != trace2 {
+		t.Error("Unexpected result:", re)
+		return
+	}
+
+	if err.Error() != "ECAL error in foo: Operand is not a number (bar) (Line:1 Pos:2)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	vs := scope.NewScope("aa")
+
+	err = NewRuntimeErrorBuilder("foo", ErrSink).Node(ast).Environment(vs).Data(123).Build()
+
+	red, ok := err.(*RuntimeErrorWithDetail)
+
+	if !ok || red.Type != ErrSink || red.Environment != vs || red.Data != 123 || red.Line != 1 {
+		t.Error("Unexpected result:", red)
+		return
+	}
+
+	if red, ok := NewRuntimeErrorBuilder("foo", ErrSink).Data(nil).Build().(*RuntimeErrorWithDetail); !ok ||
+		red.Environment != nil || red.Data != nil {
+		t.Error("Unexpected result:", red)
+		return
+	}
 }
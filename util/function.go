@@ -0,0 +1,113 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/rhedin/Abe_ecal/parser"
+)
+
+// Function composition
+// ====================
+
+/*
+composedFunction is a function which computes f(g(args...)).
+*/
+type composedFunction struct {
+	f ECALFunction
+	g ECALFunction
+}
+
+/*
+ComposeFunctions returns a new function which computes f(g(args...)). The
+result of g is passed as the only argument to f.
+*/
+func ComposeFunctions(f, g ECALFunction) ECALFunction {
+	return &composedFunction{f, g}
+}
+
+/*
+Run executes this function.
+*/
+func (cf *composedFunction) Run(instanceID string, vs parser.Scope,
+	is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+
+	res, err := cf.g.Run(instanceID, vs, is, tid, args)
+
+	if err == nil {
+		res, err = cf.f.Run(instanceID, vs, is, tid, []interface{}{res})
+	}
+
+	return res, err
+}
+
+/*
+DocString returns a descriptive text about this function.
+*/
+func (cf *composedFunction) DocString() (string, error) {
+	fdoc, err := cf.f.DocString()
+
+	if err == nil {
+		var gdoc string
+
+		if gdoc, err = cf.g.DocString(); err == nil {
+			return fmt.Sprintf("%v (applied to the result of: %v)", fdoc, gdoc), nil
+		}
+	}
+
+	return "", err
+}
+
+// Partial function application
+// ============================
+
+/*
+partialFunction is a function with pre-bound arguments.
+*/
+type partialFunction struct {
+	f         ECALFunction
+	boundArgs []interface{}
+}
+
+/*
+PartialFunction returns a new function which calls f with a given list of
+bound arguments followed by the arguments of the actual call.
+*/
+func PartialFunction(f ECALFunction, boundArgs []interface{}) ECALFunction {
+	return &partialFunction{f, append([]interface{}{}, boundArgs...)}
+}
+
+/*
+Run executes this function.
+*/
+func (pf *partialFunction) Run(instanceID string, vs parser.Scope,
+	is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+
+	allArgs := make([]interface{}, 0, len(pf.boundArgs)+len(args))
+	allArgs = append(allArgs, pf.boundArgs...)
+	allArgs = append(allArgs, args...)
+
+	return pf.f.Run(instanceID, vs, is, tid, allArgs)
+}
+
+/*
+DocString returns a descriptive text about this function.
+*/
+func (pf *partialFunction) DocString() (string, error) {
+	doc, err := pf.f.DocString()
+
+	if err == nil {
+		doc = fmt.Sprintf("%v (with bound arguments: %v)", doc, pf.boundArgs)
+	}
+
+	return doc, err
+}
@@ -0,0 +1,106 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rhedin/Abe_ecal/parser"
+)
+
+type testNumFunc struct {
+	doc string
+	f   func(args []interface{}) (interface{}, error)
+}
+
+func (tf *testNumFunc) Run(instanceID string, vs parser.Scope,
+	is map[string]interface{}, tid uint64, args []interface{}) (interface{}, error) {
+	return tf.f(args)
+}
+
+func (tf *testNumFunc) DocString() (string, error) {
+	if tf.doc == "" {
+		return "", fmt.Errorf("No docstring")
+	}
+	return tf.doc, nil
+}
+
+func TestFunctionComposition(t *testing.T) {
+	double := &testNumFunc{"Doubles a number.", func(args []interface{}) (interface{}, error) {
+		return args[0].(float64) * 2, nil
+	}}
+	increment := &testNumFunc{"Increments a number.", func(args []interface{}) (interface{}, error) {
+		return args[0].(float64) + 1, nil
+	}}
+	add := &testNumFunc{"Adds two numbers.", func(args []interface{}) (interface{}, error) {
+		return args[0].(float64) + args[1].(float64), nil
+	}}
+	fail := &testNumFunc{"", func(args []interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("testerror")
+	}}
+
+	cf := ComposeFunctions(double, increment)
+
+	if res, err := cf.Run("", nil, nil, 0, []interface{}{3.0}); res != 8.0 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := cf.DocString(); err != nil ||
+		res != "Doubles a number. (applied to the result of: Increments a number.)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	boundArgs := []interface{}{5.0}
+	pf := PartialFunction(add, boundArgs)
+	boundArgs[0] = 1.0
+
+	if res, err := pf.Run("", nil, nil, 0, []interface{}{3.0}); res != 8.0 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := pf.DocString(); err != nil || res != "Adds two numbers. (with bound arguments: [5])" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Functions can be combined
+
+	if res, err := ComposeFunctions(double, pf).Run("", nil, nil, 0, []interface{}{1.0}); res != 12.0 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Errors are passed on
+
+	if res, err := ComposeFunctions(double, fail).Run("", nil, nil, 0, nil); err == nil || err.Error() != "testerror" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, err := ComposeFunctions(double, fail).DocString(); err == nil || err.Error() != "No docstring" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ComposeFunctions(fail, double).DocString(); err == nil || err.Error() != "No docstring" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := PartialFunction(fail, nil).DocString(); err == nil || err.Error() != "No docstring" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
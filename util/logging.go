@@ -15,6 +15,7 @@ import (
 	"io"
 	"log"
 	"strings"
+	"sync/atomic"
 
 	"github.com/rhedin/Abe_common/datautil"
 )
@@ -41,30 +42,43 @@ LogLevelLogger is a wrapper around loggers to add log level functionality.
 */
 type LogLevelLogger struct {
 	logger Logger
-	level  LogLevel
+	level  *atomic.Value
 }
 
 /*
 NewLogLevelLogger wraps a given logger and adds level based filtering functionality.
 */
 func NewLogLevelLogger(logger Logger, level string) (*LogLevelLogger, error) {
-	llevel := LogLevel(strings.ToLower(level))
+	ll := &LogLevelLogger{logger, &atomic.Value{}}
 
-	if llevel != Debug && llevel != Info && llevel != Error {
-		return nil, fmt.Errorf("Invalid log level: %v", llevel)
+	if err := ll.SetLevel(level); err != nil {
+		return nil, err
 	}
 
-	return &LogLevelLogger{
-		logger,
-		llevel,
-	}, nil
+	return ll, nil
 }
 
 /*
 Level returns the current log level.
 */
 func (ll *LogLevelLogger) Level() LogLevel {
-	return ll.level
+	return ll.level.Load().(LogLevel)
+}
+
+/*
+SetLevel changes the current log level. The level can be changed while
+the logger is in use.
+*/
+func (ll *LogLevelLogger) SetLevel(level string) error {
+	llevel := LogLevel(strings.ToLower(level))
+
+	if llevel != Debug && llevel != Info && llevel != Error {
+		return fmt.Errorf("Invalid log level: %v", llevel)
+	}
+
+	ll.level.Store(llevel)
+
+	return nil
 }
 
 /*
@@ -78,7 +92,7 @@ func (ll *LogLevelLogger) LogError(m ...interface{}) {
 LogInfo adds a new info log message.
 */
 func (ll *LogLevelLogger) LogInfo(m ...interface{}) {
-	if ll.level == Info || ll.level == Debug {
+	if level := ll.Level(); level == Info || level == Debug {
 		ll.logger.LogInfo(m...)
 	}
 }
@@ -87,7 +101,7 @@ func (ll *LogLevelLogger) LogInfo(m ...interface{}) {
 LogDebug adds a new debug log message.
 */
 func (ll *LogLevelLogger) LogDebug(m ...interface{}) {
-	if ll.level == Debug {
+	if ll.Level() == Debug {
 		ll.logger.LogDebug(m...)
 	}
 }
@@ -156,6 +170,62 @@ func (ml *MemoryLogger) Size() int {
 	return ml.RingBuffer.Size()
 }
 
+/*
+Filter returns all stored messages which are at or above a given log level.
+Unknown log levels do not filter any messages.
+*/
+func (ml *MemoryLogger) Filter(level string) []string {
+	var ret []string
+
+	minRank := logLevelRank[LogLevel(strings.ToLower(level))]
+
+	for _, lm := range ml.Slice() {
+		if logLevelRank[messageLogLevel(lm)] >= minRank {
+			ret = append(ret, lm)
+		}
+	}
+
+	return ret
+}
+
+/*
+LastN returns the most recent n messages of the current log.
+*/
+func (ml *MemoryLogger) LastN(n int) []string {
+	sl := ml.Slice()
+
+	if n < 0 {
+		n = 0
+	}
+
+	if len(sl) > n {
+		sl = sl[len(sl)-n:]
+	}
+
+	return sl
+}
+
+/*
+logLevelRank orders log levels by their severity.
+*/
+var logLevelRank = map[LogLevel]int{
+	Debug: 0,
+	Info:  1,
+	Error: 2,
+}
+
+/*
+messageLogLevel determines the log level of a message stored by a MemoryLogger.
+*/
+func messageLogLevel(m string) LogLevel {
+	if strings.HasPrefix(m, "error: ") {
+		return Error
+	} else if strings.HasPrefix(m, "debug: ") {
+		return Debug
+	}
+	return Info
+}
+
 /*
 String returns the current log as a string.
 */
@@ -167,35 +237,80 @@ func (ml *MemoryLogger) String() string {
 StdOutLogger writes log messages to stdout.
 */
 type StdOutLogger struct {
-	stdlog func(v ...interface{})
+	stdlog    func(v ...interface{})
+	formatter func(level, msg string) string
 }
 
 /*
 NewStdOutLogger returns a stdout logger instance.
 */
 func NewStdOutLogger() *StdOutLogger {
-	return &StdOutLogger{log.Print}
+	return &StdOutLogger{log.Print, defaultLogFormatter}
+}
+
+/*
+NewStdOutLoggerWithWriter returns a stdout logger instance which writes
+its output to a given writer instead of stdout.
+*/
+func NewStdOutLoggerWithWriter(w io.Writer) *StdOutLogger {
+	return &StdOutLogger{log.New(w, "", log.LstdFlags).Print, defaultLogFormatter}
+}
+
+/*
+NewStdOutLoggerWithFormatter returns a stdout logger instance which writes
+its output to a given writer. Each message is formatted by the given
+formatter function which receives the log level and the message. The
+formatted message is written as a single line without any further decoration.
+*/
+func NewStdOutLoggerWithFormatter(w io.Writer, formatter func(level, msg string) string) *StdOutLogger {
+	return &StdOutLogger{func(v ...interface{}) {
+		fmt.Fprintln(w, v...)
+	}, formatter}
+}
+
+/*
+defaultLogFormatter prefixes messages with their log level (info messages
+have no prefix).
+*/
+func defaultLogFormatter(level, msg string) string {
+	if LogLevel(level) == Info {
+		return msg
+	}
+	return fmt.Sprintf("%v: %v", level, msg)
 }
 
 /*
 LogError adds a new error log message.
 */
 func (sl *StdOutLogger) LogError(m ...interface{}) {
-	sl.stdlog(fmt.Sprintf("error: %v", fmt.Sprint(m...)))
+	sl.stdlog(sl.format(Error, m))
 }
 
 /*
 LogInfo adds a new info log message.
 */
 func (sl *StdOutLogger) LogInfo(m ...interface{}) {
-	sl.stdlog(fmt.Sprintf("%v", fmt.Sprint(m...)))
+	sl.stdlog(sl.format(Info, m))
 }
 
 /*
 LogDebug adds a new debug log message.
 */
 func (sl *StdOutLogger) LogDebug(m ...interface{}) {
-	sl.stdlog(fmt.Sprintf("debug: %v", fmt.Sprint(m...)))
+	sl.stdlog(sl.format(Debug, m))
+}
+
+/*
+format formats a given log message.
+*/
+func (sl *StdOutLogger) format(level LogLevel, m []interface{}) string {
+	msg := fmt.Sprint(m...)
+
+	if sl.formatter == nil {
+		return defaultLogFormatter(string(level), msg)
+	}
+
+	return sl.formatter(string(level), msg)
 }
 
 /*
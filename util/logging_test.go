@@ -13,6 +13,7 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -48,6 +49,43 @@ test` {
 		return
 	}
 
+	ml.Reset()
+
+	ml.LogDebug("test1")
+	ml.LogInfo("test2")
+	ml.LogError("test3")
+	ml.LogDebug("test4")
+
+	if res := fmt.Sprint(ml.Filter("Info")); res != "[test2 error: test3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(ml.Filter("error")); res != "[error: test3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(ml.Filter("debug")); res != "[debug: test1 test2 error: test3 debug: test4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(ml.LastN(2)); res != "[error: test3 debug: test4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(ml.LastN(10)); res != "[debug: test1 test2 error: test3 debug: test4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(ml.LastN(0)); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
 	// Test that the functions can be called
 
 	nl := NewNullLogger()
@@ -110,6 +148,28 @@ error: ltest3` {
 		return
 	}
 
+	ml.Reset()
+
+	if err := ll.SetLevel("foo"); err == nil || err.Error() != "Invalid log level: foo" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := ll.SetLevel("Info"); err != nil || ll.Level() != "info" {
+		t.Error("Unexpected result:", ll.Level(), err)
+		return
+	}
+
+	ll.LogDebug("l", "test1")
+	ll.LogInfo("l", "test2")
+	ll.LogError("l", "test3")
+
+	if ml.String() != `ltest2
+error: ltest3` {
+		t.Error("Unexpected result:", ml.String())
+		return
+	}
+
 	buf := bytes.NewBuffer(nil)
 	bl := NewBufferLogger(buf)
 	bl.LogDebug("l", "test1")
@@ -119,6 +179,41 @@ error: ltest3` {
 	if buf.String() != `debug: ltest1
 <nil>test2
 error: ltest3
+` {
+		t.Error("Unexpected result:", buf.String())
+		return
+	}
+
+	buf = bytes.NewBuffer(nil)
+	sol = NewStdOutLoggerWithWriter(buf)
+	sol.LogDebug("l", "test1")
+	sol.LogInfo("test2")
+
+	if res := buf.String(); !strings.Contains(res, " debug: ltest1\n") ||
+		!strings.HasSuffix(res, " test2\n") || strings.Count(res, "\n") != 2 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	var levels []string
+
+	buf = bytes.NewBuffer(nil)
+	sol = NewStdOutLoggerWithFormatter(buf, func(level, msg string) string {
+		levels = append(levels, level)
+		return fmt.Sprintf("[%v] %v", strings.ToUpper(level), msg)
+	})
+	sol.LogDebug("l", "test1")
+	sol.LogInfo(nil, "test2")
+	sol.LogError("l", "test3")
+
+	if res := fmt.Sprint(levels); res != "[debug info error]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if buf.String() != `[DEBUG] ltest1
+[INFO] <nil>test2
+[ERROR] ltest3
 ` {
 		t.Error("Unexpected result:", buf.String())
 		return
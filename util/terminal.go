@@ -0,0 +1,62 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package util
+
+import (
+	"bytes"
+	"sync"
+)
+
+/*
+StringOutputTerminal is an output terminal which writes into an internal buffer.
+It can be used to capture console output in tests.
+*/
+type StringOutputTerminal struct {
+	buf  *bytes.Buffer
+	lock *sync.Mutex
+}
+
+/*
+NewStringOutputTerminal returns a new StringOutputTerminal.
+*/
+func NewStringOutputTerminal() (*StringOutputTerminal, error) {
+	return &StringOutputTerminal{&bytes.Buffer{}, &sync.Mutex{}}, nil
+}
+
+/*
+WriteString write a string on this terminal.
+*/
+func (sot *StringOutputTerminal) WriteString(s string) {
+	sot.lock.Lock()
+	defer sot.lock.Unlock()
+
+	sot.buf.WriteString(s)
+}
+
+/*
+Reset clears all output which was written so far.
+*/
+func (sot *StringOutputTerminal) Reset() {
+	sot.lock.Lock()
+	defer sot.lock.Unlock()
+
+	sot.buf.Reset()
+}
+
+/*
+String returns all output which was written so far.
+*/
+func (sot *StringOutputTerminal) String() string {
+	sot.lock.Lock()
+	defer sot.lock.Unlock()
+
+	return sot.buf.String()
+}
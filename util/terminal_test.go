@@ -0,0 +1,40 @@
+/*
+ * ECAL
+ *
+ * Copyright 2020 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the MIT
+ * License, If a copy of the MIT License was not distributed with this
+ * file, You can obtain one at https://opensource.org/licenses/MIT.
+ */
+
+package util
+
+import (
+	"testing"
+)
+
+func TestStringOutputTerminal(t *testing.T) {
+	sot, err := NewStringOutputTerminal()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var ot OutputTerminal = sot
+
+	ot.WriteString("foo")
+	ot.WriteString("bar\n")
+
+	if res := sot.String(); res != "foobar\n" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	sot.Reset()
+
+	if res := sot.String(); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -92,6 +92,17 @@ type Logger interface {
 	LogDebug(v ...interface{})
 }
 
+/*
+OutputTerminal is a generic output terminal which can write strings.
+*/
+type OutputTerminal interface {
+
+	/*
+	   WriteString write a string on this terminal.
+	*/
+	WriteString(s string)
+}
+
 /*
 ContType represents a way how to resume code execution of a suspended thread.
 */
@@ -105,8 +116,15 @@ const (
 	StepIn                   // Step into a function call or over the next non-function call
 	StepOver                 // Step over the current statement onto the next line
 	StepOut                  // Step out of the current function call
+	Abort                    // Terminate the thread with a runtime error
 )
 
+/*
+WatchListener is notified if the value of a watch expression of a thread
+has changed.
+*/
+type WatchListener func(threadID uint64, expression string, value interface{})
+
 /*
 ECALDebugger is a debugging object which can be used to inspect and modify a running
 ECAL environment.
@@ -146,6 +164,16 @@ type ECALDebugger interface {
 	*/
 	SetThreadPool(tp *pool.ThreadPool)
 
+	/*
+	   SetLogger sets the reference to the current used logger.
+	*/
+	SetLogger(logger Logger)
+
+	/*
+	   Logger returns the current used logger.
+	*/
+	Logger() Logger
+
 	/*
 	   VisitState is called for every state during the execution of a program.
 	*/
@@ -161,6 +189,11 @@ type ECALDebugger interface {
 	*/
 	VisitStepOutState(node *parser.ASTNode, vs parser.Scope, tid uint64, soErr error) TraceableRuntimeError
 
+	/*
+		NotifySourceLoaded lets the debugger know that a source has been loaded.
+	*/
+	NotifySourceLoaded(source string)
+
 	/*
 	   RecordThreadFinished lets the debugger know that a thread has finished.
 	*/
@@ -193,6 +226,43 @@ type ECALDebugger interface {
 	*/
 	InjectValue(threadID uint64, varName string, expression string) error
 
+	/*
+		EvalExpression evaluates an expression in the variable scope of a
+		suspended thread and returns the result.
+	*/
+	EvalExpression(threadID uint64, expression string) (interface{}, error)
+
+	/*
+		AddWatch registers a watch expression on a suspended thread.
+	*/
+	AddWatch(threadID uint64, expression string) error
+
+	/*
+		RemoveWatch removes a watch expression from a thread.
+	*/
+	RemoveWatch(threadID uint64, expression string) error
+
+	/*
+		Watches returns all active watch expressions.
+	*/
+	Watches() interface{}
+
+	/*
+		AddWatchListener adds a listener which is notified if the value of
+		a watch expression has changed.
+	*/
+	AddWatchListener(id string, listener WatchListener)
+
+	/*
+		RemoveWatchListener removes a watch listener.
+	*/
+	RemoveWatchListener(id string)
+
+	/*
+		Disassemble returns the AST of the statement at or near a given source line.
+	*/
+	Disassemble(source string, line int) (interface{}, error)
+
 	/*
 	   Continue will continue a suspended thread.
 	*/